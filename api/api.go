@@ -0,0 +1,260 @@
+// Package api — HTTP JSON API для учётных систем предприятия: сотрудники и их
+// балансы, история операций, товары и заказы. Запросы подписываются ключом
+// (Authorization: Bearer <ключ> или X-API-Key), ключи выпускает команда
+// cmd/apikey. Изменения выполняются теми же функциями пакета service, что и в
+// боте, поэтому правила (пауза между начислениями, бюджет, остатки) одинаковы.
+// Описание в формате OpenAPI отдаётся по /api/v1/openapi.yaml.
+package api
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/logging"
+	"tbViT/metrics"
+	"time"
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+// MaxBody — предельный размер тела запроса.
+const MaxBody = 1 << 20
+
+// Server обслуживает /api/v1/.
+type Server struct {
+	DB *sql.DB
+}
+
+// handler — обработчик запроса, прошедшего проверку ключа. Ошибку превращает в ответ writeError.
+type handler func(w http.ResponseWriter, r *http.Request, key database.APIKey) error
+
+// Register подключает маршруты API к mux.
+func (s Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPI)
+	})
+
+	mux.Handle("GET /api/v1/staff", s.auth(s.listStaff))
+	mux.Handle("GET /api/v1/staff/{id}", s.auth(s.getStaff))
+	mux.Handle("PATCH /api/v1/staff/{id}", s.auth(s.patchStaff))
+	mux.Handle("DELETE /api/v1/staff/{id}", s.auth(s.deleteStaff))
+	mux.Handle("POST /api/v1/staff/{id}/topups", s.auth(s.topUp))
+	mux.Handle("GET /api/v1/staff/{id}/transactions", s.auth(s.listTransactions))
+
+	mux.Handle("GET /api/v1/products", s.auth(s.listProducts))
+	mux.Handle("POST /api/v1/products", s.auth(s.createProduct))
+	mux.Handle("GET /api/v1/products/{id}", s.auth(s.getProduct))
+	mux.Handle("PATCH /api/v1/products/{id}", s.auth(s.patchProduct))
+	mux.Handle("DELETE /api/v1/products/{id}", s.auth(s.deleteProduct))
+
+	mux.Handle("GET /api/v1/orders", s.auth(s.listOrders))
+	mux.Handle("POST /api/v1/orders", s.auth(s.createOrder))
+	mux.Handle("GET /api/v1/orders/{id}", s.auth(s.getOrder))
+	mux.Handle("PATCH /api/v1/orders/{id}", s.auth(s.patchOrder))
+}
+
+// apiError — ответ с ошибкой: code — ключ каталога текстов, по нему удобно ветвиться
+// в клиенте; message — текст на языке из Accept-Language.
+type apiError struct {
+	status int
+	code   string
+	args   []interface{}
+}
+
+func (e *apiError) Error() string {
+	return i18n.T(i18n.Default, e.code, e.args...)
+}
+
+func fail(status int, code string, args ...interface{}) error {
+	return &apiError{status: status, code: code, args: args}
+}
+
+var (
+	errNotFound  = fail(http.StatusNotFound, "api.not_found")
+	errForbidden = fail(http.StatusForbidden, "api.forbidden")
+)
+
+func badRequest(format string, args ...interface{}) error {
+	return fail(http.StatusBadRequest, "api.bad_request", fmt.Sprintf(format, args...))
+}
+
+// statusWriter запоминает код ответа для журнала и метрик.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// auth проверяет ключ и права его владельца, затем вызывает h.
+func (s Server) auth(h handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := logging.WithID(r.Context(), logging.NewID())
+		r = r.WithContext(ctx)
+		w := &statusWriter{ResponseWriter: rw, status: http.StatusOK}
+		lang := i18n.Normalize(r.Header.Get("Accept-Language"))
+
+		key, err := s.checkKey(ctx, r)
+		if err == nil {
+			r.Body = http.MaxBytesReader(w, r.Body, MaxBody)
+			err = h(w, r, key)
+		}
+		if err != nil {
+			writeError(ctx, w, lang, err)
+		}
+
+		metrics.APIRequests.Inc(r.Pattern, strconv.Itoa(w.status))
+		slog.InfoContext(ctx, "Запрос API", "method", r.Method, "route", r.Pattern, "status", w.status,
+			"key_id", key.ID, "ms", time.Since(start).Milliseconds())
+	})
+}
+
+// checkKey находит ключ запроса и проверяет, что его владелец всё ещё вправе им
+// пользоваться: ключ суперпользователя — пока владелец суперпользователь, ключ
+// предприятия — пока владелец его админ, а предприятие не заморожено.
+func (s Server) checkKey(ctx context.Context, r *http.Request) (database.APIKey, error) {
+	raw := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); raw == "" && strings.HasPrefix(auth, "Bearer ") {
+		raw = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if raw == "" {
+		return database.APIKey{}, fail(http.StatusUnauthorized, "api.unauthorized")
+	}
+	key, err := database.FindAPIKey(s.DB, raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.APIKey{}, fail(http.StatusUnauthorized, "api.unauthorized")
+	}
+	if err != nil {
+		return database.APIKey{}, err
+	}
+
+	if database.IsSuperUser(s.DB, key.OwnerID) {
+		return key, nil
+	}
+	owner, err := database.GetUser(s.DB, key.OwnerID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+	if key.RestNumber == 0 || owner.RestNumber != key.RestNumber || owner.AccessLevel != "admin" {
+		slog.WarnContext(ctx, "Владелец ключа API лишился прав", "key_id", key.ID, "owner_id", key.OwnerID)
+		return key, fail(http.StatusForbidden, "api.owner_revoked")
+	}
+	if frozen, _ := database.IsRestFrozen(s.DB, key.RestNumber); frozen {
+		return key, fail(http.StatusForbidden, "api.rest_frozen")
+	}
+	return key, nil
+}
+
+// allowed — может ли ключ работать с данными предприятия rest.
+func allowed(key database.APIKey, rest int) bool {
+	return key.RestNumber == 0 || key.RestNumber == rest
+}
+
+// restParam — предприятие для списков: ключ предприятия всегда работает со своим,
+// ключу суперпользователя номер нужно передать параметром rest.
+func restParam(r *http.Request, key database.APIKey) (int, error) {
+	v := r.URL.Query().Get("rest")
+	if v == "" {
+		if key.RestNumber == 0 {
+			return 0, badRequest("для ключа суперпользователя нужен параметр rest")
+		}
+		return key.RestNumber, nil
+	}
+	rest, err := strconv.Atoi(v)
+	if err != nil || rest <= 0 {
+		return 0, badRequest("некорректный номер предприятия %q", v)
+	}
+	if !allowed(key, rest) {
+		return 0, errForbidden
+	}
+	return rest, nil
+}
+
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errNotFound
+	}
+	return id, nil
+}
+
+// page — limit и offset из параметров запроса: по умолчанию 50 записей, не больше 200.
+func page(r *http.Request) (int, int, error) {
+	limit, offset := 50, 0
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			return 0, 0, badRequest("limit: ожидается от 1 до 200")
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, badRequest("offset: ожидается неотрицательное число")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// decode читает тело JSON; неизвестные поля — ошибка, как и в файле конфигурации.
+func decode(r *http.Request, dst interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return badRequest("%v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError отвечает ошибкой: нарушение правил бота — 422 с ключом из каталога,
+// отсутствующая запись — 404, сбой — 500 без подробностей.
+func writeError(ctx context.Context, w http.ResponseWriter, lang string, err error) {
+	var apiErr *apiError
+	var ruleErr *i18n.Error
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.status
+		body.Error.Code, body.Error.Message = apiErr.code, i18n.T(lang, apiErr.code, apiErr.args...)
+	case errors.As(err, &ruleErr):
+		status = http.StatusUnprocessableEntity
+		body.Error.Code, body.Error.Message = ruleErr.Key, i18n.Err(lang, ruleErr)
+	case errors.Is(err, sql.ErrNoRows):
+		status = http.StatusNotFound
+		body.Error.Code, body.Error.Message = "api.not_found", i18n.T(lang, "api.not_found")
+	default:
+		slog.ErrorContext(ctx, "Ошибка обработки запроса API", "err", err)
+		body.Error.Code, body.Error.Message = "err.internal", i18n.T(lang, "err.internal")
+	}
+	writeJSON(w, status, body)
+}
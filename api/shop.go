@@ -0,0 +1,284 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"tbViT/database"
+	"tbViT/service"
+	"time"
+)
+
+type productJSON struct {
+	ID         int    `json:"id"`
+	RestNumber int    `json:"rest_number"`
+	Name       string `json:"name"`
+	Price      int    `json:"price"`
+	Remains    int    `json:"remains"`
+}
+
+func productFrom(p database.Product) productJSON {
+	return productJSON{ID: p.ID, RestNumber: p.RestNumber, Name: p.Name, Price: p.Price, Remains: p.Remains}
+}
+
+type orderJSON struct {
+	ID         int       `json:"id"`
+	TelegramID int64     `json:"telegram_id"`
+	BuyerName  string    `json:"buyer_name"`
+	BuyerTable string    `json:"buyer_table"`
+	Product    string    `json:"product"`
+	Price      int       `json:"price"`
+	Status     string    `json:"status"`
+	RestNumber int       `json:"rest_number"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Статусы заказа в API; в базе заказ в сборке хранится как database.OrderOpen.
+const (
+	statusOpen     = "open"
+	statusAccepted = database.OrderAccepted
+	statusDenied   = database.OrderDenied
+)
+
+func orderFrom(o database.Order) orderJSON {
+	status := o.Status
+	if status == database.OrderOpen {
+		status = statusOpen
+	}
+	return orderJSON{ID: o.ID, TelegramID: o.TelegramID, BuyerName: o.BuyerName, BuyerTable: o.BuyerTable,
+		Product: o.Product, Price: o.Price, Status: status, RestNumber: o.RestNumber, CreatedAt: o.CreatedAt}
+}
+
+// product — товар из пути запроса; товар чужого предприятия — как отсутствующий.
+func (s Server) product(r *http.Request, key database.APIKey) (database.Product, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return database.Product{}, err
+	}
+	p, err := database.GetProduct(s.DB, int(id))
+	if err != nil {
+		return p, err
+	}
+	if !allowed(key, p.RestNumber) {
+		return p, errNotFound
+	}
+	return p, nil
+}
+
+func (s Server) listProducts(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	rest, err := restParam(r, key)
+	if err != nil {
+		return err
+	}
+	products, err := database.ListProducts(s.DB, rest, r.URL.Query().Get("in_stock") == "true")
+	if err != nil {
+		return err
+	}
+	list := make([]productJSON, len(products))
+	for i, p := range products {
+		list[i] = productFrom(p)
+	}
+	writeJSON(w, http.StatusOK, list)
+	return nil
+}
+
+func (s Server) getProduct(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	p, err := s.product(r, key)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, productFrom(p))
+	return nil
+}
+
+func (s Server) createProduct(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	var req struct {
+		RestNumber int    `json:"rest_number"`
+		Name       string `json:"name"`
+		Price      int    `json:"price"`
+		Remains    int    `json:"remains"`
+	}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	switch {
+	case req.RestNumber == 0 && key.RestNumber == 0:
+		return badRequest("для ключа суперпользователя нужен rest_number")
+	case req.RestNumber == 0:
+		req.RestNumber = key.RestNumber
+	case req.RestNumber < 0:
+		return badRequest("некорректный номер предприятия %d", req.RestNumber)
+	case !allowed(key, req.RestNumber):
+		return errForbidden
+	}
+	id, err := service.AddProduct(s.DB, key.OwnerID, database.Product{RestNumber: req.RestNumber,
+		Name: req.Name, Price: req.Price, Remains: req.Remains})
+	if err != nil {
+		return err
+	}
+	p, err := database.GetProduct(s.DB, id)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", "/api/v1/products/"+strconv.Itoa(id))
+	writeJSON(w, http.StatusCreated, productFrom(p))
+	return nil
+}
+
+func (s Server) patchProduct(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	p, err := s.product(r, key)
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Name    *string `json:"name"`
+		Price   *int    `json:"price"`
+		Remains *int    `json:"remains"`
+	}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Price != nil {
+		p.Price = *req.Price
+	}
+	if req.Remains != nil {
+		p.Remains = *req.Remains
+	}
+	if err := service.UpdateProduct(s.DB, key.OwnerID, p); err != nil {
+		return err
+	}
+	p, err = database.GetProduct(s.DB, p.ID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, productFrom(p))
+	return nil
+}
+
+func (s Server) deleteProduct(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	p, err := s.product(r, key)
+	if err != nil {
+		return err
+	}
+	if err := service.DeleteProduct(s.DB, key.OwnerID, p.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// listOrders — заказы в сборке предприятия или, с параметром telegram_id, все
+// заказы сотрудника постранично.
+func (s Server) listOrders(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	var orders []database.Order
+	total := -1
+	if v := r.URL.Query().Get("telegram_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return badRequest("некорректный telegram_id %q", v)
+		}
+		u, err := database.GetUser(s.DB, id)
+		if err != nil {
+			return err
+		}
+		if !allowed(key, u.RestNumber) {
+			return errNotFound
+		}
+		limit, offset, err := page(r)
+		if err != nil {
+			return err
+		}
+		if orders, total, err = database.ListUserOrders(s.DB, id, limit, offset); err != nil {
+			return err
+		}
+	} else {
+		rest, err := restParam(r, key)
+		if err != nil {
+			return err
+		}
+		if orders, err = database.ListOpenOrders(s.DB, rest); err != nil {
+			return err
+		}
+		total = len(orders)
+	}
+	items := make([]orderJSON, len(orders))
+	for i, o := range orders {
+		items[i] = orderFrom(o)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "total": total})
+	return nil
+}
+
+func (s Server) order(r *http.Request, key database.APIKey) (database.Order, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return database.Order{}, err
+	}
+	o, err := database.GetOrder(s.DB, int(id))
+	if err != nil {
+		return o, err
+	}
+	if !allowed(key, o.RestNumber) {
+		return o, errNotFound
+	}
+	return o, nil
+}
+
+func (s Server) getOrder(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	o, err := s.order(r, key)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, orderFrom(o))
+	return nil
+}
+
+// createOrder оформляет покупку за сотрудника — с теми же проверками баланса и
+// остатка, что и кнопка «Купить».
+func (s Server) createOrder(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	var req struct {
+		TelegramID int64 `json:"telegram_id"`
+		ProductID  int   `json:"product_id"`
+	}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	u, err := database.GetUser(s.DB, req.TelegramID)
+	if err != nil {
+		return err
+	}
+	if !allowed(key, u.RestNumber) {
+		return errNotFound
+	}
+	o, err := service.Buy(r.Context(), s.DB, u.TelegramID, req.ProductID)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", "/api/v1/orders/"+strconv.Itoa(o.ID))
+	writeJSON(w, http.StatusCreated, orderFrom(o))
+	return nil
+}
+
+// patchOrder выдаёт (accept) или отменяет (deny) заказ в сборке.
+func (s Server) patchOrder(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	o, err := s.order(r, key)
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	if req.Status != statusAccepted && req.Status != statusDenied {
+		return badRequest("status: ожидается %s или %s", statusAccepted, statusDenied)
+	}
+	if o, err = service.DecideOrder(r.Context(), s.DB, key.OwnerID, o.ID, req.Status); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, orderFrom(o))
+	return nil
+}
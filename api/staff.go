@@ -0,0 +1,202 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"tbViT/database"
+	"tbViT/service"
+	"time"
+)
+
+type staffJSON struct {
+	TelegramID  int64  `json:"telegram_id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	TableNumber string `json:"table_number"`
+	RestNumber  int    `json:"rest_number"`
+	AccessLevel string `json:"access_level"`
+	Verified    bool   `json:"verified"`
+	Balance     int    `json:"balance"`
+	Blocked     bool   `json:"blocked"`
+}
+
+func staffFrom(u database.User) staffJSON {
+	return staffJSON{TelegramID: u.TelegramID, Username: u.Username, Name: u.Name, TableNumber: u.TableNumber,
+		RestNumber: u.RestNumber, AccessLevel: u.AccessLevel, Verified: u.Verified, Balance: u.Balance, Blocked: u.Blocked}
+}
+
+type transactionJSON struct {
+	ID           int       `json:"id"`
+	TelegramID   int64     `json:"telegram_id"`
+	RestNumber   int       `json:"rest_number"`
+	Amount       int       `json:"amount"`
+	BalanceAfter int       `json:"balance_after"`
+	Type         string    `json:"type"`
+	ActorID      int64     `json:"actor_id"`
+	ActorName    string    `json:"actor_name"`
+	Comment      string    `json:"comment"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// staffMember — сотрудник из пути запроса; чужое предприятие — как отсутствующий.
+func (s Server) staffMember(r *http.Request, key database.APIKey) (database.User, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return database.User{}, err
+	}
+	u, err := database.GetUser(s.DB, id)
+	if err != nil {
+		return u, err
+	}
+	if u.RestNumber == 0 || !allowed(key, u.RestNumber) {
+		return u, errNotFound
+	}
+	return u, nil
+}
+
+func (s Server) listStaff(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	rest, err := restParam(r, key)
+	if err != nil {
+		return err
+	}
+	users, err := database.ListRestUsers(s.DB, rest, r.URL.Query().Get("verified") == "true")
+	if err != nil {
+		return err
+	}
+	list := make([]staffJSON, len(users))
+	for i, u := range users {
+		list[i] = staffFrom(u)
+	}
+	writeJSON(w, http.StatusOK, list)
+	return nil
+}
+
+func (s Server) getStaff(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	u, err := s.staffMember(r, key)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, staffFrom(u))
+	return nil
+}
+
+// patchStaff меняет переданные поля; баланс выставляется корректировкой, как
+// в меню «Сотрудники», и попадает в историю операций.
+func (s Server) patchStaff(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	u, err := s.staffMember(r, key)
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Name        *string `json:"name"`
+		TableNumber *string `json:"table_number"`
+		AccessLevel *string `json:"access_level"`
+		Balance     *int    `json:"balance"`
+	}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	if req.Name != nil {
+		if err := service.CorrectStaff(s.DB, key.OwnerID, u.TelegramID, "name", *req.Name); err != nil {
+			return err
+		}
+	}
+	if req.TableNumber != nil {
+		if err := service.CorrectStaff(s.DB, key.OwnerID, u.TelegramID, "tablenumber", *req.TableNumber); err != nil {
+			return err
+		}
+	}
+	if req.Balance != nil {
+		if err := service.CorrectStaff(s.DB, key.OwnerID, u.TelegramID, "balance", strconv.Itoa(*req.Balance)); err != nil {
+			return err
+		}
+	}
+	if req.AccessLevel != nil {
+		if err := service.SetAccessLevel(s.DB, key.OwnerID, u.TelegramID, *req.AccessLevel); err != nil {
+			return err
+		}
+	}
+	u, err = database.GetUser(s.DB, u.TelegramID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, staffFrom(u))
+	return nil
+}
+
+func (s Server) deleteStaff(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	u, err := s.staffMember(r, key)
+	if err != nil {
+		return err
+	}
+	if err := service.CorrectStaff(s.DB, key.OwnerID, u.TelegramID, "delete", ""); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// topUp начисляет звёзды по тем же правилам, что и кнопки начисления в боте.
+func (s Server) topUp(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	u, err := s.staffMember(r, key)
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Amount int `json:"amount"`
+	}
+	if err := decode(r, &req); err != nil {
+		return err
+	}
+	balance, err := service.TopUp(r.Context(), s.DB, key.OwnerID, u.TelegramID, req.Amount)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"balance": balance})
+	return nil
+}
+
+func (s Server) listTransactions(w http.ResponseWriter, r *http.Request, key database.APIKey) error {
+	u, err := s.staffMember(r, key)
+	if err != nil {
+		return err
+	}
+	limit, offset, err := page(r)
+	if err != nil {
+		return err
+	}
+	var f database.TxFilter
+	q := r.URL.Query()
+	if v := q.Get("type"); v != "" {
+		if !isTxType(v) {
+			return badRequest("неизвестный тип операции %q", v)
+		}
+		f.Type = v
+	}
+	if v := q.Get("days"); v != "" {
+		if f.Days, err = strconv.Atoi(v); err != nil || f.Days < 0 {
+			return badRequest("days: ожидается неотрицательное число")
+		}
+	}
+	txs, total, err := database.ListTransactions(s.DB, u.TelegramID, f, limit, offset)
+	if err != nil {
+		return err
+	}
+	items := make([]transactionJSON, len(txs))
+	for i, t := range txs {
+		items[i] = transactionJSON{ID: t.ID, TelegramID: t.TelegramID, RestNumber: t.RestNumber, Amount: t.Amount,
+			BalanceAfter: t.BalanceAfter, Type: t.Type, ActorID: t.ActorID, ActorName: t.ActorName,
+			Comment: t.Comment, CreatedAt: t.CreatedAt}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "total": total})
+	return nil
+}
+
+func isTxType(t string) bool {
+	for _, v := range database.TxTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
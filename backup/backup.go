@@ -0,0 +1,369 @@
+// Package backup делает резервные копии базы SQLite на ходу (VACUUM INTO), хранит
+// последние Keep копий и восстанавливает базу из копии с проверкой целостности.
+// Копия при желании сжимается gzip и шифруется AES-256-GCM ключом, выведенным из
+// парольной фразы через scrypt со случайной солью.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"tbViT/i18n"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	"modernc.org/sqlite"
+)
+
+// Настройки копий; задаются разделом backup конфигурации.
+var (
+	Dir  = "backups"
+	Keep = 14
+	Gzip = true
+	// Passphrase — парольная фраза шифрования; пустая — копии не шифруются.
+	Passphrase string
+)
+
+const (
+	namePrefix = "botdata-"
+	nameTime   = "20060102-150405"
+	extGzip    = ".gz"
+	extEnc     = ".enc"
+)
+
+// encMagic — заголовок зашифрованной копии, за ним соль scrypt, nonce и шифротекст.
+var encMagic = []byte("TBVBAK2\n")
+
+// Параметры scrypt (рекомендованные для интерактивного входа) и длина соли.
+const (
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	saltSize = 16
+)
+
+// File — резервная копия в каталоге Dir.
+type File struct {
+	Name      string
+	Path      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// SetPassphrase задаёт парольную фразу шифрования; пустая — без шифрования.
+func SetPassphrase(passphrase string) {
+	Passphrase = passphrase
+}
+
+// deriveKey — ключ AES-256 из парольной фразы и соли копии.
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+}
+
+// Create снимает копию работающей базы, упаковывает её и удаляет устаревшие копии.
+func Create(db *sql.DB) (File, error) {
+	if _, ok := db.Driver().(*sqlite.Driver); !ok {
+		return File{}, i18n.NewError("err.backup_driver")
+	}
+	if err := os.MkdirAll(Dir, 0o700); err != nil {
+		return File{}, err
+	}
+	now := time.Now().UTC()
+	snapshot := filepath.Join(Dir, ".snapshot-"+now.Format(nameTime)+".db")
+	os.Remove(snapshot)
+	defer os.Remove(snapshot)
+
+	// VACUUM INTO пишет согласованный снимок, не останавливая запись в базу
+	if _, err := db.Exec(`VACUUM INTO ?`, snapshot); err != nil {
+		return File{}, fmt.Errorf("VACUUM INTO: %w", err)
+	}
+	data, err := os.ReadFile(snapshot)
+	if err != nil {
+		return File{}, err
+	}
+
+	name := namePrefix + now.Format(nameTime) + ".db"
+	if Gzip {
+		if data, err = compress(data); err != nil {
+			return File{}, err
+		}
+		name += extGzip
+	}
+	if Passphrase != "" {
+		if data, err = encrypt(data, Passphrase); err != nil {
+			return File{}, err
+		}
+		name += extEnc
+	}
+	path := filepath.Join(Dir, name)
+	if err := writeFile(path, data); err != nil {
+		return File{}, err
+	}
+	slog.Info("Резервная копия", "file", path, "bytes", len(data))
+
+	if err := rotate(); err != nil {
+		slog.Error("Ошибка удаления старых копий", "err", err)
+	}
+	return File{Name: name, Path: path, Size: int64(len(data)), CreatedAt: now}, nil
+}
+
+// List — копии в каталоге Dir, новые первыми.
+func List() ([]File, error) {
+	entries, err := os.ReadDir(Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	for _, e := range entries {
+		created, ok := parseName(e.Name())
+		if e.IsDir() || !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, File{
+			Name:      e.Name(),
+			Path:      filepath.Join(Dir, e.Name()),
+			Size:      info.Size(),
+			CreatedAt: created,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].CreatedAt.After(files[j].CreatedAt) })
+	return files, nil
+}
+
+// parseName извлекает время создания из имени botdata-20060102-150405.db[.gz][.enc].
+func parseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, namePrefix) {
+		return time.Time{}, false
+	}
+	rest := strings.TrimPrefix(name, namePrefix)
+	if len(rest) < len(nameTime) || !strings.HasPrefix(rest[len(nameTime):], ".db") {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(nameTime, rest[:len(nameTime)])
+	return t, err == nil
+}
+
+// rotate оставляет Keep самых свежих копий.
+func rotate() error {
+	files, err := List()
+	if err != nil {
+		return err
+	}
+	for i := Keep; i < len(files); i++ {
+		if err := os.Remove(files[i].Path); err != nil {
+			return err
+		}
+		slog.Info("Удалена старая резервная копия", "file", files[i].Name)
+	}
+	return nil
+}
+
+// Verify распаковывает копию во временный файл и проверяет целостность базы.
+func Verify(path string) error {
+	tmp, err := unpack(path, filepath.Join(os.TempDir(), "tbvit-verify-"+strconv.FormatInt(time.Now().UnixNano(), 10)+".db"))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return checkIntegrity(tmp)
+}
+
+// Restore заменяет файл базы dbPath содержимым копии. Бот при этом должен быть
+// остановлен. Копия сначала распаковывается рядом с базой и проверяется;
+// текущая база вместе с WAL сохраняется с суффиксом .before-restore-<время>,
+// её путь возвращается. Если заменить базу не удалось, прежние файлы
+// возвращаются на место.
+func Restore(path, dbPath string) (string, error) {
+	tmp, err := unpack(path, dbPath+".restore")
+	if err != nil {
+		return "", err
+	}
+	if err := checkIntegrity(tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	saved := ""
+	var moved []string // суффиксы файлов базы, уже перенесённых в saved
+	if _, err := os.Stat(dbPath); err == nil {
+		saved = dbPath + ".before-restore-" + time.Now().UTC().Format(nameTime)
+		// Журнал WAL относится к старой базе и не должен примениться к восстановленной
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if _, err := os.Stat(dbPath + suffix); err != nil {
+				continue
+			}
+			if err := os.Rename(dbPath+suffix, saved+suffix); err != nil {
+				os.Remove(tmp)
+				return "", rollback(dbPath, saved, moved, err)
+			}
+			moved = append(moved, suffix)
+		}
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		os.Remove(tmp)
+		return "", rollback(dbPath, saved, moved, err)
+	}
+	slog.Info("База восстановлена", "db", dbPath, "file", path)
+	return saved, nil
+}
+
+// rollback возвращает на место файлы базы, перенесённые Restore до ошибки err.
+// Если вернуть не удалось, в ошибке указано, где лежит прежняя база.
+func rollback(dbPath, saved string, moved []string, err error) error {
+	for _, suffix := range moved {
+		if rerr := os.Rename(saved+suffix, dbPath+suffix); rerr != nil {
+			return fmt.Errorf("%w; прежняя база осталась в %s: %v", err, saved, rerr)
+		}
+	}
+	return err
+}
+
+// unpack расшифровывает и распаковывает копию в файл dst по расширениям имени.
+func unpack(path, dst string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(path)
+	if strings.HasSuffix(name, extEnc) {
+		if Passphrase == "" {
+			return "", errors.New("копия зашифрована, а ключ (backup.key или BACKUP_KEY) не задан")
+		}
+		if data, err = decrypt(data, Passphrase); err != nil {
+			return "", err
+		}
+		name = strings.TrimSuffix(name, extEnc)
+	}
+	if strings.HasSuffix(name, extGzip) {
+		if data, err = decompress(data); err != nil {
+			return "", err
+		}
+	}
+	if err := writeFile(dst, data); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// checkIntegrity открывает файл как базу SQLite и запускает PRAGMA integrity_check.
+func checkIntegrity(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("integrity_check: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity_check: %s", result)
+	}
+	// Проверяем, что это база бота, а не произвольный файл SQLite
+	var users int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users); err != nil {
+		return fmt.Errorf("в копии нет таблицы users: %w", err)
+	}
+	return nil
+}
+
+func writeFile(path string, data []byte) error {
+	tmp := path + ".part"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func encrypt(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append(append([]byte{}, encMagic...), salt...), nonce...)
+	return gcm.Seal(out, nonce, data, nil), nil
+}
+
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, encMagic) || len(data) < len(encMagic)+saltSize {
+		return nil, errors.New("файл не похож на зашифрованную копию")
+	}
+	data = data[len(encMagic):]
+	key, err := deriveKey(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("файл не похож на зашифрованную копию")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("не удалось расшифровать копию: неверный ключ или файл повреждён")
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
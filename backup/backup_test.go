@@ -0,0 +1,216 @@
+package backup
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tbViT/database"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	plain := []byte("SQLite format 3\x00")
+	a, err := encrypt(plain, "секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := encrypt(plain, "секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Соль случайная: одна и та же фраза даёт разные ключи
+	if bytes.Equal(a[len(encMagic):len(encMagic)+saltSize], b[len(encMagic):len(encMagic)+saltSize]) {
+		t.Error("соль не меняется между копиями")
+	}
+	got, err := decrypt(a, "секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("расшифровано %q, want %q", got, plain)
+	}
+	if _, err := decrypt(a, "другой"); err == nil {
+		t.Error("неверная фраза должна давать ошибку")
+	}
+	if _, err := decrypt([]byte("не копия"), "секрет"); err == nil {
+		t.Error("чужой файл должен давать ошибку")
+	}
+}
+
+func TestParseName(t *testing.T) {
+	want := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"botdata-20250301-093000.db", true},
+		{"botdata-20250301-093000.db.gz", true},
+		{"botdata-20250301-093000.db.gz.enc", true},
+		{"botdata-20250301-093000.txt", false},
+		{"botdata-2025030.db", false},
+		{".snapshot-20250301-093000.db", false},
+		{"other-20250301-093000.db", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseName(tt.name)
+		if ok != tt.ok || (ok && !got.Equal(want)) {
+			t.Errorf("parseName(%q) = %v, %v; want ok=%v", tt.name, got, ok, tt.ok)
+		}
+	}
+}
+
+// openBotDB создаёт базу бота path с одним сотрудником.
+func openBotDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := database.Open(database.DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db, database.DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, name, rest_number) VALUES (1, 'Иван', 5)`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// setup направляет копии во временный каталог и возвращает настройки после теста.
+func setup(t *testing.T, keep int, gz bool, passphrase string) string {
+	t.Helper()
+	dir, keepWas, gzWas, passWas := Dir, Keep, Gzip, Passphrase
+	t.Cleanup(func() { Dir, Keep, Gzip, Passphrase = dir, keepWas, gzWas, passWas })
+	Dir, Keep, Gzip, Passphrase = filepath.Join(t.TempDir(), "backups"), keep, gz, passphrase
+	return Dir
+}
+
+func TestCreateRotate(t *testing.T) {
+	dir := setup(t, 2, true, "секрет")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// Старые копии: после новой должна остаться только самая свежая из них
+	old := []string{"botdata-20240101-000000.db.gz", "botdata-20240102-000000.db.gz", "botdata-20240103-000000.db"}
+	for _, name := range old {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db := openBotDB(t, filepath.Join(t.TempDir(), "bot.db"))
+	defer db.Close()
+	f, err := Create(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(f.Name, ".db.gz.enc") {
+		t.Errorf("имя копии %q", f.Name)
+	}
+	if err := Verify(f.Path); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	files, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if len(names) != 2 || names[0] != f.Name || names[1] != old[2] {
+		t.Errorf("копии после ротации %v, want [%s %s]", names, f.Name, old[2])
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".snapshot-"+f.CreatedAt.Format(nameTime)+".db")); !os.IsNotExist(err) {
+		t.Error("снимок VACUUM INTO не удалён")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	setup(t, 14, true, "")
+	dbPath := filepath.Join(t.TempDir(), "bot.db")
+	db := openBotDB(t, dbPath)
+	f, err := Create(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Изменения после копии пропадут при восстановлении
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, name, rest_number) VALUES (2, 'Пётр', 5)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	saved, err := Restore(f.Path, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(saved); err != nil {
+		t.Errorf("прежняя база не сохранена: %v", err)
+	}
+	if err := checkIntegrity(saved); err != nil {
+		t.Errorf("прежняя база: %v", err)
+	}
+	restored, err := database.Open(database.DriverSQLite, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	var n int
+	if err := restored.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("сотрудников после восстановления %d, want 1", n)
+	}
+}
+
+// Копия, не прошедшая проверку, не трогает текущую базу.
+func TestRestoreRejectsBadCopy(t *testing.T) {
+	dir := setup(t, 14, false, "")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "bot.db")
+	db := openBotDB(t, dbPath)
+	db.Close()
+	before, err := os.ReadFile(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// База SQLite без таблиц бота
+	foreign := filepath.Join(dir, "botdata-20250101-000000.db")
+	other, err := sql.Open("sqlite", foreign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Exec(`CREATE TABLE t (x INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+	// Обрезанная копия базы бота
+	truncated := filepath.Join(dir, "botdata-20250102-000000.db")
+	if err := os.WriteFile(truncated, before[:len(before)/2], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{foreign, truncated} {
+		if _, err := Restore(path, dbPath); err == nil {
+			t.Errorf("%s: копия принята", filepath.Base(path))
+		}
+		after, err := os.ReadFile(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(after, before) {
+			t.Errorf("%s: текущая база изменена", filepath.Base(path))
+		}
+		if _, err := os.Stat(dbPath + ".restore"); !os.IsNotExist(err) {
+			t.Errorf("%s: временный файл не удалён", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/export"
+	"tbViT/i18n"
+	"time"
+)
+
+const auditPageSize = 10
+
+// auditFilters хранит текущий фильтр журнала для каждого пользователя между страницами.
+var auditFilters = make(map[int64]database.AuditFilter)
+
+// handleAudit показывает журнал действий. Админ видит только своё предприятие,
+// суперпользователь — всю сеть (или предприятие из фильтра rest=).
+func handleAudit(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState, isSuper bool) {
+	data := cq.Data
+	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
+
+	switch {
+	case data == "audit", data == "audit_reset":
+		auditFilters[fromID] = database.AuditFilter{}
+		sendAuditPage(ctx, bot, db, fromID, isSuper, 0)
+
+	case strings.HasPrefix(data, "audit_page:"):
+		page, err := strconv.Atoi(strings.TrimPrefix(data, "audit_page:"))
+		if err != nil || page < 0 {
+			return
+		}
+		sendAuditPage(ctx, bot, db, fromID, isSuper, page)
+
+	case strings.HasPrefix(data, "audit_period:"):
+		days, err := strconv.Atoi(strings.TrimPrefix(data, "audit_period:"))
+		if err != nil || days < 0 {
+			return
+		}
+		f := auditFilters[fromID]
+		f.From, f.To = time.Time{}, time.Time{}
+		if days > 0 {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			f.From = today.AddDate(0, 0, -(days - 1))
+		}
+		auditFilters[fromID] = f
+		sendAuditPage(ctx, bot, db, fromID, isSuper, 0)
+
+	case data == "audit_filter":
+		userState[fromID] = &CorrectionState{ID: fromID, Field: "audit:wait_filter"}
+		hint := i18n.T(lang, "audit.filter_hint")
+		if isSuper {
+			hint += i18n.T(lang, "audit.filter_hint_rest")
+		}
+		bot.Send(tgbotapi.NewMessage(fromID, hint))
+
+	case data == "audit_csv":
+		f := scopedAuditFilter(db, fromID, isSuper)
+		table, err := database.AuditTable(db, f)
+		var content []byte
+		if err == nil {
+			// Значения target/before/after вводят пользователи — export экранирует формулы
+			content, err = export.CSV(table)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка выгрузки журнала", "user_id", fromID, "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "audit.err_export")))
+			return
+		}
+		doc := tgbotapi.NewDocument(fromID, tgbotapi.FileBytes{
+			Name:  fmt.Sprintf("audit_%s.csv", time.Now().Format("2006-01-02")),
+			Bytes: content,
+		})
+		doc.Caption = i18n.T(lang, "audit.caption", f.Label(lang))
+		if _, err := bot.Send(doc); err != nil {
+			slog.ErrorContext(ctx, "Ошибка отправки журнала", "user_id", fromID, "err", err)
+		}
+	}
+}
+
+// HandleAuditFilter применяет введённый текстом фильтр журнала.
+func HandleAuditFilter(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, text string) {
+	f, err := database.ParseAuditFilter(text)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, database.Tr(db, fromID, "audit.err_filter", i18n.Err(database.UserLang(db, fromID), err))))
+		return
+	}
+	auditFilters[fromID] = f
+	sendAuditPage(ctx, bot, db, fromID, database.IsSuperUser(db, fromID), 0)
+}
+
+// scopedAuditFilter ограничивает фильтр предприятием админа, если он не суперпользователь.
+func scopedAuditFilter(db *sql.DB, userID int64, isSuper bool) database.AuditFilter {
+	f := auditFilters[userID]
+	if !isSuper {
+		f.RestNumber = restOf(db, userID)
+	}
+	return f
+}
+
+func sendAuditPage(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, isSuper bool, page int) {
+	f := scopedAuditFilter(db, chatID, isSuper)
+	lang := database.UserLang(db, chatID)
+	records, total, err := database.ListAudit(db, f, auditPageSize, page*auditPageSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка загрузки журнала", "user_id", chatID, "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "audit.err_load")))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(i18n.T(lang, "audit.header", f.Label(lang), total))
+	for _, r := range records {
+		text.WriteString(fmt.Sprintf("%s | %d | %s", r.CreatedAt.Format("2006-01-02 15:04"), r.ActorID, r.Action))
+		if r.Target != "" {
+			text.WriteString(" → " + r.Target)
+		}
+		if r.Before != "" || r.After != "" {
+			text.WriteString(fmt.Sprintf(" | %s → %s", shorten(r.Before), shorten(r.After)))
+		}
+		if isSuper {
+			text.WriteString(fmt.Sprintf(" | 🏢%d", r.RestNumber))
+		}
+		text.WriteString("\n")
+	}
+	if len(records) == 0 {
+		text.WriteString(i18n.T(lang, "audit.empty"))
+	}
+
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.prev"), fmt.Sprintf("audit_page:%d", page-1)))
+	}
+	if (page+1)*auditPageSize < total {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.next"), fmt.Sprintf("audit_page:%d", page+1)))
+	}
+	if len(nav) > 0 {
+		kbRows = append(kbRows, nav)
+	}
+	kbRows = append(kbRows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "audit.period_today"), "audit_period:1"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.N(lang, "audit.period_days", 7), "audit_period:7"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.N(lang, "audit.period_days", 30), "audit_period:30"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "history.period_all"), "audit_period:0"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "audit.btn_filter"), "audit_filter"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "audit.btn_reset"), "audit_reset"),
+			tgbotapi.NewInlineKeyboardButtonData("📥 CSV", "audit_csv"),
+		),
+	)
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+	bot.Send(msg)
+}
+
+// shorten обрезает длинные значения, чтобы страница журнала влезала в одно сообщение.
+func shorten(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	r := []rune(s)
+	if len(r) > 40 {
+		return string(r[:40]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"os"
+	"tbViT/backup"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// maxDocumentSize — предел размера файла, который бот может отправить в Telegram.
+const maxDocumentSize = 50 << 20
+
+// sendBackup снимает резервную копию по запросу суперпользователя и присылает её документом.
+func sendBackup(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, chatID int64) {
+	lang := database.UserLang(db, chatID)
+	f, err := backup.Create(db)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка резервного копирования по запросу", "chat_id", chatID, "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "backup.err", i18n.Err(lang, err))))
+		return
+	}
+	superAudit(db, chatID, 0, "super_user:backup", f.Name, "", "")
+	if f.Size > maxDocumentSize {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "backup.too_big", f.Path, f.Size>>10)))
+		return
+	}
+	content, err := os.ReadFile(f.Path)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка чтения резервной копии", "file", f.Path, "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "backup.err", i18n.Err(lang, err))))
+		return
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: f.Name, Bytes: content})
+	doc.Caption = i18n.T(lang, "backup.caption", f.Size>>10, backup.Keep)
+	if backup.Passphrase == "" {
+		doc.Caption += "\n" + i18n.T(lang, "backup.unencrypted")
+	}
+	if _, err := bot.Send(doc); err != nil {
+		slog.ErrorContext(ctx, "Ошибка отправки резервной копии", "file", f.Name, "err", err)
+	}
+}
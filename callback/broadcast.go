@@ -0,0 +1,194 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/features"
+	"tbViT/i18n"
+)
+
+// broadcastRoles — фильтр получателей по роли; "all" — все. Подпись — broadcast.to_<роль> в каталоге.
+var broadcastRoles = []string{"all", "worker", "manager", "admin"}
+
+// handleBroadcast — составление объявления. Админ пишет своему предприятию,
+// суперпользователь — любому предприятию или всей сети (0).
+// Формат callback: broadcast[:<предприятие>[:<роль>]] | broadcast_send:<id> | broadcast_cancel:<id>
+func handleBroadcast(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState, isSuper bool) {
+	fromID := cq.From.ID
+	data := cq.Data
+	lang := database.UserLang(db, fromID)
+
+	switch {
+	case strings.HasPrefix(data, "broadcast_send:"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(data, "broadcast_send:"))
+		sendBroadcast(bot, db, fromID, id)
+		return
+	case strings.HasPrefix(data, "broadcast_cancel:"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(data, "broadcast_cancel:"))
+		b, err := database.GetBroadcast(db, id)
+		if err != nil || b.AuthorID != fromID {
+			return
+		}
+		if err := database.CancelBroadcast(db, id); err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, "❌ "+i18n.Err(lang, err)))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.cancelled")))
+		return
+	}
+
+	parts := strings.Split(data, ":")
+	switch len(parts) {
+	case 1:
+		if !isSuper {
+			sendBroadcastRoles(bot, lang, fromID, restOf(db, fromID))
+			return
+		}
+		var row []tgbotapi.InlineKeyboardButton
+		if own := restOf(db, fromID); own != 0 {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				i18n.T(lang, "broadcast.btn_rest", own), fmt.Sprintf("broadcast:%d", own)))
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "broadcast.btn_net"), "broadcast:0"))
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.choose_scope"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+		bot.Send(msg)
+
+	case 2:
+		restNumber, ok := broadcastScope(db, fromID, isSuper, parts[1])
+		if ok {
+			sendBroadcastRoles(bot, lang, fromID, restNumber)
+		}
+
+	case 3:
+		restNumber, ok := broadcastScope(db, fromID, isSuper, parts[1])
+		if !ok {
+			return
+		}
+		role := parts[2]
+		if role != "all" && !database.IsValidAccessLevel(role) {
+			return
+		}
+		userState[fromID] = &CorrectionState{
+			ID:    fromID,
+			Field: "broadcast:wait_content",
+			Value: fmt.Sprintf("%d:%s", restNumber, role),
+		}
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.ask_content")))
+	}
+}
+
+// broadcastScope проверяет, что пользователь может писать выбранному предприятию.
+func broadcastScope(db *sql.DB, fromID int64, isSuper bool, value string) (int, bool) {
+	restNumber, err := strconv.Atoi(value)
+	if err != nil || restNumber < 0 {
+		return 0, false
+	}
+	if isSuper {
+		return restNumber, true
+	}
+	own := restOf(db, fromID)
+	return own, own != 0 && own == restNumber
+}
+
+func sendBroadcastRoles(bot *tgbotapi.BotAPI, lang string, chatID int64, restNumber int) {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, r := range broadcastRoles {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "broadcast.to_"+r), fmt.Sprintf("broadcast:%d:%s", restNumber, r)))
+	}
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "broadcast.choose_role"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+	bot.Send(msg)
+}
+
+// HandleBroadcastContent сохраняет черновик объявления и показывает предпросмотр с подтверждением.
+// value — "<предприятие>:<роль>" из состояния.
+func HandleBroadcastContent(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, message *tgbotapi.Message, value string) {
+	fromID := message.From.ID
+	lang := database.UserLang(db, fromID)
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return
+	}
+	restNumber, _ := strconv.Atoi(parts[0])
+	b := database.Broadcast{AuthorID: fromID, RestNumber: restNumber, Role: parts[1]}
+	if b.Role == "all" {
+		b.Role = ""
+	}
+
+	switch {
+	case len(message.Photo) > 0:
+		b.Kind = database.BroadcastPhoto
+		b.FileID = message.Photo[len(message.Photo)-1].FileID
+		b.Text = message.Caption
+	case message.Document != nil:
+		b.Kind = database.BroadcastDocument
+		b.FileID = message.Document.FileID
+		b.Text = message.Caption
+	default:
+		b.Kind = database.BroadcastText
+		b.Text = strings.TrimSpace(message.Text)
+		if b.Text == "" {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.err_empty")))
+			return
+		}
+	}
+
+	id, err := database.CreateBroadcast(db, b)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка создания рассылки", "user_id", fromID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.err_save")))
+		return
+	}
+	count, err := database.CountBroadcastRecipients(db, b)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка подсчёта получателей рассылки", "broadcast_id", id, "err", err)
+	}
+
+	scope := i18n.T(lang, "lb.scope_net")
+	if restNumber != 0 {
+		scope = i18n.T(lang, "lb.scope_rest", restNumber)
+	}
+	role := i18n.T(lang, "broadcast.all_roles")
+	if b.Role != "" {
+		role = roleName(lang, b.Role)
+	}
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.preview", scope, role, count)))
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.send"), fmt.Sprintf("broadcast_send:%d", id)),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.cancel"), fmt.Sprintf("broadcast_cancel:%d", id)),
+		),
+	)
+	b.ID = id
+	bot.Send(features.BroadcastMessage(b, fromID, markup))
+}
+
+func sendBroadcast(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, id int) {
+	lang := database.UserLang(db, fromID)
+	b, err := database.GetBroadcast(db, id)
+	if err != nil || b.AuthorID != fromID {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.not_found")))
+		return
+	}
+	count, err := database.StartBroadcast(db, id)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, "❌ "+i18n.Err(lang, err)))
+		return
+	}
+	database.Audit(db, database.AuditEntry{
+		ActorID:    fromID,
+		RestNumber: b.RestNumber,
+		Action:     "broadcast",
+		Target:     strconv.Itoa(id),
+		After:      fmt.Sprintf("%s, получателей: %d", b.Kind, count),
+	})
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "broadcast.started", id, count)))
+	features.StartDelivery(bot, db, id)
+}
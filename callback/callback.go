@@ -1,14 +1,19 @@
 package callback
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 	"tbViT/database"
 	"tbViT/features"
+	"tbViT/i18n"
+	"tbViT/outbox"
+	"tbViT/view"
+	"tbViT/web"
 )
 
 type CorrectionState struct {
@@ -19,34 +24,47 @@ type CorrectionState struct {
 
 var accessLevel string
 
-func HandleCallback(bot *tgbotapi.BotAPI, db *sql.DB, callback *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState, shopState map[int64]*CorrectionState, superUser int64) {
+// HandleCallback разбирает нажатие инлайн-кнопки; ctx несёт cid обновления.
+func HandleCallback(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, callback *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState, shopState map[int64]*CorrectionState) {
 	fromID := callback.From.ID
 	data := callback.Data
 
-	adminTelegramID, err := database.GetAdminID(db, fromID)
-	if err != nil {
-		log.Println(err)
-	}
-
+	var err error
 	accessLevel, err = database.GetAccessLevel(db, fromID)
 	if err != nil {
-		log.Println(err)
+		slog.ErrorContext(ctx, "Ошибка чтения уровня доступа", "user_id", fromID, "err", err)
 	}
 
-	log.Printf("Callback data: %s, user: %d, level: %s", data, fromID, accessLevel)
+	slog.InfoContext(ctx, "Нажата кнопка", "data", data, "user_id", fromID, "level", accessLevel)
+	lang := database.UserLang(db, fromID)
+
+	isSuper := database.IsSuperUser(db, fromID)
+	if !isSuper && database.IsUserRestFrozen(db, fromID) {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "rest.frozen")))
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+	}
 
 	switch {
-	case strings.HasPrefix(data, "super_user") && fromID == superUser:
-		handleSuper(bot, db, callback, userState)
-		answerCallback(bot, callback.ID, "")
+	case data == "lang" || strings.HasPrefix(data, "lang:"):
+		handleLang(ctx, bot, db, callback)
+		answerCallback(ctx, bot, callback.ID, "")
+	case strings.HasPrefix(data, "super_user") && isSuper:
+		handleSuper(ctx, bot, db, callback, userState)
+		answerCallback(ctx, bot, callback.ID, "")
 	case strings.HasPrefix(data, "approve:") && accessLevel == "admin":
 		parts := strings.Split(data, ":")
 		if len(parts) == 3 {
 			role := parts[1]
 			uid, _ := strconv.ParseInt(parts[2], 10, 64)
-			db.Exec(`UPDATE users SET access_level=?, verified=1, current_balance=0, last_ts=0 WHERE telegram_id=?`, role, uid)
-			bot.Send(tgbotapi.NewMessage(uid, fmt.Sprintf("✅ Регистрация подтверждена! Ваш статус: %s.\n/menu — доступ к функциям.", role)))
-			answerCallback(bot, callback.ID, "Пользователь принят.")
+			if err := database.ApproveRegistration(db, fromID, uid, role); err != nil {
+				answerRegistrationError(ctx, bot, callback, lang, uid, err)
+				return
+			}
+			database.AuditAction(db, fromID, "approve", parts[2], "", role)
+			uLang := database.UserLang(db, uid)
+			outbox.Send(db, uid, i18n.T(uLang, "reg.approved", roleName(uLang, role)))
+			answerCallback(ctx, bot, callback.ID, i18n.T(lang, "reg.approved_ack"))
 			return
 		}
 
@@ -54,21 +72,25 @@ func HandleCallback(bot *tgbotapi.BotAPI, db *sql.DB, callback *tgbotapi.Callbac
 		parts := strings.Split(data, ":")
 		if len(parts) == 2 {
 			uid, _ := strconv.ParseInt(parts[1], 10, 64)
-			db.Exec(`UPDATE users SET verified=0, access_level='' WHERE telegram_id=?`, uid)
-			bot.Send(tgbotapi.NewMessage(uid, "❌ Ваша регистрация отклонена администратором."))
-			bot.Send(tgbotapi.NewMessage(adminTelegramID, "Пользователь отклонён."))
-			answerCallback(bot, callback.ID, "Заявка отклонена")
+			if err := database.RejectRegistration(db, fromID, uid); err != nil {
+				answerRegistrationError(ctx, bot, callback, lang, uid, err)
+				return
+			}
+			database.AuditAction(db, fromID, "reject", parts[1], "", "")
+			outbox.Send(db, uid, database.Tr(db, uid, "reg.rejected"))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "reg.rejected_ack")))
+			answerCallback(ctx, bot, callback.ID, i18n.T(lang, "reg.rejected_answer"))
 			return
 		}
 
 	case data == "menu_admin_setbal" && accessLevel == "admin":
 		dep, err := database.GetUserDep(db, fromID)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Ошибка поиска вашего предприятия."))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.rest_lookup")))
 			return
 		}
-		database.SendWorkersList(bot, db, fromID, "correction", dep, 0)
-		answerCallback(bot, callback.ID, "")
+		sendWorkersList(bot, db, fromID, "correction", dep, 0)
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case strings.HasPrefix(data, "correction:") && accessLevel == "admin":
@@ -81,33 +103,40 @@ func HandleCallback(bot *tgbotapi.BotAPI, db *sql.DB, callback *tgbotapi.Callbac
 			return
 		}
 
+		info, err := workerInfo(db, lang, workerID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "worker.err_info")))
+			return
+		}
 		// Сохраняем workerID, но поле пока пустое
 		userState[fromID] = &CorrectionState{ID: workerID}
-		info := database.GetWorkerInfo(db, workerID)
-		message := fmt.Sprintf("%s\nЧто хотите скорректировать?", info)
+		message := i18n.T(lang, "correction.choose_field", info)
 		msg := tgbotapi.NewMessage(fromID, message)
 		// Инлайн-клавиатура с выбором параметра
 		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("Баланс", "setfield:balance"),
-				tgbotapi.NewInlineKeyboardButtonData("Имя", "setfield:name"),
-				tgbotapi.NewInlineKeyboardButtonData("Номер", "setfield:tablenumber"),
-				tgbotapi.NewInlineKeyboardButtonData("❗️Удалить❗️", "setfield:delete"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "correction.field_balance"), "setfield:balance"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "correction.field_name"), "setfield:name"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "correction.field_tablenumber"), "setfield:tablenumber"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "correction.field_delete"), "setfield:delete"),
 			),
 		)
 		bot.Send(msg)
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case data == "accesslevel" && accessLevel == "admin":
 		roleMarkup := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("Работник", "changeRole:worker"),
-				tgbotapi.NewInlineKeyboardButtonData("Менеджер", "changeRole:manager"),
-				tgbotapi.NewInlineKeyboardButtonData("Админ", "changeRole:admin"),
+				tgbotapi.NewInlineKeyboardButtonData(roleName(lang, "worker"), "changeRole:worker"),
+				tgbotapi.NewInlineKeyboardButtonData(roleName(lang, "manager"), "changeRole:manager"),
+				tgbotapi.NewInlineKeyboardButtonData(roleName(lang, "admin"), "changeRole:admin"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "role.btn_owner"), "changeRole:owner"),
 			),
 		)
-		msg := tgbotapi.NewMessage(fromID, "Выберите роль, которую хотите передать:")
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "role.choose"))
 		msg.ReplyMarkup = roleMarkup
 		bot.Send(msg)
 
@@ -117,33 +146,53 @@ func HandleCallback(bot *tgbotapi.BotAPI, db *sql.DB, callback *tgbotapi.Callbac
 			Field: "wait_table_number",
 			Value: role,
 		}
-		bot.Send(tgbotapi.NewMessage(fromID, "Введите номер расписания работника:"))
-		answerCallback(bot, callback.ID, "")
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "role.ask_table")))
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case strings.HasPrefix(data, "confirmAdmin:") && accessLevel == "admin":
 		tableNumber := strings.TrimPrefix(data, "confirmAdmin:")
 		_, ok := userState[fromID]
 		if !ok {
-			bot.Send(tgbotapi.NewMessage(fromID, "⛔ Ошибка действия. Попробуйте начать заново."))
-			answerCallback(bot, callback.ID, "")
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.action_restart")))
+			answerCallback(ctx, bot, callback.ID, "")
 			return
 		}
 		role := "admin"
-		err := database.ChangeRole(db, fromID, tableNumber, role)
+		targetID, before, err := database.ChangeRole(db, fromID, tableNumber, role)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "role.err_admin", i18n.Err(lang, err))))
+		} else {
+			database.AuditAction(db, fromID, "change_role", strconv.FormatInt(targetID, 10), before, role)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "role.admin_done")))
+		}
+		delete(userState, fromID)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case strings.HasPrefix(data, "confirmOwner:") && accessLevel == "admin":
+		tableNumber := strings.TrimPrefix(data, "confirmOwner:")
+		_, ok := userState[fromID]
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.action_restart")))
+			answerCallback(ctx, bot, callback.ID, "")
+			return
+		}
+		targetID, err := database.TransferOwnership(db, fromID, tableNumber)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "❌ Ошибка назначения админа: "+err.Error()))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "role.err_owner", i18n.Err(lang, err))))
 		} else {
-			bot.Send(tgbotapi.NewMessage(fromID, "✅ Теперь этот человек — админ. Вы стали менеджером."))
+			database.AuditAction(db, fromID, "transfer_ownership", strconv.FormatInt(targetID, 10), strconv.FormatInt(fromID, 10), strconv.FormatInt(targetID, 10))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "role.owner_done")))
 		}
 		delete(userState, fromID)
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case data == "cancelAdmin" && accessLevel == "admin":
-		bot.Send(tgbotapi.NewMessage(fromID, "✅ Операция отменена!"))
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "op.cancelled")))
 		delete(userState, fromID)
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case strings.HasPrefix(data, "setfield:") && accessLevel == "admin":
@@ -158,92 +207,176 @@ func HandleCallback(bot *tgbotapi.BotAPI, db *sql.DB, callback *tgbotapi.Callbac
 		}
 		if field == "delete" {
 			// Удаляем ПОЛЬЗОВАТЕЛЯ, ID которого хранится в state.ID
+			before, _ := workerInfo(db, i18n.Default, state.ID)
 			err := database.DeleteUser(db, state.ID) // ← передаём db и workerID
-			if err != nil {
-				log.Printf("Ошибка удаления пользователя %d: %v", state.ID, err)
-				bot.Send(tgbotapi.NewMessage(fromID, "❌ Не удалось удалить пользователя."))
+			if err == database.ErrLastAdmin {
+				bot.Send(tgbotapi.NewMessage(fromID, "❌ "+i18n.Err(lang, err)))
+			} else if err != nil {
+				slog.ErrorContext(ctx, "Ошибка удаления пользователя", "user_id", state.ID, "err", err)
+				bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "user.err_delete")))
 			} else {
-				bot.Send(tgbotapi.NewMessage(fromID, "✅ Пользователь удалён."))
+				database.AuditAction(db, fromID, "delete_user", strconv.FormatInt(state.ID, 10), before, "")
+				bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "user.deleted")))
 			}
 			delete(userState, fromID)
-			answerCallback(bot, callback.ID, "")
+			answerCallback(ctx, bot, callback.ID, "")
 			return
 		}
 
-		messege := fmt.Sprintf("Введите новое значение(%s):", field)
+		messege := i18n.T(lang, "correction.ask_value", i18n.T(lang, "correction.field_"+field))
 		state.Field = field // теперь помним и работника, и поле
 		bot.Send(tgbotapi.NewMessage(fromID, messege))
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case strings.HasPrefix(data, "shop_edit") && accessLevel == "admin":
-		handleShopEdit(bot, db, callback, shopState)
-		answerCallback(bot, callback.ID, "")
+		handleShopEdit(ctx, bot, db, callback, shopState)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case strings.HasPrefix(data, "audit") && (accessLevel == "admin" || isSuper):
+		handleAudit(ctx, bot, db, callback, userState, isSuper)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case strings.HasPrefix(data, "broadcast") && (accessLevel == "admin" || isSuper):
+		handleBroadcast(ctx, bot, db, callback, userState, isSuper)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case strings.HasPrefix(data, "settings") && accessLevel == "admin":
+		handleSettings(ctx, bot, db, callback, userState)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case data == "web_login" && web.Enabled() && (accessLevel == "admin" || isSuper):
+		sendWebLogin(ctx, bot, db, fromID)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case strings.HasPrefix(data, "export") && (accessLevel == "admin" || isSuper):
+		handleExport(ctx, bot, db, callback, userState, isSuper)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case strings.HasPrefix(data, "transfer") && accessLevel == "admin":
+		handleTransfer(ctx, bot, db, callback, userState)
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case strings.HasPrefix(data, "orders") && accessLevel == "admin":
-		handleOrderComplete(bot, db, callback)
-		answerCallback(bot, callback.ID, "")
+		handleOrderComplete(ctx, bot, db, callback)
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case strings.HasPrefix(data, "topup_") && (accessLevel == "admin" || accessLevel == "manager"):
-		handleTopUpCallback(bot, db, fromID, data, callback)
-		answerCallback(bot, callback.ID, "")
+		handleTopUpCallback(ctx, bot, db, fromID, data, callback)
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case data == "menu_list" && (accessLevel == "admin" || accessLevel == "manager"):
-		list, err := database.SendWorkersString(db, fromID)
-		msg := fmt.Sprintf("Актуальный список сотрудников:\n%s", list)
+		restNumber, err := database.SameRest(db, fromID)
+		var users []database.User
+		if err == nil {
+			users, err = database.ListRestUsers(db, int(restNumber), false)
+		}
+		msg := i18n.T(lang, "workers.list", view.UsersList(lang, users))
 		if err != nil {
-			msg = "Ошибка загрузки списка"
+			msg = i18n.T(lang, "workers.err_list")
 		}
 		bot.Send(tgbotapi.NewMessage(fromID, msg))
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case data == "show_balance" && accessLevel == "worker":
 		balance, err := database.GetBalance(db, fromID)
 		msg := ""
 		if err != nil {
-			msg = "Ошибка получения баланса!"
+			msg = i18n.T(lang, "balance.err")
 		} else {
-			msg = fmt.Sprintf("Ваш текущий баланс: %d🌟", balance)
+			msg = i18n.T(lang, "balance.current", balance) + balancePolicyText(ctx, db, fromID)
 		}
 		bot.Send(tgbotapi.NewMessage(fromID, msg))
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case data == "menu_market" && accessLevel == "worker":
 		features.ShowShop(bot, db, callback.Message.Chat.ID, fromID)
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	case strings.HasPrefix(data, "buy_product:") && accessLevel == "worker":
-		handleBuyCallback(bot, db, callback)
-		answerCallback(bot, callback.ID, "Покупка оформлена!")
+		handleBuyCallback(ctx, bot, db, callback)
+		answerCallback(ctx, bot, callback.ID, i18n.T(lang, "buy.done_answer"))
 		return
 
-	case data == "history_orders" && accessLevel == "worker":
-		list, err := database.SendHistoryOrders(db, fromID)
-		msg := fmt.Sprintf("История заказов:\n%s", list)
-		if err != nil {
-			msg = "Ошибка загрузки истории"
+	case strings.HasPrefix(data, "history_orders") && accessLevel == "worker":
+		page := 0
+		if p := strings.TrimPrefix(data, "history_orders:"); p != data {
+			page, _ = strconv.Atoi(p)
 		}
-		bot.Send(tgbotapi.NewMessage(fromID, msg))
-		answerCallback(bot, callback.ID, "")
+		sendOrdersHistory(bot, db, fromID, page)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case (data == "balance_history" || strings.HasPrefix(data, "bhist:")) && accessLevel == "worker":
+		handleBalanceHistory(ctx, bot, db, callback)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case (data == "kudos" || strings.HasPrefix(data, "kudos:") || strings.HasPrefix(data, "kudos_to:")) && accessLevel == "worker":
+		handleKudos(ctx, bot, db, callback, userState)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case (data == "kudos_queue" || strings.HasPrefix(data, "kudos_mod:")) && accessLevel == "admin":
+		handleKudosModeration(ctx, bot, db, callback)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case data == "profile" && accessLevel == "worker":
+		sendProfile(ctx, bot, db, fromID)
+		answerCallback(ctx, bot, callback.ID, "")
+		return
+
+	case (data == "leaderboard" || strings.HasPrefix(data, "lb:") || strings.HasPrefix(data, "lb_hide:")) && accessLevel != "":
+		handleLeaderboard(ctx, bot, db, callback)
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	default:
-		bot.Send(tgbotapi.NewMessage(fromID, fmt.Sprintf("⛔ Ошибка доступа. Ваш уровень: %s", accessLevel)))
-		answerCallback(bot, callback.ID, "")
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.access", accessLevel)))
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 	}
 
 }
 
-func answerCallback(bot *tgbotapi.BotAPI, callbackID, text string) {
+// Route — маршрут нажатия для метрик: данные кнопки до первого «:»
+// (approve:worker:5 → approve), чтобы число серий не росло с числом пользователей.
+func Route(data string) string {
+	route, _, _ := strings.Cut(data, ":")
+	if route == "" || len(route) > 32 || strings.TrimLeft(route, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") != "" {
+		return "other"
+	}
+	return route
+}
+
+// answerRegistrationError отвечает админу на кнопку заявки, которую не удалось решить:
+// уже рассмотрена, чужое предприятие — текстом правила, прочее — внутренней ошибкой.
+func answerRegistrationError(ctx context.Context, bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery, lang string, userID int64, err error) {
+	var ruleErr *i18n.Error
+	if errors.As(err, &ruleErr) {
+		answerCallback(ctx, bot, callback.ID, i18n.Err(lang, ruleErr))
+		return
+	}
+	slog.ErrorContext(ctx, "Ошибка решения по заявке на регистрацию", "user_id", userID, "err", err)
+	answerCallback(ctx, bot, callback.ID, i18n.T(lang, "err.internal"))
+}
+
+func answerCallback(ctx context.Context, bot *tgbotapi.BotAPI, callbackID, text string) {
 	cb := tgbotapi.NewCallback(callbackID, text)
 	if _, err := bot.Request(cb); err != nil {
-		log.Println("Ошибка отправки callback:", err)
+		slog.ErrorContext(ctx, "Ошибка ответа на callback", "err", err)
 	}
 }
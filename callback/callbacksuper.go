@@ -1,29 +1,430 @@
 package callback
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/outbox"
+	"tbViT/view"
 )
 
-func handleSuper(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState) {
+const superSearchLimit = 20
+
+func handleSuper(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState) {
 	data := cq.Data
 	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
+
+	// super_user:<действие>[:<аргумент>]
+	parts := strings.SplitN(data, ":", 3)
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	arg := ""
+	if len(parts) > 2 {
+		arg = parts[2]
+	}
 
-	switch data {
-	case "super_user:transition":
+	switch action {
+	case "transition":
 		userState[fromID] = &CorrectionState{
 			ID:    fromID,
 			Field: "super_user:wait_rest_number",
 		}
-		msg := tgbotapi.NewMessage(fromID, "Номер предприятия:")
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "super.ask_rest"))
+		bot.Send(msg)
+
+	case "access":
+		var row []tgbotapi.InlineKeyboardButton
+		for _, level := range database.ValidAccessLevels {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(level, "super_user:setaccess:"+level))
+		}
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "super.access_level"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+		bot.Send(msg)
+
+	case "setaccess":
+		before, _ := database.GetAccessLevel(db, fromID)
+		if err := database.ChangeAccess(db, fromID, arg); err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.err_access")))
+			return
+		}
+		superAudit(db, fromID, 0, "super_user:setaccess", strconv.FormatInt(fromID, 10), before, arg)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.access_set", arg)))
+
+	case "console":
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "super.console"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_rests"), "super_user:rests"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_search"), "super_user:search"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_supers"), "super_user:supers"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.audit"), "audit"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.export"), "export"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.broadcast"), "broadcast"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_jobs"), "super_user:jobs"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_outbox"), "super_user:outbox"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_backup"), "super_user:backup"),
+			),
+		)
+		bot.Send(msg)
+
+	case "rests":
+		sendRestaurantList(bot, db, fromID)
+
+	case "rest":
+		restNumber, ok := parseRestArg(bot, lang, fromID, arg)
+		if ok {
+			sendRestaurantCard(bot, db, fromID, restNumber)
+		}
+
+	case "staff", "orders", "shop":
+		restNumber, ok := parseRestArg(bot, lang, fromID, arg)
+		if !ok {
+			return
+		}
+		var list, title string
+		var err error
+		switch action {
+		case "staff":
+			title = i18n.T(lang, "super.view_staff")
+			var users []database.User
+			users, err = database.ListRestUsers(db, restNumber, true)
+			list = view.UsersList(lang, users)
+		case "orders":
+			title = i18n.T(lang, "super.view_orders")
+			var orders []database.Order
+			orders, err = database.ListOpenOrders(db, restNumber)
+			list = view.OpenOrdersList(orders)
+		case "shop":
+			title = i18n.T(lang, "super.view_shop")
+			var products []database.Product
+			products, err = database.ListProducts(db, restNumber, false)
+			list = view.ProductsList(lang, products)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка просмотра предприятия", "rest", restNumber, "action", action, "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.load_data")))
+			return
+		}
+		superAudit(db, fromID, restNumber, "super_user:view_"+action, "", "", "")
+		if list == "" {
+			list = i18n.T(lang, "list.empty")
+		}
+		bot.Send(tgbotapi.NewMessage(fromID, fmt.Sprintf("🏢 %d · %s:\n%s", restNumber, title, list)))
+
+	case "admins":
+		restNumber, ok := parseRestArg(bot, lang, fromID, arg)
+		if !ok {
+			return
+		}
+		users, err := database.ListRestUsers(db, restNumber, true)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "workers.err_load")))
+			return
+		}
+		if len(users) == 0 {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.no_staff")))
+			return
+		}
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "super.choose_admin", restNumber))
+		msg.ReplyMarkup = usersMarkup(lang, users, "super_user:setadmin:")
 		bot.Send(msg)
 
-	case "super_user:access":
+	case "setadmin":
+		uid, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.user_id")))
+			return
+		}
+		before, _ := database.GetUser(db, uid)
+		if err := database.AssignAdmin(db, uid); err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "role.err_admin", i18n.Err(lang, err))))
+			return
+		}
+		superAudit(db, fromID, before.RestNumber, "super_user:setadmin", arg, before.AccessLevel, "admin")
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.admin_assigned")))
+		outbox.Send(db, uid, database.Tr(db, uid, "super.admin_notice"))
+
+	case "freeze", "unfreeze":
+		restNumber, ok := parseRestArg(bot, lang, fromID, arg)
+		if !ok {
+			return
+		}
+		if err := database.SetRestFrozen(db, restNumber, action == "freeze"); err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.generic", i18n.Err(lang, err))))
+			return
+		}
+		superAudit(db, fromID, restNumber, "super_user:"+action, strconv.Itoa(restNumber), "", "")
+		sendRestaurantCard(bot, db, fromID, restNumber)
+
+	case "search":
 		userState[fromID] = &CorrectionState{
 			ID:    fromID,
-			Field: "super_user:wait_access_level",
+			Field: "super_user:wait_search",
 		}
-		msg := tgbotapi.NewMessage(fromID, "Уровень доступа(worker/manager/admin):")
-		bot.Send(msg)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.ask_search")))
+
+	case "user":
+		uid, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.user_id")))
+			return
+		}
+		sendUserCard(bot, db, fromID, uid)
+
+	case "supers":
+		sendSuperUsers(bot, db, fromID)
+
+	case "jobs":
+		sendJobs(bot, db, fromID)
+
+	case "outbox":
+		sendOutboxStats(bot, db, fromID)
+
+	case "backup":
+		sendBackup(ctx, bot, db, fromID)
+
+	case "outbox_requeue":
+		n, err := database.RequeueDeadOutbox(db)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "outbox.err_requeue")))
+			return
+		}
+		superAudit(db, fromID, 0, "super_user:outbox_requeue", "", "", strconv.Itoa(n))
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "outbox.requeued", n)))
+
+	case "jobrun":
+		if err := database.RunJobNow(db, arg); err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "jobs.err_run")))
+			return
+		}
+		superAudit(db, fromID, 0, "super_user:jobrun", arg, "", "")
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "jobs.run_scheduled", arg)))
+
+	case "jobspec":
+		userState[fromID] = &CorrectionState{
+			ID:    fromID,
+			Field: "super_user:wait_job_spec",
+			Value: arg,
+		}
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "jobs.ask_spec")))
+
+	case "addsuper":
+		userState[fromID] = &CorrectionState{
+			ID:    fromID,
+			Field: "super_user:wait_super_id",
+		}
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.ask_id")))
+
+	case "delsuper":
+		uid, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.user_id")))
+			return
+		}
+		if err := database.RemoveSuperUser(db, uid, fromID); err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, "❌ "+i18n.Err(lang, err)))
+			return
+		}
+		superAudit(db, fromID, 0, "super_user:delsuper", arg, "super", "")
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.removed")))
+		sendSuperUsers(bot, db, fromID)
+	}
+}
+
+// HandleSuperAdd добавляет суперпользователя по введённому Telegram ID.
+func HandleSuperAdd(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, text string) {
+	lang := database.UserLang(db, fromID)
+	uid, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	if err != nil || uid <= 0 {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.err_id")))
+		return
+	}
+	if err := database.AddSuperUser(db, uid, fromID); err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, "❌ "+i18n.Err(lang, err)))
+		return
+	}
+	superAudit(db, fromID, 0, "super_user:addsuper", strconv.FormatInt(uid, 10), "", "super")
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.added")))
+	outbox.Send(db, uid, database.Tr(db, uid, "super.added_notice"))
+}
+
+// superAudit фиксирует действие суперпользователя в audit_log.
+func superAudit(db *sql.DB, actorID int64, restNumber int, action, target, before, after string) {
+	database.Audit(db, database.AuditEntry{
+		ActorID:    actorID,
+		RestNumber: restNumber,
+		Action:     action,
+		Target:     target,
+		Before:     before,
+		After:      after,
+	})
+}
+
+func sendSuperUsers(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64) {
+	lang := database.UserLang(db, chatID)
+	supers, err := database.ListSuperUsers(db)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "super.err_load")))
+		return
+	}
+	var text strings.Builder
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+	text.WriteString(i18n.T(lang, "super.list_header"))
+	for _, su := range supers {
+		source := i18n.T(lang, "super.source_console")
+		if su.Source == "env" {
+			source = i18n.T(lang, "super.source_env")
+		}
+		text.WriteString(fmt.Sprintf("%d %s (%s)\n", su.TelegramID, su.Name, source))
+		if su.Source != "env" && su.TelegramID != chatID {
+			kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_remove", su.TelegramID), fmt.Sprintf("super_user:delsuper:%d", su.TelegramID)),
+			))
+		}
+	}
+	kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_add"), "super_user:addsuper"),
+	))
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+	bot.Send(msg)
+}
+
+// HandleSuperSearch обрабатывает текст поискового запроса суперпользователя.
+func HandleSuperSearch(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, query string) {
+	lang := database.UserLang(db, fromID)
+	users, err := database.SearchUsers(db, query, superSearchLimit)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.err_search", i18n.Err(lang, err))))
+		return
+	}
+	superAudit(db, fromID, 0, "super_user:search", query, "", "")
+	if len(users) == 0 {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "super.search_empty")))
+		return
+	}
+	msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "super.search_found", len(users)))
+	msg.ReplyMarkup = usersMarkup(lang, users, "super_user:user:")
+	bot.Send(msg)
+}
+
+func parseRestArg(bot *tgbotapi.BotAPI, lang string, chatID int64, arg string) (int, bool) {
+	restNumber, err := strconv.Atoi(arg)
+	if err != nil || restNumber <= 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "super.err_rest")))
+		return 0, false
+	}
+	return restNumber, true
+}
+
+func sendRestaurantList(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64) {
+	lang := database.UserLang(db, chatID)
+	rests, err := database.ListRestaurants(db)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "super.err_rests")))
+		return
+	}
+	if len(rests) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "super.rests_empty")))
+		return
+	}
+	var text strings.Builder
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+	text.WriteString(i18n.T(lang, "super.rests_header"))
+	for _, r := range rests {
+		frozen := ""
+		if r.Frozen {
+			frozen = " 🧊"
+		}
+		text.WriteString(fmt.Sprintf("%d — %d | %d | %d🌟%s\n", r.RestNumber, r.Staff, r.Admins, r.Balance, frozen))
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d%s", r.RestNumber, frozen), fmt.Sprintf("super_user:rest:%d", r.RestNumber)),
+		))
+	}
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+	bot.Send(msg)
+}
+
+func sendRestaurantCard(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, restNumber int) {
+	lang := database.UserLang(db, chatID)
+	r, err := database.GetRestaurantSummary(db, restNumber)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "super.err_rest_card")))
+		return
+	}
+	status := i18n.T(lang, "super.rest_active")
+	freeze := tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_freeze"), fmt.Sprintf("super_user:freeze:%d", restNumber))
+	if r.Frozen {
+		status = i18n.T(lang, "super.rest_frozen")
+		freeze = tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_unfreeze"), fmt.Sprintf("super_user:unfreeze:%d", restNumber))
+	}
+	text := i18n.T(lang, "super.rest_card",
+		restNumber, status, r.Staff, r.Admins, r.Balance)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "export.kind_staff"), fmt.Sprintf("super_user:staff:%d", restNumber)),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "export.kind_orders"), fmt.Sprintf("super_user:orders:%d", restNumber)),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_shop"), fmt.Sprintf("super_user:shop:%d", restNumber)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_assign_admin"), fmt.Sprintf("super_user:admins:%d", restNumber)),
+			freeze,
+		),
+	)
+	bot.Send(msg)
+}
+
+func sendUserCard(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, uid int64) {
+	lang := database.UserLang(db, chatID)
+	u, err := database.GetUser(db, uid)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "super.user_not_found")))
+		return
+	}
+	verified := i18n.T(lang, "common.no")
+	if u.Verified {
+		verified = i18n.T(lang, "common.yes")
+	}
+	text := i18n.T(lang, "super.user_card",
+		u.Name, u.Username, u.TelegramID, u.RestNumber, u.TableNumber, roleName(lang, u.AccessLevel), verified, u.Balance)
+	msg := tgbotapi.NewMessage(chatID, text)
+	var row []tgbotapi.InlineKeyboardButton
+	if u.RestNumber > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_rest"), fmt.Sprintf("super_user:rest:%d", u.RestNumber)))
+	}
+	if u.Verified && u.AccessLevel != "admin" {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "super.btn_make_admin"), fmt.Sprintf("super_user:setadmin:%d", u.TelegramID)))
+	}
+	if len(row) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(row)
+	}
+	bot.Send(msg)
+}
+
+func usersMarkup(lang string, users []database.User, prefix string) tgbotapi.InlineKeyboardMarkup {
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+	for _, u := range users {
+		btnText := fmt.Sprintf("%d · %s %s (%s)", u.RestNumber, u.TableNumber, u.Name, roleName(lang, u.AccessLevel))
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(btnText, fmt.Sprintf("%s%d", prefix, u.TelegramID)),
+		))
 	}
+	return tgbotapi.NewInlineKeyboardMarkup(kbRows...)
 }
@@ -0,0 +1,185 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tbViT/database"
+	"tbViT/export"
+	"tbViT/i18n"
+	"time"
+)
+
+// exportKinds — доступные выгрузки; withPeriod — нужен ли выбор периода.
+// Подпись кнопки — export.kind_<вид> в каталоге.
+var exportKinds = []struct {
+	Kind       string
+	WithPeriod bool
+}{
+	{"staff", false},
+	{"orders", true},
+	{"topups", true},
+	{"stock", false},
+}
+
+// handleExport выгружает данные в CSV/XLSX. Админ получает данные своего предприятия,
+// суперпользователь — всей сети.
+// Формат callback: export[:<вид>[:<период>[:<формат>]]]
+func handleExport(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState, isSuper bool) {
+	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
+	if strings.HasPrefix(cq.Data, "export_range:") {
+		kind := strings.TrimPrefix(cq.Data, "export_range:")
+		if withPeriod, ok := exportKindPeriod(kind); ok && withPeriod {
+			userState[fromID] = &CorrectionState{ID: fromID, Field: "export:wait_range", Value: kind}
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "export.ask_range")))
+		}
+		return
+	}
+	parts := strings.Split(cq.Data, ":")
+
+	switch len(parts) {
+	case 1:
+		var kbRows [][]tgbotapi.InlineKeyboardButton
+		for _, k := range exportKinds {
+			kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "export.kind_"+k.Kind), "export:"+k.Kind),
+			))
+		}
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "export.choose_kind"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+		bot.Send(msg)
+
+	case 2:
+		kind := parts[1]
+		withPeriod, ok := exportKindPeriod(kind)
+		if !ok {
+			return
+		}
+		if !withPeriod {
+			sendExportFormats(bot, lang, fromID, kind, "0")
+			return
+		}
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "export.choose_period"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.N(lang, "audit.period_days", 7), "export:"+kind+":7"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.N(lang, "audit.period_days", 30), "export:"+kind+":30"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.N(lang, "audit.period_days", 90), "export:"+kind+":90"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "history.period_all"), "export:"+kind+":0"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "export.btn_range"), "export_range:"+kind),
+			),
+		)
+		bot.Send(msg)
+
+	case 3:
+		if _, ok := database.ParsePeriod(parts[2]); !ok {
+			return
+		}
+		sendExportFormats(bot, lang, fromID, parts[1], parts[2])
+
+	case 4:
+		kind, periodValue, format := parts[1], parts[2], parts[3]
+		period, ok := database.ParsePeriod(periodValue)
+		if !ok || !export.IsValidFormat(format) {
+			return
+		}
+		restNumber := 0
+		if !isSuper {
+			restNumber = restOf(db, fromID)
+		}
+		sendExport(ctx, bot, db, fromID, kind, restNumber, period, format)
+	}
+}
+
+// HandleExportRange принимает свой период выгрузки в формате "2025-01-01 2025-01-31".
+func HandleExportRange(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, kind, text string) {
+	lang := database.UserLang(db, fromID)
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "export.err_range_format")))
+		return
+	}
+	from, err1 := time.Parse("2006-01-02", fields[0])
+	to, err2 := time.Parse("2006-01-02", fields[1])
+	if err1 != nil || err2 != nil || to.Before(from) {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "export.err_range")))
+		return
+	}
+	sendExportFormats(bot, lang, fromID, kind, from.Format("20060102")+"-"+to.Format("20060102"))
+}
+
+func exportKindPeriod(kind string) (bool, bool) {
+	for _, k := range exportKinds {
+		if k.Kind == kind {
+			return k.WithPeriod, true
+		}
+	}
+	return false, false
+}
+
+func sendExportFormats(bot *tgbotapi.BotAPI, lang string, chatID int64, kind, period string) {
+	prefix := fmt.Sprintf("export:%s:%s:", kind, period)
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "export.choose_format"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("CSV", prefix+export.FormatCSV),
+			tgbotapi.NewInlineKeyboardButtonData("XLSX", prefix+export.FormatXLSX),
+		),
+	)
+	bot.Send(msg)
+}
+
+func sendExport(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, kind string, restNumber int, period database.Period, format string) {
+	lang := database.UserLang(db, chatID)
+	var t database.Table
+	var err error
+	switch kind {
+	case "staff":
+		t, err = database.StaffTable(db, restNumber)
+	case "orders":
+		t, err = database.OrdersTable(db, restNumber, period)
+	case "topups":
+		t, err = database.TopUpsTable(db, restNumber, period)
+	case "stock":
+		t, err = database.StockTable(db, restNumber)
+	default:
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка выгрузки", "kind", kind, "user_id", chatID, "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "export.err")))
+		return
+	}
+	content, err := export.Render(t, format)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка формирования файла выгрузки", "kind", kind, "format", format, "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "export.err_render")))
+		return
+	}
+
+	scope := i18n.T(lang, "lb.scope_net")
+	name := fmt.Sprintf("%s_all_%s.%s", kind, time.Now().Format("2006-01-02"), format)
+	if restNumber != 0 {
+		scope = i18n.T(lang, "lb.scope_rest", restNumber)
+		name = fmt.Sprintf("%s_%d_%s.%s", kind, restNumber, time.Now().Format("2006-01-02"), format)
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: content})
+	doc.Caption = i18n.T(lang, "export.caption", scope, period.Label(lang), len(t.Rows))
+	if _, err := bot.Send(doc); err != nil {
+		slog.ErrorContext(ctx, "Ошибка отправки выгрузки", "file", name, "err", err)
+		return
+	}
+	database.Audit(db, database.AuditEntry{
+		ActorID:    chatID,
+		RestNumber: restNumber,
+		Action:     "export:" + kind,
+		Target:     format,
+		After:      period.String(),
+	})
+}
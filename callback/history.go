@@ -0,0 +1,186 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/view"
+)
+
+const historyPageSize = 10
+
+// historyPeriods — варианты фильтра по периоду (дней, 0 — всё время).
+var historyPeriods = []struct {
+	Days int
+	Key  string
+}{
+	{7, "history.period_week"},
+	{30, "history.period_month"},
+	{90, "history.period_3months"},
+	{0, "history.period_all"},
+}
+
+// handleBalanceHistory показывает историю баланса работника.
+// Формат callback: bhist:<страница>:<дней>:<тип|all>
+func handleBalanceHistory(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery) {
+	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
+	page, filter := 0, database.TxFilter{}
+
+	if strings.HasPrefix(cq.Data, "bhist:") {
+		parts := strings.Split(cq.Data, ":")
+		if len(parts) != 4 {
+			return
+		}
+		page, _ = strconv.Atoi(parts[1])
+		filter.Days, _ = strconv.Atoi(parts[2])
+		if parts[3] != "all" {
+			filter.Type = parts[3]
+		}
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	balance, _ := database.GetBalance(db, fromID)
+	msg, err := balanceHistoryPage(database.NewStore(db), lang, fromID, balance, filter, page)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка загрузки истории баланса", "user_id", fromID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "history.err_load")))
+		return
+	}
+	bot.Send(msg)
+}
+
+// balanceHistoryPage собирает страницу истории баланса; операции читаются через
+// database.TransactionRepository.
+func balanceHistoryPage(txRepo database.TransactionRepository, lang string, userID int64, balance int, filter database.TxFilter, page int) (tgbotapi.MessageConfig, error) {
+	txs, total, err := txRepo.ListTransactions(userID, filter, historyPageSize, page*historyPageSize)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, err
+	}
+	var text strings.Builder
+	text.WriteString(i18n.T(lang, "history.balance_header", balance, total))
+	for _, t := range txs {
+		text.WriteString(view.Transaction(lang, t) + "\n")
+	}
+	if len(txs) == 0 {
+		text.WriteString(i18n.T(lang, "history.empty"))
+	}
+
+	msg := tgbotapi.NewMessage(userID, text.String())
+	msg.ReplyMarkup = balanceHistoryMarkup(lang, page, total, filter)
+	return msg, nil
+}
+
+func bhistData(page int, f database.TxFilter) string {
+	txType := f.Type
+	if txType == "" {
+		txType = "all"
+	}
+	return fmt.Sprintf("bhist:%d:%d:%s", page, f.Days, txType)
+}
+
+func balanceHistoryMarkup(lang string, page, total int, f database.TxFilter) tgbotapi.InlineKeyboardMarkup {
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.prev"), bhistData(page-1, f)))
+	}
+	if (page+1)*historyPageSize < total {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.next"), bhistData(page+1, f)))
+	}
+	if len(nav) > 0 {
+		kbRows = append(kbRows, nav)
+	}
+
+	var periods []tgbotapi.InlineKeyboardButton
+	for _, p := range historyPeriods {
+		title := i18n.T(lang, p.Key)
+		if p.Days == f.Days {
+			title = "• " + title
+		}
+		periods = append(periods, tgbotapi.NewInlineKeyboardButtonData(title,
+			bhistData(0, database.TxFilter{Days: p.Days, Type: f.Type})))
+	}
+	kbRows = append(kbRows, periods)
+
+	allTitle := i18n.T(lang, "history.type_all")
+	if f.Type == "" {
+		allTitle = "• " + allTitle
+	}
+	types := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(allTitle, bhistData(0, database.TxFilter{Days: f.Days})),
+	}
+	for _, t := range database.TxTypes {
+		title := view.TxTypeName(lang, t)
+		if t == f.Type {
+			title = "• " + title
+		}
+		types = append(types, tgbotapi.NewInlineKeyboardButtonData(title,
+			bhistData(0, database.TxFilter{Days: f.Days, Type: t})))
+	}
+	kbRows = append(kbRows, types[:3], types[3:6], types[6:])
+
+	return tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+}
+
+// sendOrdersHistory показывает историю заказов работника постранично.
+func sendOrdersHistory(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, page int) {
+	lang := database.UserLang(db, fromID)
+	msg, err := ordersHistoryPage(database.NewStore(db), lang, fromID, page)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "history.err_load")))
+		return
+	}
+	bot.Send(msg)
+}
+
+// ordersHistoryPage собирает страницу истории заказов; заказы читаются через
+// database.OrderRepository.
+func ordersHistoryPage(orderRepo database.OrderRepository, lang string, userID int64, page int) (tgbotapi.MessageConfig, error) {
+	orders, total, err := orderRepo.ListUserOrders(userID, historyPageSize, page*historyPageSize)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, err
+	}
+	msg := tgbotapi.NewMessage(userID, i18n.T(lang, "history.orders", view.OrderHistory(lang, orders)))
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.prev"), fmt.Sprintf("history_orders:%d", page-1)))
+	}
+	if (page+1)*historyPageSize < total {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.next"), fmt.Sprintf("history_orders:%d", page+1)))
+	}
+	if len(nav) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(nav)
+	}
+	return msg, nil
+}
+
+// balancePolicyText — правила сгорания и максимальный баланс предприятия для экрана баланса.
+func balancePolicyText(ctx context.Context, db *sql.DB, fromID int64) string {
+	restNumber := restOf(db, fromID)
+	lang := database.UserLang(db, fromID)
+	var text strings.Builder
+	if months := database.GetRestSetting(db, restNumber, "stars_expire_months"); months > 0 {
+		text.WriteString(i18n.T(lang, "balance.expiry_policy", months))
+		amount, at, err := database.NextExpiry(db, fromID, months)
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка расчёта ближайшего сгорания", "user_id", fromID, "err", err)
+		} else if amount > 0 {
+			text.WriteString(i18n.T(lang, "balance.next_expiry", amount, at.Format("2006-01-02")))
+		}
+	}
+	if max := database.GetRestSetting(db, restNumber, "max_balance"); max > 0 {
+		text.WriteString(i18n.T(lang, "balance.max", max))
+	}
+	return text.String()
+}
@@ -0,0 +1,187 @@
+package callback
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tbViT/database"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeRepo отдаёт заранее заданные списки с учётом limit/offset, как база.
+type fakeRepo struct {
+	workers []database.User
+	orders  []database.Order
+	txs     []database.Transaction
+	err     error
+
+	gotLimit, gotOffset int
+}
+
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	return items[offset:min(offset+limit, len(items))]
+}
+
+func (f *fakeRepo) GetUser(telegramID int64) (database.User, error) {
+	for _, u := range f.workers {
+		if u.TelegramID == telegramID {
+			return u, nil
+		}
+	}
+	return database.User{}, errors.New("not found")
+}
+
+func (f *fakeRepo) ListRestUsers(restNumber int, verifiedOnly bool) ([]database.User, error) {
+	return f.workers, f.err
+}
+
+func (f *fakeRepo) ListWorkersPage(restNumber, limit, offset int) ([]database.User, int, error) {
+	f.gotLimit, f.gotOffset = limit, offset
+	return page(f.workers, limit, offset), len(f.workers), f.err
+}
+
+func (f *fakeRepo) ListUserOrders(telegramID int64, limit, offset int) ([]database.Order, int, error) {
+	f.gotLimit, f.gotOffset = limit, offset
+	return page(f.orders, limit, offset), len(f.orders), f.err
+}
+
+func (f *fakeRepo) ListTransactions(telegramID int64, _ database.TxFilter, limit, offset int) ([]database.Transaction, int, error) {
+	f.gotLimit, f.gotOffset = limit, offset
+	return page(f.txs, limit, offset), len(f.txs), f.err
+}
+
+func (f *fakeRepo) GetOrder(id int) (database.Order, error)                 { return database.Order{}, f.err }
+func (f *fakeRepo) ListOpenOrders(restNumber int) ([]database.Order, error) { return nil, f.err }
+
+var (
+	_ database.UserRepository        = (*fakeRepo)(nil)
+	_ database.OrderRepository       = (*fakeRepo)(nil)
+	_ database.TransactionRepository = (*fakeRepo)(nil)
+)
+
+// callbacks — данные всех кнопок разметки сообщения.
+func callbacks(msg tgbotapi.MessageConfig) []string {
+	markup, ok := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok {
+		return nil
+	}
+	var data []string
+	for _, row := range markup.InlineKeyboard {
+		for _, b := range row {
+			if b.CallbackData != nil {
+				data = append(data, *b.CallbackData)
+			}
+		}
+	}
+	return data
+}
+
+func has(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOrdersHistoryPage(t *testing.T) {
+	orders := make([]database.Order, 25)
+	for i := range orders {
+		orders[i] = database.Order{ID: i + 1, Product: "товар", Status: database.OrderAccepted}
+	}
+	tests := []struct {
+		name       string
+		page       int
+		offset     int
+		prev, next bool
+	}{
+		{"первая", 0, 0, false, true},
+		{"средняя", 1, 10, true, true},
+		{"последняя", 2, 20, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{orders: orders}
+			msg, err := ordersHistoryPage(repo, "ru", 42, tt.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo.gotLimit != historyPageSize || repo.gotOffset != tt.offset {
+				t.Errorf("limit/offset = %d/%d, want %d/%d", repo.gotLimit, repo.gotOffset, historyPageSize, tt.offset)
+			}
+			data := callbacks(msg)
+			if got := has(data, "history_orders:"+strconv.Itoa(tt.page-1)); got != tt.prev {
+				t.Errorf("кнопка назад = %v, want %v (%v)", got, tt.prev, data)
+			}
+			if got := has(data, "history_orders:"+strconv.Itoa(tt.page+1)); got != tt.next {
+				t.Errorf("кнопка вперёд = %v, want %v (%v)", got, tt.next, data)
+			}
+		})
+	}
+}
+
+func TestOrdersHistoryPageSingle(t *testing.T) {
+	repo := &fakeRepo{orders: []database.Order{{ID: 1, Product: "чай"}}}
+	msg, err := ordersHistoryPage(repo, "ru", 42, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ReplyMarkup != nil {
+		t.Errorf("одна страница — без навигации, got %v", msg.ReplyMarkup)
+	}
+	if !strings.Contains(msg.Text, "чай") {
+		t.Errorf("нет заказа в тексте: %q", msg.Text)
+	}
+}
+
+func TestBalanceHistoryPageEmpty(t *testing.T) {
+	repo := &fakeRepo{}
+	msg, err := balanceHistoryPage(repo, "ru", 42, 7, database.TxFilter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ChatID != 42 {
+		t.Errorf("ChatID = %d", msg.ChatID)
+	}
+	for _, d := range callbacks(msg) {
+		if d == bhistData(1, database.TxFilter{}) {
+			t.Errorf("пустая история не листается: %v", callbacks(msg))
+		}
+	}
+}
+
+func TestWorkersPage(t *testing.T) {
+	if _, err := workersPage(&fakeRepo{}, "ru", 1, "", "не число", 0); err == nil {
+		t.Error("нечисловой номер предприятия должен давать ошибку")
+	}
+	want := errors.New("база недоступна")
+	if _, err := workersPage(&fakeRepo{err: want}, "ru", 1, "", "3", 0); !errors.Is(err, want) {
+		t.Errorf("err = %v, want %v", err, want)
+	}
+
+	repo := &fakeRepo{}
+	for i := 0; i < workersPageSize+1; i++ {
+		repo.workers = append(repo.workers, database.User{TelegramID: int64(i + 1), Name: "работник"})
+	}
+	if _, err := workersPage(repo, "ru", 1, "", "3", 1); err != nil {
+		t.Fatal(err)
+	}
+	if repo.gotOffset != workersPageSize {
+		t.Errorf("offset = %d, want %d", repo.gotOffset, workersPageSize)
+	}
+
+	msg, err := workersPage(repo, "ru", 1, "", "3", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ReplyMarkup != nil {
+		t.Errorf("за концом списка — сообщение без кнопок, got %v", msg.ReplyMarkup)
+	}
+}
@@ -0,0 +1,90 @@
+package callback
+
+import (
+	"database/sql"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/scheduler"
+	"time"
+)
+
+func formatJobTime(ts int64) string {
+	if ts == 0 {
+		return "—"
+	}
+	return time.Unix(ts, 0).Format("2006-01-02 15:04")
+}
+
+// sendJobs показывает задачи планировщика в консоли суперпользователя.
+func sendJobs(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64) {
+	lang := database.UserLang(db, chatID)
+	jobs, err := database.ListJobs(db)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "jobs.err_load")))
+		return
+	}
+	if len(jobs) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "jobs.empty")))
+		return
+	}
+	var text strings.Builder
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+	text.WriteString(i18n.T(lang, "jobs.header"))
+	for _, j := range jobs {
+		status := "✅"
+		if j.LastError != "" {
+			status = "❌ " + j.LastError
+		}
+		text.WriteString(i18n.T(lang, "jobs.line",
+			j.Name, j.Spec, formatJobTime(j.LastRun), status, formatJobTime(j.NextRun)))
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("▶️ "+j.Name, "super_user:jobrun:"+j.Name),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "jobs.btn_spec"), "super_user:jobspec:"+j.Name),
+		))
+	}
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+	bot.Send(msg)
+}
+
+// sendOutboxStats показывает состояние исходящей очереди уведомлений.
+func sendOutboxStats(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64) {
+	lang := database.UserLang(db, chatID)
+	s, err := database.GetOutboxStats(db)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "outbox.err_load")))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "outbox.stats",
+		s.Pending, s.Sent, s.Dead))
+	if s.Dead > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "outbox.btn_requeue"), "super_user:outbox_requeue"),
+		))
+	}
+	bot.Send(msg)
+}
+
+// HandleJobSpec меняет расписание задачи на введённое cron-выражение.
+func HandleJobSpec(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, name, text string) {
+	lang := database.UserLang(db, fromID)
+	spec := strings.TrimSpace(text)
+	if _, err := scheduler.ParseSpec(spec); err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "jobs.err_spec", err)))
+		return
+	}
+	job, err := database.GetJob(db, name)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "jobs.not_found")))
+		return
+	}
+	if err := database.SetJobSpec(db, name, spec); err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "jobs.err_save")))
+		return
+	}
+	superAudit(db, fromID, 0, "super_user:jobspec", name, job.Spec, spec)
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "jobs.saved")))
+	sendJobs(bot, db, fromID)
+}
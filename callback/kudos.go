@@ -0,0 +1,229 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/outbox"
+	"unicode/utf8"
+)
+
+const kudosPageSize = 15
+
+// handleKudos — благодарности коллегам со стороны работника.
+// Формат callback: kudos | kudos:<страница> | kudos_to:<telegramID>
+func handleKudos(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState) {
+	fromID := cq.From.ID
+	data := cq.Data
+	lang := database.UserLang(db, fromID)
+
+	left, allowance, err := database.KudosLeft(db, fromID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка расчёта лимита благодарностей", "user_id", fromID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.err_load")))
+		return
+	}
+
+	if strings.HasPrefix(data, "kudos_to:") {
+		toID, err := strconv.ParseInt(strings.TrimPrefix(data, "kudos_to:"), 10, 64)
+		if err != nil {
+			return
+		}
+		if left == 0 {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.limit_reached", allowance)))
+			return
+		}
+		to, err := database.GetUser(db, toID)
+		if err != nil || toID == fromID || to.RestNumber != restOf(db, fromID) || to.AccessLevel != "worker" {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.err_target")))
+			return
+		}
+		userState[fromID] = &CorrectionState{ID: toID, Field: "kudos:wait_message"}
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.ask_message",
+			to.Name, to.TableNumber, database.KudosMaxLength)))
+		return
+	}
+
+	page := 0
+	if p := strings.TrimPrefix(data, "kudos:"); p != data {
+		page, _ = strconv.Atoi(p)
+	}
+	users, err := database.ListRestUsers(db, restOf(db, fromID), true)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "workers.err_load")))
+		return
+	}
+	var colleagues []database.User
+	for _, u := range users {
+		if u.AccessLevel == "worker" && u.TelegramID != fromID {
+			colleagues = append(colleagues, u)
+		}
+	}
+	received, _ := database.CountReceivedKudos(db, fromID)
+
+	text := i18n.T(lang, "kudos.header", left, allowance, received)
+	if left == 0 || len(colleagues) == 0 {
+		if len(colleagues) == 0 {
+			text += i18n.T(lang, "kudos.no_colleagues")
+		}
+		bot.Send(tgbotapi.NewMessage(fromID, text))
+		return
+	}
+	text += i18n.T(lang, "kudos.choose")
+
+	if page < 0 || page*kudosPageSize >= len(colleagues) {
+		page = 0
+	}
+	end := (page + 1) * kudosPageSize
+	if end > len(colleagues) {
+		end = len(colleagues)
+	}
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+	for _, u := range colleagues[page*kudosPageSize : end] {
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s %s", u.TableNumber, u.Name), fmt.Sprintf("kudos_to:%d", u.TelegramID))))
+	}
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.prev"), fmt.Sprintf("kudos:%d", page-1)))
+	}
+	if end < len(colleagues) {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.next"), fmt.Sprintf("kudos:%d", page+1)))
+	}
+	if len(nav) > 0 {
+		kbRows = append(kbRows, nav)
+	}
+	msg := tgbotapi.NewMessage(fromID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+	bot.Send(msg)
+}
+
+// HandleKudosMessage принимает текст благодарности и отправляет её админам на модерацию.
+func HandleKudosMessage(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID, toID int64, text string) {
+	lang := database.UserLang(db, fromID)
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > database.KudosMaxLength {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.err_length", database.KudosMaxLength)))
+		return
+	}
+	if left, _, err := database.KudosLeft(db, fromID); err != nil || left == 0 {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.limit_reached_short")))
+		return
+	}
+	id, err := database.CreateKudos(db, fromID, toID, text)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка сохранения благодарности", "user_id", fromID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.err_save")))
+		return
+	}
+	k, err := database.GetKudos(db, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка загрузки благодарности", "kudos_id", id, "err", err)
+		return
+	}
+
+	admins, err := database.GetRestAdminIDs(db, k.RestNumber)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка поиска админов для благодарности", "kudos_id", id, "err", err)
+	}
+	for _, adminID := range admins {
+		adminLang := database.UserLang(db, adminID)
+		msg := tgbotapi.NewMessage(adminID, i18n.T(adminLang, "kudos.new_for_mod", kudosText(adminLang, k)))
+		msg.ReplyMarkup = kudosModMarkup(adminLang, id)
+		outbox.Enqueue(db, msg)
+	}
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.sent")))
+}
+
+func kudosText(lang string, k database.Kudos) string {
+	return i18n.T(lang, "kudos.text", k.FromName, k.ToName, k.Message)
+}
+
+func kudosModMarkup(lang string, id int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.approve"), fmt.Sprintf("kudos_mod:%d:ok", id)),
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.reject"), fmt.Sprintf("kudos_mod:%d:no", id)),
+	))
+}
+
+// handleKudosModeration — очередь благодарностей админа и решения по ним.
+// Формат callback: kudos_queue | kudos_mod:<id>:<ok|no>
+func handleKudosModeration(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery) {
+	fromID := cq.From.ID
+	restNumber := restOf(db, fromID)
+	lang := database.UserLang(db, fromID)
+
+	if cq.Data == "kudos_queue" {
+		list, err := database.PendingKudos(db, restNumber)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.err_load")))
+			return
+		}
+		if len(list) == 0 {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.queue_empty")))
+			return
+		}
+		for _, k := range list {
+			msg := tgbotapi.NewMessage(fromID, kudosText(lang, k))
+			msg.ReplyMarkup = kudosModMarkup(lang, k.ID)
+			bot.Send(msg)
+		}
+		return
+	}
+
+	parts := strings.Split(cq.Data, ":")
+	if len(parts) != 3 {
+		return
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return
+	}
+	k, err := database.GetKudos(db, id)
+	if err != nil || k.RestNumber != restNumber {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.not_found")))
+		return
+	}
+
+	if parts[2] == "ok" {
+		stars, err := database.ApproveKudosContext(ctx, db, id, fromID)
+		if err == database.ErrKudosDecided {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.already_decided")))
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка одобрения благодарности", "kudos_id", id, "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.err_approve")))
+			return
+		}
+		database.AuditAction(db, fromID, "kudos_approve", strconv.Itoa(id), "", strconv.Itoa(stars))
+		toLang := database.UserLang(db, k.ToID)
+		notice := i18n.T(toLang, "kudos.received", k.FromName, k.Message)
+		if stars > 0 {
+			notice += i18n.T(toLang, "kudos.received_stars", stars)
+		}
+		outbox.Send(db, k.ToID, notice)
+		outbox.Send(db, k.FromID, database.Tr(db, k.FromID, "kudos.approved_notice", k.ToName))
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.approved")))
+		return
+	}
+
+	if err := database.RejectKudos(db, id, fromID); err != nil {
+		if err == database.ErrKudosDecided {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.already_decided")))
+			return
+		}
+		slog.ErrorContext(ctx, "Ошибка отклонения благодарности", "kudos_id", id, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.err_reject")))
+		return
+	}
+	database.AuditAction(db, fromID, "kudos_reject", strconv.Itoa(id), "", "")
+	outbox.Send(db, k.FromID, database.Tr(db, k.FromID, "kudos.rejected_notice", k.ToName))
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "kudos.rejected")))
+}
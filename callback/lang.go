@@ -0,0 +1,44 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// handleLang — выбор языка интерфейса: lang показывает языки, lang:<код> сохраняет выбор.
+func handleLang(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, callback *tgbotapi.CallbackQuery) {
+	fromID := callback.From.ID
+	code := strings.TrimPrefix(callback.Data, "lang:")
+	if code == callback.Data {
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, l := range i18n.Langs {
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.LangNames[l], "lang:"+l)))
+		}
+		msg := tgbotapi.NewMessage(fromID, database.Tr(db, fromID, "lang.choose"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+		bot.Send(msg)
+		return
+	}
+
+	if err := database.SetUserLang(db, fromID, code); err != nil {
+		slog.ErrorContext(ctx, "Ошибка сохранения языка", "user_id", fromID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, database.Tr(db, fromID, "err.db")))
+		return
+	}
+	lang := database.UserLang(db, fromID)
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "lang.set", i18n.LangNames[lang])))
+}
+
+// roleName — название роли на языке lang.
+func roleName(lang, role string) string {
+	if !database.IsValidAccessLevel(role) {
+		return role
+	}
+	return i18n.T(lang, "role."+role)
+}
@@ -0,0 +1,149 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+const leaderboardSize = 10
+
+// leaderboardPeriods — варианты периода рейтинга; подпись — lb.period_<период> в каталоге.
+var leaderboardPeriods = []string{database.PeriodWeek, database.PeriodMonth, database.PeriodAll}
+
+// handleLeaderboard показывает рейтинг по предприятию или по всей сети.
+// Формат callback: leaderboard | lb:<week|month|all>:<rest|net> | lb_hide:<1|0>
+func handleLeaderboard(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery) {
+	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
+	period, scope := database.PeriodWeek, "rest"
+
+	switch {
+	case strings.HasPrefix(cq.Data, "lb_hide:"):
+		hidden := strings.TrimPrefix(cq.Data, "lb_hide:") == "1"
+		if err := database.SetLeaderboardHidden(db, fromID, hidden); err != nil {
+			slog.ErrorContext(ctx, "Ошибка смены видимости в рейтинге", "user_id", fromID, "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "settings.err_save_short")))
+			return
+		}
+		if hidden {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "lb.hidden")))
+		} else {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "lb.shown")))
+		}
+	case strings.HasPrefix(cq.Data, "lb:"):
+		parts := strings.Split(cq.Data, ":")
+		if len(parts) != 3 {
+			return
+		}
+		period, scope = parts[1], parts[2]
+	}
+
+	restNumber := 0
+	if scope == "rest" {
+		restNumber = restOf(db, fromID)
+	}
+	list, err := database.Leaderboard(db, period, restNumber)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка загрузки рейтинга", "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "lb.err_load")))
+		return
+	}
+	hidden := database.IsLeaderboardHidden(db, fromID)
+
+	var text strings.Builder
+	title := i18n.T(lang, "lb.scope_rest", restNumber)
+	if restNumber == 0 {
+		title = i18n.T(lang, "lb.scope_net")
+	}
+	text.WriteString(i18n.T(lang, "lb.header", title, periodTitle(lang, period)))
+
+	own := -1
+	for i, e := range list {
+		if e.TelegramID == fromID {
+			own = i
+		}
+		if i < leaderboardSize {
+			text.WriteString(leaderboardLine(lang, e, restNumber == 0, e.TelegramID == fromID) + "\n")
+		}
+	}
+	if len(list) == 0 {
+		text.WriteString(i18n.T(lang, "lb.empty"))
+	}
+
+	switch {
+	case own >= leaderboardSize:
+		text.WriteString("…\n" + leaderboardLine(lang, list[own], restNumber == 0, true) + "\n")
+	case hidden:
+		stars, _ := database.UserEarnings(db, fromID, period)
+		text.WriteString(i18n.T(lang, "lb.own_hidden", stars))
+	case own < 0:
+		text.WriteString(i18n.T(lang, "lb.own_absent"))
+	}
+
+	msg := tgbotapi.NewMessage(fromID, text.String())
+	msg.ReplyMarkup = leaderboardMarkup(lang, period, scope, hidden)
+	bot.Send(msg)
+}
+
+func periodTitle(lang, period string) string {
+	for _, p := range leaderboardPeriods {
+		if p == period {
+			return strings.ToLower(i18n.T(lang, "lb.period_"+p))
+		}
+	}
+	return period
+}
+
+func leaderboardLine(lang string, e database.LeaderboardEntry, withRest, own bool) string {
+	medal := fmt.Sprintf("%d.", e.Rank)
+	switch e.Rank {
+	case 1:
+		medal = "🥇"
+	case 2:
+		medal = "🥈"
+	case 3:
+		medal = "🥉"
+	}
+	line := fmt.Sprintf("%s %s (%s) — %d🌟", medal, e.Name, e.TableNumber, e.Stars)
+	if withRest {
+		line = i18n.T(lang, "lb.line_net", medal, e.Name, e.TableNumber, e.RestNumber, e.Stars)
+	}
+	if own {
+		line = i18n.T(lang, "lb.line_own", line)
+	}
+	return line
+}
+
+func leaderboardMarkup(lang, period, scope string, hidden bool) tgbotapi.InlineKeyboardMarkup {
+	var periods []tgbotapi.InlineKeyboardButton
+	for _, p := range leaderboardPeriods {
+		title := i18n.T(lang, "lb.period_"+p)
+		if p == period {
+			title = "• " + title
+		}
+		periods = append(periods, tgbotapi.NewInlineKeyboardButtonData(title, "lb:"+p+":"+scope))
+	}
+
+	restTitle, netTitle := i18n.T(lang, "lb.btn_rest"), i18n.T(lang, "lb.btn_net")
+	if scope == "net" {
+		netTitle = "• " + netTitle
+	} else {
+		restTitle = "• " + restTitle
+	}
+	scopes := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(restTitle, "lb:"+period+":rest"),
+		tgbotapi.NewInlineKeyboardButtonData(netTitle, "lb:"+period+":net"),
+	)
+
+	hide := tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "lb.btn_hide"), "lb_hide:1")
+	if hidden {
+		hide = tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "lb.btn_show"), "lb_hide:0")
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(periods, scopes, tgbotapi.NewInlineKeyboardRow(hide))
+}
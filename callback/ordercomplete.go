@@ -1,15 +1,16 @@
 package callback
 
 import (
+	"context"
 	"database/sql"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 	"tbViT/features"
 )
 
-func handleOrderComplete(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery) {
+func handleOrderComplete(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery) {
 	data := cq.Data
 	fromID := cq.From.ID
 
@@ -21,7 +22,7 @@ func handleOrderComplete(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.Callback
 		parts := strings.Split(data, ":")
 		orderID, err := strconv.Atoi(parts[1])
 		if err != nil {
-			log.Printf("Ошибка получения ID заказа: %v", err)
+			slog.WarnContext(ctx, "Некорректный ID заказа", "err", err)
 		}
 
 		if len(parts) == 2 {
@@ -29,7 +30,7 @@ func handleOrderComplete(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.Callback
 		}
 		if len(parts) == 3 {
 			decision := parts[2]
-			features.CompliteOrders(bot, db, fromID, orderID, decision)
+			features.CompliteOrders(ctx, bot, db, fromID, orderID, decision)
 		}
 	}
 }
@@ -0,0 +1,44 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// sendProfile показывает профиль работника: баланс, заработок и достижения.
+func sendProfile(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID int64) {
+	lang := database.UserLang(db, fromID)
+	got, err := database.GetUserAchievements(db, fromID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка загрузки достижений", "user_id", fromID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "profile.err_load")))
+		return
+	}
+	u, err := database.GetUser(db, fromID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "profile.err_load")))
+		return
+	}
+	lifetime, _ := database.UserEarnings(db, fromID, database.PeriodAll)
+	kudos, _ := database.CountReceivedKudos(db, fromID)
+
+	var text strings.Builder
+	text.WriteString(i18n.T(lang, "profile.header", u.Name, u.TableNumber, u.Balance, lifetime, kudos))
+	text.WriteString(i18n.T(lang, "profile.achievements", len(got), len(database.Achievements)))
+	for _, a := range database.Achievements {
+		line := fmt.Sprintf("🔒 %s — %s", a.Title(lang), a.Description(lang))
+		if ua, ok := got[a.Code]; ok {
+			line = i18n.T(lang, "profile.achievement_got", a.Icon, a.Title(lang), ua.AwardedAt.Format("2006-01-02"))
+		} else if a.Bonus > 0 {
+			line += fmt.Sprintf(" (+%d🌟)", a.Bonus)
+		}
+		text.WriteString(line + "\n")
+	}
+	bot.Send(tgbotapi.NewMessage(fromID, text.String()))
+}
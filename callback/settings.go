@@ -0,0 +1,106 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// handleSettings показывает и меняет настройки предприятия админа.
+// Формат callback: settings | settings_edit:<ключ>
+func handleSettings(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState) {
+	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
+	if strings.HasPrefix(cq.Data, "settings_edit:") {
+		key := strings.TrimPrefix(cq.Data, "settings_edit:")
+		for _, s := range database.RestSettings {
+			if s.Key == key {
+				userState[fromID] = &CorrectionState{ID: fromID, Field: "settings:wait_value", Value: key}
+				bot.Send(tgbotapi.NewMessage(fromID, fmt.Sprintf("%s, %s:", s.Title(lang), s.Unit(lang))+"\n"+i18n.T(lang, "settings.reset_hint")))
+				return
+			}
+		}
+		return
+	}
+	sendSettings(bot, db, fromID)
+}
+
+func sendSettings(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64) {
+	restNumber := restOf(db, chatID)
+	lang := database.UserLang(db, chatID)
+	var text strings.Builder
+	var kbRows [][]tgbotapi.InlineKeyboardButton
+	text.WriteString(i18n.T(lang, "settings.header", restNumber))
+	for _, s := range database.RestSettings {
+		value, custom := database.LookupRestSetting(db, restNumber, s.Key)
+		if custom {
+			text.WriteString(fmt.Sprintf("%s: %d\n", s.Title(lang), value))
+		} else {
+			text.WriteString(i18n.T(lang, "settings.default_value", s.Title(lang), value))
+		}
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ "+s.Title(lang), "settings_edit:"+s.Key),
+		))
+	}
+	msg := tgbotapi.NewMessage(chatID, text.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+	bot.Send(msg)
+}
+
+// HandleSettingValue сохраняет введённое админом значение настройки.
+func HandleSettingValue(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, key, text string) {
+	lang := database.UserLang(db, fromID)
+	if strings.TrimSpace(text) == "-" {
+		resetSetting(ctx, bot, db, fromID, lang, key)
+		return
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || value < 0 {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.non_negative_int")))
+		return
+	}
+	restNumber := restOf(db, fromID)
+	before := database.GetRestSetting(db, restNumber, key)
+	if err := database.SetRestSetting(db, restNumber, key, value); err != nil {
+		slog.ErrorContext(ctx, "Ошибка сохранения настройки", "setting", key, "rest", restNumber, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "settings.err_save")))
+		return
+	}
+	database.Audit(db, database.AuditEntry{
+		ActorID:    fromID,
+		RestNumber: restNumber,
+		Action:     "setting:" + key,
+		Target:     strconv.Itoa(restNumber),
+		Before:     strconv.Itoa(before),
+		After:      strconv.Itoa(value),
+	})
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "settings.saved")))
+	sendSettings(bot, db, fromID)
+}
+
+// resetSetting убирает значение, заданное админом: действует значение из конфигурации.
+func resetSetting(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, lang, key string) {
+	restNumber := restOf(db, fromID)
+	before := database.GetRestSetting(db, restNumber, key)
+	if err := database.ResetRestSetting(db, restNumber, key); err != nil {
+		slog.ErrorContext(ctx, "Ошибка сброса настройки", "setting", key, "rest", restNumber, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "settings.err_save")))
+		return
+	}
+	database.Audit(db, database.AuditEntry{
+		ActorID:    fromID,
+		RestNumber: restNumber,
+		Action:     "setting:" + key,
+		Target:     strconv.Itoa(restNumber),
+		Before:     strconv.Itoa(before),
+		After:      strconv.Itoa(database.GetRestSetting(db, restNumber, key)),
+	})
+	bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "settings.reset_done")))
+	sendSettings(bot, db, fromID)
+}
@@ -1,23 +1,28 @@
 package callback
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 	"tbViT/database"
 	"tbViT/features"
+	"tbViT/i18n"
+	"tbViT/service"
 )
 
-func handleShopEdit(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, shopState map[int64]*CorrectionState) {
+func handleShopEdit(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, shopState map[int64]*CorrectionState) {
 	data := cq.Data
 	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
 
 	switch {
 	case data == "shop_edit" && accessLevel == "admin":
-		features.ShowShopEdit(bot, fromID)
+		features.ShowShopEdit(bot, db, fromID)
 	case data == "shop_edit:choose" && accessLevel == "admin":
 		rows, _ := db.Query(`SELECT id, product, price, remains FROM shop WHERE rest_number=(
 			SELECT rest_number FROM users WHERE telegram_id=?)`, fromID)
@@ -29,16 +34,16 @@ func handleShopEdit(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery
 			var name string
 			rows.Scan(&id, &name, &price, &remains)
 			btn := tgbotapi.NewInlineKeyboardButtonData(
-				fmt.Sprintf("%s (%d🌟, %d шт.)", name, price, remains),
+				i18n.T(lang, "shop.item_btn", name, price, remains),
 				fmt.Sprintf("shop_edititem:%d", id),
 			)
 			keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(btn))
 		}
 		if !hasItems {
-			bot.Send(tgbotapi.NewMessage(fromID, "Нет товаров для редактирования."))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.edit_empty")))
 			return
 		}
-		msg := tgbotapi.NewMessage(fromID, "Выберите товар для редактирования:")
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.edit_choose"))
 		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboardRows...)
 		bot.Send(msg)
 
@@ -48,19 +53,19 @@ func handleShopEdit(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery
 		// Показываем меню для товара
 		btns := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("💲 Цена", fmt.Sprintf("shop_editfield:price:%d", id)),
-				tgbotapi.NewInlineKeyboardButtonData("📦 Остаток", fmt.Sprintf("shop_editfield:remains:%d", id)),
-				tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить", fmt.Sprintf("shop_editdel:%d", id)),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "shop.btn_price"), fmt.Sprintf("shop_editfield:price:%d", id)),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "shop.btn_remains"), fmt.Sprintf("shop_editfield:remains:%d", id)),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "shop.btn_delete"), fmt.Sprintf("shop_editdel:%d", id)),
 			),
 		)
-		msg := tgbotapi.NewMessage(fromID, "Что изменить?")
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.edit_what"))
 		msg.ReplyMarkup = btns
 		bot.Send(msg)
 
 	case strings.HasPrefix(data, "shop_editfield:"):
 		parts := strings.Split(data, ":")
 		if len(parts) != 3 {
-			bot.Send(tgbotapi.NewMessage(fromID, "Ошибка выбора поля"))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.err_field")))
 			return
 		}
 		field, sid := parts[1], parts[2]
@@ -70,73 +75,71 @@ func handleShopEdit(bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery
 		var msg string
 		switch field {
 		case "price":
-			msg = fmt.Sprintf("Введите новую цену товара(%s):", name)
+			msg = i18n.T(lang, "shop.ask_new_price", name)
 		case "remains":
-			msg = fmt.Sprintf("Введите остаток товара(%s):", name)
+			msg = i18n.T(lang, "shop.ask_new_remains", name)
 		}
 		bot.Send(tgbotapi.NewMessage(fromID, msg))
 
 	case strings.HasPrefix(data, "shop_editdel:"):
 		id, err := strconv.Atoi(strings.TrimPrefix(data, "shop_editdel:"))
 		if err != nil {
-			log.Printf("Ошибка конвертации в блоке (shop_editdel)", err)
+			slog.WarnContext(ctx, "Некорректный ID товара для удаления", "err", err)
 		}
-		err = database.DeleteProduct(db, id)
+		err = service.DeleteProduct(db, fromID, id)
 		if err != nil {
-			log.Printf("Ошибка удаления: %w", err)
-			bot.Send(tgbotapi.NewMessage(fromID, "❌ Ошибка удаления товара"))
+			slog.ErrorContext(ctx, "Ошибка удаления товара", "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.err_delete")))
 		} else {
-			bot.Send(tgbotapi.NewMessage(fromID, "✅ Товар удалён"))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.deleted")))
 		}
 		delete(shopState, fromID)
 
 	case data == "shop_edit:shop_add":
 		shopState[fromID] = &CorrectionState{Field: "wait_new_product_name"}
-		bot.Send(tgbotapi.NewMessage(fromID, "Введите название нового товара:"))
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.ask_name")))
 	}
 }
 
-func HandleShopMessage(bot *tgbotapi.BotAPI, db *sql.DB, msg *tgbotapi.Message, shopState map[int64]*CorrectionState) {
+func HandleShopMessage(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, msg *tgbotapi.Message, shopState map[int64]*CorrectionState) {
 	fromID := msg.From.ID
 	st, ok := shopState[fromID]
 	if !ok {
 		return
 	}
+	lang := database.UserLang(db, fromID)
 
 	switch st.Field {
 	// --- редактирование ---
-	case "wait_new_price":
-		price, err := strconv.Atoi(msg.Text)
+	case "wait_new_price", "wait_new_remains":
+		value, err := strconv.Atoi(msg.Text)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Вводите только число!⛔️"))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.number_only")))
 			return
 		}
-		if price < 0 {
-			bot.Send(tgbotapi.NewMessage(fromID, "Цена не может быть отрицательной!⛔️"))
-			return
-		}
-
-		_, err = db.Exec("UPDATE shop SET price=? WHERE id=?", price, st.ID)
+		p, err := database.GetProduct(db, int(st.ID))
 		if err == nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "✅ Цена обновлена!"))
-		} else {
-			bot.Send(tgbotapi.NewMessage(fromID, "❌ Не удалось обновить"))
-		}
-		delete(shopState, fromID)
-
-	case "wait_new_remains":
-		remains, err := strconv.Atoi(msg.Text)
-		if err != nil {
-			log.Println("Ошибка парсинга remains:", err)
-			bot.Send(tgbotapi.NewMessage(fromID, "Вводите только число!"))
+			done := "shop.price_updated"
+			if st.Field == "wait_new_price" {
+				p.Price = value
+			} else {
+				p.Remains = value
+				done = "shop.remains_updated"
+			}
+			err = service.UpdateProduct(db, fromID, p)
+			if err == nil {
+				bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, done)))
+			}
+		}
+		var ruleErr *i18n.Error
+		switch {
+		case errors.As(err, &ruleErr):
+			// Неверное значение — ждём исправленного
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.Err(lang, err)))
 			return
-		}
-		_, err = db.Exec("UPDATE shop SET remains=? WHERE id=?", remains, st.ID)
-		if err == nil {
-			log.Println("Ошибка UPDATE remains:", err)
-			bot.Send(tgbotapi.NewMessage(fromID, "✅ Остаток обновлён!"))
-		} else {
-			bot.Send(tgbotapi.NewMessage(fromID, "❌ Не удалось обновить"))
+		case err != nil:
+			slog.ErrorContext(ctx, "Ошибка изменения товара", "product_id", st.ID, "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.err_update")))
 		}
 		delete(shopState, fromID)
 
@@ -145,27 +148,27 @@ func HandleShopMessage(bot *tgbotapi.BotAPI, db *sql.DB, msg *tgbotapi.Message,
 		st.Value = msg.Text
 		st.Field = "wait_new_product_price"
 		shopState[fromID] = st
-		bot.Send(tgbotapi.NewMessage(fromID, "Введите цену товара:"))
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.ask_price")))
 
 	case "wait_new_product_price":
 		price, err := strconv.Atoi(msg.Text)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Вводите только число!⛔️"))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.number_only")))
 			return
 		}
 		if price < 0 {
-			bot.Send(tgbotapi.NewMessage(fromID, "Цена не может быть отрицательной!⛔️"))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.err_negative_price")))
 			return
 		}
 		st.Field = "wait_new_product_remains"
 		st.Value = fmt.Sprintf("%s|%d", st.Value, price)
 		shopState[fromID] = st
-		bot.Send(tgbotapi.NewMessage(fromID, "Введите количество товара:"))
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.ask_remains")))
 
 	case "wait_new_product_remains":
 		remains, err := strconv.Atoi(msg.Text)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Вводите только число!"))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.number_only")))
 			return
 		}
 		// value = "название|цена"
@@ -173,15 +176,20 @@ func HandleShopMessage(bot *tgbotapi.BotAPI, db *sql.DB, msg *tgbotapi.Message,
 		name := parts[0]
 		restNum, err := database.GetUserRestID(db, fromID)
 		if err != nil {
-			log.Println("ошибка получения номера ресторана при добавлении товара", err)
+			slog.ErrorContext(ctx, "Ошибка получения номера предприятия при добавлении товара", "err", err)
 		}
 		price, _ := strconv.Atoi(parts[1])
-		_, err = db.Exec("INSERT INTO shop (product, price, remains, rest_number) VALUES (?, ?, ?, ?)",
-			name, price, remains, restNum)
-		if err == nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "✅ Товар добавлен!"))
-		} else {
-			bot.Send(tgbotapi.NewMessage(fromID, "❌ Ошибка добавления"))
+		_, err = service.AddProduct(db, fromID, database.Product{RestNumber: restNum, Name: name, Price: price, Remains: remains})
+		var ruleErr *i18n.Error
+		switch {
+		case errors.As(err, &ruleErr):
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.Err(lang, err)))
+			return
+		case err != nil:
+			slog.ErrorContext(ctx, "Ошибка добавления товара", "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.err_add")))
+		default:
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "shop.added")))
 		}
 		delete(shopState, fromID)
 	}
@@ -1,19 +1,24 @@
 package callback
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
-	"tbViT/database" // Убедитесь, что пути импорта корректны
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/service"
 )
 
 // handleTopUpCallback обрабатывает callback-запросы, связанные с пополнением баланса.
 // Он парсит callback-данные, чтобы определить действие (выбор работника, выбор суммы)
 // и выполняет соответствующую операцию, напрямую используя ID из callback-данных.
 func handleTopUpCallback(
+	ctx context.Context,
 	bot *tgbotapi.BotAPI,
 	db *sql.DB,
 	fromID int64,                     // ID пользователя, который инициировал callback (менеджер/админ)
@@ -21,20 +26,21 @@ func handleTopUpCallback(
 	callback *tgbotapi.CallbackQuery, // объект callback-query
 // accessLevel string,    // Уровень доступа теперь проверяется раньше, здесь не нужен
 ) {
+	lang := database.UserLang(db, fromID)
 	switch {
 	// --- Инициация процесса пополнения ---
 	case data == "topup_":
 		dep, err := database.GetUserDep(db, fromID)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Ошибка поиска вашего предприятия."))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.rest_lookup")))
 			return
 		}
 		// Отправляем список работников. status "topup_select_worker" - это префикс для callback'ов выбора работника.
-		err = database.SendWorkersList(bot, db, fromID, "topup_select_worker", dep, 0)
+		err = sendWorkersList(bot, db, fromID, "topup_select_worker", dep, 0)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Не удалось отобразить список работников."))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "workers.err_show")))
 		}
-		answerCallback(bot, callback.ID, "")
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 
 	// --- Обработка выбора суммы пополнения ---
@@ -42,8 +48,8 @@ func handleTopUpCallback(
 		// Ожидаемый формат: "topup_amount:сумма:workerID"
 		parts := strings.Split(data, ":")
 		if len(parts) != 3 {
-			bot.Send(tgbotapi.NewMessage(fromID, "Ошибка: некорректный формат данных суммы пополнения."))
-			answerCallback(bot, callback.ID, "")
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err_data")))
+			answerCallback(ctx, bot, callback.ID, "")
 			return
 		}
 
@@ -52,47 +58,47 @@ func handleTopUpCallback(
 
 		amount, err := strconv.Atoi(amountStr)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Ошибка: неверная сумма пополнения."))
-			answerCallback(bot, callback.ID, "")
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err_amount")))
+			answerCallback(ctx, bot, callback.ID, "")
 			return
 		}
 
 		workerID, err := strconv.ParseInt(workerIDStr, 10, 64)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(fromID, "Ошибка: неверный ID работника."))
-			answerCallback(bot, callback.ID, "")
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err_worker_id")))
+			answerCallback(ctx, bot, callback.ID, "")
 			return
 		}
 
 		// Проверяем, что workerID валиден
 		if workerID <= 0 {
-			bot.Send(tgbotapi.NewMessage(fromID, "Ошибка: работник не выбран или ID некорректен."))
-			answerCallback(bot, callback.ID, "")
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err_no_worker")))
+			answerCallback(ctx, bot, callback.ID, "")
 			return
 		}
 
 		// Выполняем пополнение баланса работника
-		msg, isSuccess, err := database.TopUpBalance(db, workerID, amount)
-		if err != nil {
-			log.Printf("Ошибка TopUpBalance для workerID %d: %v", workerID, err)
-			bot.Send(tgbotapi.NewMessage(fromID, "Произошла ошибка при пополнении баланса."))
-			answerCallback(bot, callback.ID, "")
+		balance, err := service.TopUp(ctx, db, fromID, workerID, amount)
+		var ruleErr *i18n.Error
+		switch {
+		case errors.As(err, &ruleErr):
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.Err(lang, err)))
+		case err != nil:
+			slog.ErrorContext(ctx, "Ошибка начисления", "worker_id", workerID, "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err")))
+			answerCallback(ctx, bot, callback.ID, "")
 			return
-		}
-
-		bot.Send(tgbotapi.NewMessage(fromID, msg))
-
-		if isSuccess {
-			bot.Send(tgbotapi.NewMessage(workerID, fmt.Sprintf("Ваш баланс пополнен на %d 🌟!", amount)))
+		default:
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.done", amount, balance)))
 		}
 
 		// Сбрасываем tmp_field менеджера, т.к. операция завершена
 		// Это сделано для очистки состояния, даже если оно больше не используется напрямую.
-		_, err = db.Exec(`UPDATE users SET tmp_field='' WHERE telegram_id=?`, fromID)
+		_, err = db.Exec(`UPDATE users SET tmp_field=NULL WHERE telegram_id=?`, fromID)
 		if err != nil {
-			log.Printf("Ошибка сброса tmp_field для менеджера %d: %v", fromID, err)
+			slog.ErrorContext(ctx, "Ошибка сброса tmp_field", "user_id", fromID, "err", err)
 		}
-		answerCallback(bot, callback.ID, "Готово!")
+		answerCallback(ctx, bot, callback.ID, i18n.T(lang, "topup.done_answer"))
 		return
 
 	//  Обработка выбора работника (предполагается, что доступ уже проверен)
@@ -104,59 +110,59 @@ func handleTopUpCallback(
 				// Выбор конкретного работника
 				workerID, err := strconv.ParseInt(parts[1], 10, 64)
 				if err != nil {
-					bot.Send(tgbotapi.NewMessage(fromID, "Ошибка: некорректный ID работника."))
-					answerCallback(bot, callback.ID, "")
+					bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err_worker_id")))
+					answerCallback(ctx, bot, callback.ID, "")
 					return
 				}
 
-				workerInfo := database.GetWorkerInfo(db, workerID)
+				info, err := workerInfo(db, lang, workerID)
 				if err != nil {
-					log.Printf("Ошибка получения информации о работнике (ID %d): %v", workerID, err)
-					bot.Send(tgbotapi.NewMessage(fromID, "Ошибка получения информации о работнике."))
-					answerCallback(bot, callback.ID, "")
+					slog.ErrorContext(ctx, "Ошибка получения информации о работнике", "worker_id", workerID, "err", err)
+					bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "worker.err_info")))
+					answerCallback(ctx, bot, callback.ID, "")
 					return
 				}
 
 				// Формируем кнопки для выбора суммы, включая workerID в callback-данные
-				replyMarkup := tgbotapi.NewInlineKeyboardMarkup(
-					tgbotapi.NewInlineKeyboardRow(
-						tgbotapi.NewInlineKeyboardButtonData("1🌟", fmt.Sprintf("topup_amount:1:%d", workerID)),
-						tgbotapi.NewInlineKeyboardButtonData("2🌟", fmt.Sprintf("topup_amount:2:%d", workerID)),
-					),
-				)
-
-				msg := tgbotapi.NewMessage(fromID, fmt.Sprintf("%s\n\nВыберите сумму пополнения:", workerInfo))
+				var amountRow []tgbotapi.InlineKeyboardButton
+				for _, amount := range database.TopUpAmounts {
+					amountRow = append(amountRow, tgbotapi.NewInlineKeyboardButtonData(
+						fmt.Sprintf("%d🌟", amount), fmt.Sprintf("topup_amount:%d:%d", amount, workerID)))
+				}
+				replyMarkup := tgbotapi.NewInlineKeyboardMarkup(amountRow)
+
+				msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.choose_amount", info))
 				msg.ReplyMarkup = replyMarkup
 
 				bot.Send(msg)
-				answerCallback(bot, callback.ID, "")
+				answerCallback(ctx, bot, callback.ID, "")
 				return
 
 			} else if len(parts) == 4 {
 				// 👉 Обработка пагинации (перелистывание страниц)
 				page, err := strconv.Atoi(parts[1])
 				if err != nil {
-					bot.Send(tgbotapi.NewMessage(fromID, "Ошибка: некорректный номер страницы."))
-					answerCallback(bot, callback.ID, "")
+					bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.page")))
+					answerCallback(ctx, bot, callback.ID, "")
 					return
 				}
 				status := parts[2]
 				dep := parts[3]
 
-				err = database.SendWorkersList(bot, db, fromID, status, dep, page)
+				err = sendWorkersList(bot, db, fromID, status, dep, page)
 				if err != nil {
-					log.Printf("Ошибка при перелистывании списка работников (page %d, dep %s): %v", page, dep, err)
-					bot.Send(tgbotapi.NewMessage(fromID, "Ошибка при перелистывании списка."))
+					slog.ErrorContext(ctx, "Ошибка перелистывания списка работников", "page", page, "rest", dep, "err", err)
+					bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.paging")))
 				}
-				answerCallback(bot, callback.ID, "")
+				answerCallback(ctx, bot, callback.ID, "")
 				return
 			}
 		}
-		bot.Send(tgbotapi.NewMessage(fromID, "❌ Ошибка при выборе работника."))
-		answerCallback(bot, callback.ID, "")
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err_select")))
+		answerCallback(ctx, bot, callback.ID, "")
 		return
 	}
 
 	// Если data не подходит ни под одно из условий выше
-	answerCallback(bot, callback.ID, "")
+	answerCallback(ctx, bot, callback.ID, "")
 }
@@ -0,0 +1,185 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/outbox"
+	"tbViT/view"
+)
+
+// handleTransfer обрабатывает перевод сотрудника в другое предприятие.
+// Заявку создаёт админ текущего предприятия, принимает — админ предприятия назначения.
+func handleTransfer(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, cq *tgbotapi.CallbackQuery, userState map[int64]*CorrectionState) {
+	data := cq.Data
+	fromID := cq.From.ID
+	lang := database.UserLang(db, fromID)
+
+	switch {
+	case data == "transfer":
+		dep, err := database.GetUserDep(db, fromID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.rest_lookup")))
+			return
+		}
+		sendWorkersList(bot, db, fromID, "transfer_worker", dep, 0)
+
+	case strings.HasPrefix(data, "transfer_worker:"):
+		workerID, err := strconv.ParseInt(strings.TrimPrefix(data, "transfer_worker:"), 10, 64)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "topup.err_worker_id")))
+			return
+		}
+		adminRest, _ := database.SameRest(db, fromID)
+		workerRest, err := database.SameRest(db, workerID)
+		if err != nil || workerRest != adminRest {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.err_not_in_rest")))
+			return
+		}
+		info, err := workerInfo(db, lang, workerID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "worker.err_info")))
+			return
+		}
+		userState[fromID] = &CorrectionState{ID: workerID, Field: "transfer:wait_rest"}
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.ask_rest", info)))
+
+	case strings.HasPrefix(data, "transfer_mode:"):
+		state, ok := userState[fromID]
+		if !ok || state.Field != "transfer:wait_mode" {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.action_restart")))
+			return
+		}
+		mode := strings.TrimPrefix(data, "transfer_mode:")
+		if !database.IsValidTransferBalanceMode(mode) {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.err_mode")))
+			return
+		}
+		state.Field = "transfer:wait_orders"
+		state.Value = state.Value + "|" + mode
+
+		openOrders, _ := database.CountOpenOrders(db, state.ID)
+		msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.ask_orders", openOrders))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "transfer.btn_orders_cancel"), "transfer_orders:"+database.TransferOrdersCancel),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "transfer.btn_orders_complete"), "transfer_orders:"+database.TransferOrdersComplete),
+			),
+		)
+		bot.Send(msg)
+
+	case strings.HasPrefix(data, "transfer_orders:"):
+		state, ok := userState[fromID]
+		if !ok || state.Field != "transfer:wait_orders" {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.action_restart")))
+			return
+		}
+		delete(userState, fromID)
+
+		// state.Value = "предприятие|режим баланса"
+		parts := strings.Split(state.Value, "|")
+		toRest, _ := strconv.Atoi(parts[0])
+		ordersMode := strings.TrimPrefix(data, "transfer_orders:")
+		id, err := database.CreateTransfer(db, state.ID, toRest, parts[1], ordersMode, fromID)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.err_create", i18n.Err(lang, err))))
+			return
+		}
+		database.AuditAction(db, fromID, "transfer_request", strconv.FormatInt(state.ID, 10),
+			strconv.Itoa(restOf(db, fromID)), fmt.Sprintf("%d|%s|%s", toRest, parts[1], ordersMode))
+		sendTransferRequest(ctx, bot, db, id)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.sent", toRest)))
+
+	case strings.HasPrefix(data, "transfer_accept:"), strings.HasPrefix(data, "transfer_decline:"):
+		parts := strings.Split(data, ":")
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return
+		}
+		t, err := database.GetTransfer(db, id)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.not_found")))
+			return
+		}
+		adminRest, _ := database.GetUserRestID(db, fromID)
+		if adminRest != t.ToRest {
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.err_not_target")))
+			return
+		}
+
+		if parts[0] == "transfer_decline" {
+			if _, err := database.DeclineTransfer(db, id, fromID); err != nil {
+				bot.Send(tgbotapi.NewMessage(fromID, "❌ "+i18n.Err(lang, err)))
+				return
+			}
+			database.AuditAction(db, fromID, "transfer_decline", strconv.FormatInt(t.TelegramID, 10), "", strconv.Itoa(id))
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.declined")))
+			outbox.Send(db, t.InitiatorID, database.Tr(db, t.InitiatorID, "transfer.declined_notice", t.ToRest))
+			return
+		}
+
+		t, before, after, err := database.AcceptTransfer(db, id, fromID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка перевода", "transfer_id", id, "err", err)
+			bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.err_accept", i18n.Err(lang, err))))
+			return
+		}
+		database.AuditAction(db, fromID, "transfer_accept", strconv.FormatInt(t.TelegramID, 10),
+			fmt.Sprintf("%d|%d🌟", t.FromRest, before), fmt.Sprintf("%d|%d🌟", t.ToRest, after))
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "transfer.accepted")))
+		outbox.Send(db, t.InitiatorID, database.Tr(db, t.InitiatorID, "transfer.accepted_notice", t.ToRest))
+		outbox.Send(db, t.TelegramID, database.Tr(db, t.TelegramID,
+			"transfer.moved_notice", t.ToRest, before, after))
+	}
+}
+
+// sendTransferRequest уведомляет админов предприятия назначения о новой заявке на перевод.
+func sendTransferRequest(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, id int) {
+	t, err := database.GetTransfer(db, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка загрузки заявки на перевод", "transfer_id", id, "err", err)
+		return
+	}
+	adminIDs, err := database.GetRestAdminIDs(db, t.ToRest)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка поиска админов предприятия", "rest", t.ToRest, "err", err)
+		return
+	}
+	num, name, access, balance, _ := database.GetWorkerInfoValues(db, t.TelegramID)
+	for _, adminID := range adminIDs {
+		lang := database.UserLang(db, adminID)
+		text := i18n.T(lang, "transfer.request",
+			t.FromRest, num, name, roleName(lang, access), balance,
+			view.TransferBalanceMode(lang, t.BalanceMode), view.TransferOrdersMode(lang, t.OrdersMode))
+		msg := tgbotapi.NewMessage(adminID, text)
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.accept"), fmt.Sprintf("transfer_accept:%d", id)),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.reject"), fmt.Sprintf("transfer_decline:%d", id)),
+			),
+		)
+		outbox.Enqueue(db, msg)
+	}
+}
+
+// TransferBalanceModeMarkup клавиатура выбора режима переноса баланса.
+func TransferBalanceModeMarkup(lang string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "transfer.btn_carry"), "transfer_mode:"+database.TransferBalanceCarry),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("💱 %d%%", database.TransferConvertPercent), "transfer_mode:"+database.TransferBalanceConvert),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "transfer.btn_reset"), "transfer_mode:"+database.TransferBalanceReset),
+		),
+	)
+}
+
+func restOf(db *sql.DB, userID int64) int {
+	restNumber, _ := database.GetUserRestID(db, userID)
+	return restNumber
+}
@@ -0,0 +1,34 @@
+package callback
+
+import (
+	"context"
+	"database/sql"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/web"
+)
+
+// sendWebLogin присылает одноразовую ссылку для входа в веб-панель.
+func sendWebLogin(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID int64) {
+	lang := database.UserLang(db, fromID)
+	link, err := web.LoginLink(db, fromID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка выпуска ссылки веб-панели", "user_id", fromID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.internal")))
+		return
+	}
+	msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "web.link", link, int(database.WebLoginTTL.Minutes())))
+	// Предпросмотр не нужен: ссылка одноразовая и никому, кроме адресата, не полезна
+	msg.DisableWebPagePreview = true
+	// Telegram принимает в кнопках только публичные адреса; для http://localhost
+	// и подобных остаётся ссылка в тексте
+	if strings.HasPrefix(link, "https://") {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL(i18n.T(lang, "web.link_btn"), link),
+		))
+	}
+	bot.Send(msg)
+}
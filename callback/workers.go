@@ -0,0 +1,54 @@
+package callback
+
+import (
+	"database/sql"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/view"
+)
+
+// workersPageSize — работников на одной странице выбора.
+const workersPageSize = 15
+
+// sendWorkersList показывает страницу выбора работника предприятия dep;
+// выбранный работник приходит callback'ом "<status>:<telegram_id>".
+func sendWorkersList(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, status, dep string, page int) error {
+	lang := database.UserLang(db, chatID)
+	msg, err := workersPage(database.NewStore(db), lang, chatID, status, dep, page)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "workers.err_load")))
+		return err
+	}
+	bot.Send(msg)
+	return nil
+}
+
+// workersPage собирает страницу выбора работника. Данные читаются через
+// database.UserRepository, поэтому страницу можно проверить без базы.
+func workersPage(users database.UserRepository, lang string, chatID int64, status, dep string, page int) (tgbotapi.MessageConfig, error) {
+	restNumber, err := strconv.Atoi(dep)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, err
+	}
+	workers, total, err := users.ListWorkersPage(restNumber, workersPageSize, page*workersPageSize)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, err
+	}
+	if len(workers) == 0 {
+		return tgbotapi.NewMessage(chatID, i18n.T(lang, "workers.none")), nil
+	}
+	hasNext := (page+1)*workersPageSize < total
+	return view.WorkersPicker(lang, chatID, workers, status, dep, page, hasNext), nil
+}
+
+// workerInfo — карточка сотрудника для админа или менеджера.
+func workerInfo(db *sql.DB, lang string, workerID int64) (string, error) {
+	u, err := database.GetUser(db, workerID)
+	if err != nil {
+		return "", err
+	}
+	return view.WorkerInfo(lang, u), nil
+}
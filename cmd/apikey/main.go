@@ -0,0 +1,131 @@
+// Команда apikey выпускает и отзывает ключи HTTP API. База берётся из настроек
+// бота (config.yaml, DB_DRIVER, DB_DSN). Ключ показывается один раз — в базе
+// хранится только его хеш.
+//
+//	go run ./cmd/apikey create -owner 123456789 -rest 3 -name "1С"   # ключ предприятия 3
+//	go run ./cmd/apikey create -owner 123456789 -name "Отчёты"       # ключ суперпользователя
+//	go run ./cmd/apikey list
+//	go run ./cmd/apikey revoke <id>
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"tbViT/config"
+	"tbViT/database"
+
+	"github.com/joho/godotenv"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey create -owner <telegram id> [-rest <номер>] -name <название> | list | revoke <id>")
+	os.Exit(2)
+}
+
+func main() {
+	godotenv.Load()
+	cfg, err := config.Load("")
+	if err != nil {
+		log.Fatal("Ошибка чтения конфигурации: ", err)
+	}
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	db, err := database.Open(cfg.DB.Driver, cfg.DB.DSN)
+	if err != nil {
+		log.Fatal("Ошибка подключения к БД: ", err)
+	}
+	defer db.Close()
+	if err := database.Migrate(db, cfg.DB.Driver); err != nil {
+		log.Fatal("Ошибка миграции схемы: ", err)
+	}
+
+	switch cmd := os.Args[1]; cmd {
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ExitOnError)
+		owner := fs.Int64("owner", 0, "Telegram ID владельца: админ предприятия или суперпользователь")
+		rest := fs.Int("rest", 0, "номер предприятия; 0 — ключ суперпользователя для всех предприятий")
+		name := fs.String("name", "", "название ключа, например имя системы")
+		fs.Parse(os.Args[2:])
+		if *owner <= 0 || *name == "" || *rest < 0 {
+			usage()
+		}
+		if err := checkOwner(db, *owner, *rest); err != nil {
+			log.Fatal(err)
+		}
+		key, id, err := database.CreateAPIKey(db, *name, *rest, *owner)
+		if err != nil {
+			log.Fatal("Ошибка создания ключа: ", err)
+		}
+		database.AuditAction(db, *owner, "api_key_create", strconv.Itoa(id), "", *name)
+		fmt.Printf("Ключ %d создан. Сохраните его — показать повторно нельзя:\n%s\n", id, key)
+
+	case "list":
+		keys, err := database.ListAPIKeys(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, k := range keys {
+			scope := "все"
+			if k.RestNumber > 0 {
+				scope = strconv.Itoa(k.RestNumber)
+			}
+			state := "действует"
+			if k.Revoked {
+				state = "отозван"
+			}
+			used := "-"
+			if !k.LastUsed.IsZero() {
+				used = k.LastUsed.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%4d  %-20s  предприятие: %-4s  владелец: %-12d  %s  использован: %s\n",
+				k.ID, k.Name, scope, k.OwnerID, state, used)
+		}
+
+	case "revoke":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		id, err := strconv.Atoi(os.Args[2])
+		if err != nil {
+			usage()
+		}
+		if err := database.RevokeAPIKey(db, id); errors.Is(err, sql.ErrNoRows) {
+			log.Fatalf("Ключ %d не найден", id)
+		} else if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Ключ отозван")
+
+	default:
+		usage()
+	}
+}
+
+// checkOwner проверяет, что владелец вправе выпустить ключ: ключ для всех
+// предприятий — только суперпользователю, ключ предприятия — его админу.
+func checkOwner(db *sql.DB, owner int64, rest int) error {
+	if database.IsSuperUser(db, owner) {
+		return nil
+	}
+	if rest == 0 {
+		return fmt.Errorf("%d не суперпользователь: укажите -rest", owner)
+	}
+	u, err := database.GetUser(db, owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("пользователь %d не найден", owner)
+	}
+	if err != nil {
+		return err
+	}
+	if u.RestNumber != rest || u.AccessLevel != "admin" {
+		return fmt.Errorf("%d не админ предприятия %d", owner, rest)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Команда backup управляет резервными копиями базы SQLite. Настройки берутся так же,
+// как у бота: раздел backup файла config.yaml и переменные BACKUP_DIR, BACKUP_KEEP,
+// BACKUP_GZIP, BACKUP_KEY.
+//
+//	go run ./cmd/backup create            # снять копию
+//	go run ./cmd/backup list              # копии, новые первыми
+//	go run ./cmd/backup verify <файл>     # проверить целостность копии
+//	go run ./cmd/backup restore <файл>    # восстановить базу (бот должен быть остановлен)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"tbViT/backup"
+	"tbViT/config"
+	"tbViT/database"
+
+	"github.com/joho/godotenv"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: backup [-db botdata.db] create | list | verify <file> | restore <file>")
+	os.Exit(2)
+}
+
+func main() {
+	godotenv.Load()
+	cfg, err := config.Load("")
+	if err != nil {
+		log.Fatal("Ошибка чтения конфигурации: ", err)
+	}
+	cfg.Apply()
+	defaultPath := database.DefaultSQLitePath
+	if driver, _ := database.NormalizeDriver(cfg.DB.Driver); driver == database.DriverSQLite && cfg.DB.DSN != "" {
+		defaultPath = cfg.DB.DSN
+	}
+	dbPath := flag.String("db", defaultPath, "файл базы SQLite")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	switch cmd := flag.Arg(0); cmd {
+	case "create":
+		db, err := database.Open(database.DriverSQLite, *dbPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		f, err := backup.Create(db)
+		if err != nil {
+			log.Fatal("Ошибка резервного копирования: ", err)
+		}
+		fmt.Println(f.Path)
+
+	case "list":
+		files, err := backup.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, f := range files {
+			fmt.Printf("%s  %s  %d\n", f.CreatedAt.Format("2006-01-02 15:04:05"), f.Name, f.Size)
+		}
+
+	case "verify", "restore":
+		if flag.NArg() != 2 {
+			usage()
+		}
+		file := flag.Arg(1)
+		if cmd == "verify" {
+			if err := backup.Verify(file); err != nil {
+				log.Fatal("Проверка не пройдена: ", err)
+			}
+			fmt.Println("ok")
+			return
+		}
+		saved, err := backup.Restore(file, *dbPath)
+		if err != nil {
+			log.Fatal("Ошибка восстановления: ", err)
+		}
+		if saved != "" {
+			fmt.Println("Прежняя база сохранена в", saved)
+		}
+		fmt.Println("Готово")
+
+	default:
+		usage()
+	}
+}
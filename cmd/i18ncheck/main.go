@@ -0,0 +1,82 @@
+// Команда i18ncheck проверяет каталоги текстов: все ли ключи переведены на все языки
+// и есть ли в каталоге каждый ключ, использованный в коде и в шаблонах веб-панели.
+// Код возврата 1 — есть ошибки.
+//
+//	go run ./cmd/i18ncheck
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"tbViT/i18n"
+)
+
+// keyRe находит литеральные ключи в вызовах i18n.T, i18n.N, i18n.NewError и обёрток
+// Tr/TrN/tr/trN: ключ — первая строка вида "раздел.имя" в аргументах вызова.
+// Ключ, оканчивающийся на "_", — начало составного ключа вроде "lb.period_"+p.
+var keyRe = regexp.MustCompile(`\b(?:i18n\.(?:T|N|NewError)|[Tt]rN?)\((?:[^"()\n]|\([^()\n]*\))*"([a-z0-9_]+(?:\.[a-z0-9_]+)+)"`)
+
+// tmplKeyRe находит ключи в шаблонах html/template: {{tr "раздел.имя" …}}.
+var tmplKeyRe = regexp.MustCompile(`\btr\s+"([a-z0-9_]+(?:\.[a-z0-9_]+)+)"`)
+
+func main() {
+	problems := i18n.Missing()
+
+	used := make(map[string][]string)
+	err := filepath.WalkDir(".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != "." && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		re := keyRe
+		switch {
+		case strings.HasSuffix(p, ".html"):
+			re = tmplKeyRe
+		case !strings.HasSuffix(p, ".go"):
+			return nil
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		for _, m := range re.FindAllStringSubmatch(string(src), -1) {
+			used[m[1]] = append(used[m[1]], p)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ошибка обхода исходников:", err)
+		os.Exit(2)
+	}
+	for key, files := range used {
+		if strings.HasSuffix(key, "_") {
+			if !i18n.HasPrefix(key) {
+				problems = append(problems, fmt.Sprintf("в каталоге нет ключей с префиксом %q из %s", key, files[0]))
+			}
+			continue
+		}
+		if !i18n.Has(key) {
+			problems = append(problems, fmt.Sprintf("ключ %q используется в %s, но отсутствует в каталоге", key, files[0]))
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("i18n: ок, ключей в коде: %d\n", len(used))
+		return
+	}
+	sort.Strings(problems)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tbViT/database"
+)
+
+func TestConvert(t *testing.T) {
+	ts := time.Date(2025, 3, 1, 12, 30, 0, 0, time.FixedZone("MSK", 3*3600))
+	tests := []struct {
+		name    string
+		in      interface{}
+		pgType  string
+		want    interface{}
+		wantErr bool
+	}{
+		{"nil", nil, "bigint", nil, false},
+		{"int64", int64(42), "bigint", int64(42), false},
+		{"float в integer", float64(3.9), "integer", int64(3), false},
+		{"bool true", true, "bigint", int64(1), false},
+		{"bool false", false, "bigint", int64(0), false},
+		{"строка-число", "17", "bigint", int64(17), false},
+		{"байты-число", []byte(" 17 "), "bigint", int64(17), false},
+		{"дробная строка", "2.5", "bigint", int64(2), false},
+		{"пустая строка в integer", "", "bigint", nil, false},
+		{"не число", "abc", "bigint", nil, true},
+		{"текст", "Иван", "text", "Иван", false},
+		{"число в text", int64(5), "text", "5", false},
+		{"float в text", float64(1.5), "character varying", "1.5", false},
+		{"время в text", ts, "text", "2025-03-01 09:30:00", false},
+		{"время в timestamp", ts, "timestamp without time zone", ts.UTC(), false},
+		{"пустая строка в timestamp", " ", "timestamp without time zone", nil, false},
+		{"строка в timestamp", "2025-03-01 09:30:00", "timestamp without time zone", "2025-03-01 09:30:00", false},
+		{"unix в timestamp", int64(0), "timestamp with time zone", time.Unix(0, 0).UTC(), false},
+		{"неизвестный тип", "x", "jsonb", "x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convert(tt.in, tt.pgType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("convert(%#v, %s) = %#v, want %#v", tt.in, tt.pgType, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCopyTable переносит строки SQLite с «грязными» значениями в PostgreSQL
+// из TEST_PG_DSN (в отдельной схеме) и проверяет, что счётчик id сдвинут.
+func TestCopyTable(t *testing.T) {
+	dsn := os.Getenv("TEST_PG_DSN")
+	if dsn == "" {
+		t.Skip("TEST_PG_DSN не задан")
+	}
+
+	src, err := database.Open(database.DriverSQLite, filepath.Join(t.TempDir(), "src.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if err := database.Migrate(src, database.DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	// SQLite допускает пустую строку в INTEGER и строку в DATETIME
+	_, err = src.Exec(`INSERT INTO users (id, telegram_id, name, rest_number, verified, current_balance, registration_start_time)
+VALUES (3, 7000000001, 'Иван', '5', 1, '', '2025-03-01 09:30:00'), (8, 2, 'Пётр', 5, '', 10, '')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := openPostgres(t, dsn)
+	n, err := copyTable(src, dst, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("перенесено %d строк, want 2", n)
+	}
+
+	var balance sql.NullInt64
+	var rest int
+	if err := dst.QueryRow(`SELECT current_balance, rest_number FROM users WHERE telegram_id=?`, int64(7000000001)).
+		Scan(&balance, &rest); err != nil {
+		t.Fatal(err)
+	}
+	if balance.Valid || rest != 5 {
+		t.Errorf("баланс %v, предприятие %d; want NULL и 5", balance, rest)
+	}
+
+	var id int
+	if err := dst.QueryRow(`INSERT INTO users (telegram_id) VALUES (?) RETURNING id`, 3).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id != 9 {
+		t.Errorf("id новой строки %d, want 9", id)
+	}
+}
+
+// openPostgres открывает базу dsn в новой схеме с актуальной структурой;
+// схема удаляется после теста.
+func openPostgres(t *testing.T, dsn string) *sql.DB {
+	t.Helper()
+	db, err := database.Open(database.DriverPostgres, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Одно соединение — чтобы search_path действовал на все запросы теста
+	db.SetMaxOpenConns(1)
+	schema := fmt.Sprintf("tbvit_migrate_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		db.Close()
+	})
+	if _, err := db.Exec(`SET search_path TO ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db, database.DriverPostgres); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
@@ -0,0 +1,369 @@
+// Package config — настройки бота. Значения по умолчанию перекрываются файлом
+// config.yaml (путь задаётся CONFIG_FILE), а его — переменными окружения.
+// Validate проверяет итоговые значения и перечисляет все ошибки сразу.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"tbViT/backup"
+	"tbViT/database"
+	"tbViT/web"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath — файл настроек, если CONFIG_FILE не задан. Его отсутствие — не ошибка.
+const DefaultPath = "config.yaml"
+
+// Режимы получения обновлений от Telegram
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
+// Уровни журнала
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+type Config struct {
+	Token      string   `yaml:"token"`
+	SuperUsers []int64  `yaml:"super_users"`
+	DB         DB       `yaml:"db"`
+	Telegram   Telegram `yaml:"telegram"`
+	HTTP       HTTP     `yaml:"http"`
+	LogLevel   string   `yaml:"log_level"`
+	// ShutdownTimeout — сколько секунд после SIGTERM даётся на обработку уже
+	// полученных обновлений и отправку очереди.
+	ShutdownTimeout int      `yaml:"shutdown_timeout"`
+	Defaults        Defaults `yaml:"defaults"`
+	Backup          Backup   `yaml:"backup"`
+	// Restaurants — значения настроек предприятий по номеру предприятия; перекрывают
+	// Defaults, но не то, что админ задал в меню «⚙️ Настройки».
+	Restaurants map[int]map[string]int `yaml:"restaurants"`
+}
+
+type DB struct {
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+}
+
+type Telegram struct {
+	Mode        string `yaml:"mode"`
+	PollTimeout int    `yaml:"poll_timeout"`
+	WebhookURL  string `yaml:"webhook_url"`
+}
+
+// HTTP — сервер для вебхука и проверок /healthz, /readyz. Пустой Listen — сервер
+// не запускается. MetricsListen — отдельный адрес для метрик Prometheus (/metrics),
+// пустой — метрики не отдаются. API — включить JSON API на /api/v1/, Dashboard —
+// веб-панель на /web/; PublicURL — адрес сервера из браузера, из него бот собирает
+// ссылки для входа в панель.
+type HTTP struct {
+	Listen        string `yaml:"listen"`
+	MetricsListen string `yaml:"metrics_listen"`
+	API           bool   `yaml:"api"`
+	Dashboard     bool   `yaml:"dashboard"`
+	PublicURL     string `yaml:"public_url"`
+}
+
+// Defaults — значения по умолчанию для всех предприятий.
+type Defaults struct {
+	TopUpCooldownHours     int   `yaml:"topup_cooldown_hours"`
+	TopUpAmounts           []int `yaml:"topup_amounts"`
+	ManagerWeeklyBudget    int   `yaml:"manager_weekly_budget"`
+	KudosWeeklyAllowance   int   `yaml:"kudos_weekly_allowance"`
+	TransferConvertPercent int   `yaml:"transfer_convert_percent"`
+}
+
+type Backup struct {
+	Dir  string `yaml:"dir"`
+	Keep int    `yaml:"keep"`
+	Gzip bool   `yaml:"gzip"`
+	Key  string `yaml:"key"`
+}
+
+// Default — настройки, с которыми бот работал до появления файла конфигурации.
+func Default() *Config {
+	return &Config{
+		DB:       DB{Driver: database.DriverSQLite},
+		Telegram: Telegram{Mode: ModePolling, PollTimeout: 60},
+		HTTP:     HTTP{Listen: ":7540"},
+		LogLevel: "info",
+		// docker stop ждёт 10 секунд, прежде чем убить процесс
+		ShutdownTimeout: 8,
+		Defaults: Defaults{
+			TopUpCooldownHours:     database.TopUpCooldownHours,
+			TopUpAmounts:           database.TopUpAmounts,
+			ManagerWeeklyBudget:    database.ManagerWeeklyBudget,
+			KudosWeeklyAllowance:   database.KudosWeeklyAllowance,
+			TransferConvertPercent: database.TransferConvertPercent,
+		},
+		Backup: Backup{Dir: backup.Dir, Keep: backup.Keep, Gzip: backup.Gzip},
+	}
+}
+
+// Load читает файл path (пустой — CONFIG_FILE или DefaultPath) и применяет
+// переменные окружения. Значения не проверяются — для этого есть Validate.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	explicit := path != ""
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+		explicit = path != ""
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		// Опечатка в имени поля — ошибка, а не молча проигнорированная настройка
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case !os.IsNotExist(err) || explicit:
+		return nil, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv перекрывает значения переменными окружения, в том числе прежними
+// TOCKEN, TELEGRAM_SUPER_USER, TRANSFER_CONVERT_PERCENT и MANAGER_WEEKLY_BUDGET.
+func (c *Config) applyEnv() error {
+	var errs []error
+	str := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			*dst = v
+		}
+	}
+	num := func(key string, dst *int) {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: ожидается целое число, получено %q", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+
+	str("TOCKEN", &c.Token)
+	if v := os.Getenv("TELEGRAM_SUPER_USER"); v != "" {
+		ids, err := database.ParseSuperUsers(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TELEGRAM_SUPER_USER: %w", err))
+		}
+		c.SuperUsers = ids
+	}
+	str("DB_DRIVER", &c.DB.Driver)
+	str("DB_DSN", &c.DB.DSN)
+	str("TELEGRAM_MODE", &c.Telegram.Mode)
+	num("POLL_TIMEOUT", &c.Telegram.PollTimeout)
+	str("WEBHOOK_URL", &c.Telegram.WebhookURL)
+	// WEBHOOK_LISTEN — прежнее имя HTTP_LISTEN
+	str("WEBHOOK_LISTEN", &c.HTTP.Listen)
+	str("HTTP_LISTEN", &c.HTTP.Listen)
+	str("HTTP_METRICS_LISTEN", &c.HTTP.MetricsListen)
+	if v := os.Getenv("HTTP_API"); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_API: ожидается true или false, получено %q", v))
+		}
+		c.HTTP.API = on
+	}
+	if v := os.Getenv("HTTP_DASHBOARD"); v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_DASHBOARD: ожидается true или false, получено %q", v))
+		}
+		c.HTTP.Dashboard = on
+	}
+	str("HTTP_PUBLIC_URL", &c.HTTP.PublicURL)
+	str("LOG_LEVEL", &c.LogLevel)
+	num("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+	num("TOPUP_COOLDOWN_HOURS", &c.Defaults.TopUpCooldownHours)
+	if v := os.Getenv("TOPUP_AMOUNTS"); v != "" {
+		amounts, err := parseInts(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("TOPUP_AMOUNTS: %w", err))
+		}
+		c.Defaults.TopUpAmounts = amounts
+	}
+	num("MANAGER_WEEKLY_BUDGET", &c.Defaults.ManagerWeeklyBudget)
+	num("KUDOS_WEEKLY_ALLOWANCE", &c.Defaults.KudosWeeklyAllowance)
+	num("TRANSFER_CONVERT_PERCENT", &c.Defaults.TransferConvertPercent)
+	str("BACKUP_DIR", &c.Backup.Dir)
+	num("BACKUP_KEEP", &c.Backup.Keep)
+	if v := os.Getenv("BACKUP_GZIP"); v != "" {
+		gz, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("BACKUP_GZIP: ожидается true или false, получено %q", v))
+		}
+		c.Backup.Gzip = gz
+	}
+	str("BACKUP_KEY", &c.Backup.Key)
+	return errors.Join(errs...)
+}
+
+func parseInts(value string) ([]int, error) {
+	var list []int
+	for _, f := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное число %q", f)
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+// Validate проверяет настройки и возвращает все найденные ошибки.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Token == "" {
+		fail("token", "не задан (token в файле или TOCKEN)")
+	}
+	if len(c.SuperUsers) == 0 {
+		fail("super_users", "не задан ни один суперпользователь (super_users в файле или TELEGRAM_SUPER_USER)")
+	}
+	for _, id := range c.SuperUsers {
+		if id <= 0 {
+			fail("super_users", "некорректный Telegram ID %d", id)
+		}
+	}
+	if driver, err := database.NormalizeDriver(c.DB.Driver); err != nil {
+		fail("db.driver", "%v", err)
+	} else if driver == database.DriverPostgres && c.DB.DSN == "" {
+		fail("db.dsn", "для PostgreSQL нужна строка подключения")
+	}
+
+	switch c.Telegram.Mode {
+	case ModePolling:
+		if c.Telegram.PollTimeout < 1 || c.Telegram.PollTimeout > 600 {
+			fail("telegram.poll_timeout", "ожидается от 1 до 600 секунд, задано %d", c.Telegram.PollTimeout)
+		}
+	case ModeWebhook:
+		if !strings.HasPrefix(c.Telegram.WebhookURL, "https://") {
+			fail("telegram.webhook_url", "для режима webhook нужен адрес https://…")
+		}
+		if c.HTTP.Listen == "" {
+			fail("http.listen", "не задан адрес HTTP-сервера для вебхука")
+		}
+	default:
+		fail("telegram.mode", "ожидается %s или %s, задано %q", ModePolling, ModeWebhook, c.Telegram.Mode)
+	}
+
+	if c.HTTP.MetricsListen != "" && c.HTTP.MetricsListen == c.HTTP.Listen {
+		fail("http.metrics_listen", "метрики нужно отдавать на отдельном адресе, а не на http.listen")
+	}
+	if c.HTTP.API && c.HTTP.Listen == "" {
+		fail("http.listen", "не задан адрес HTTP-сервера для API")
+	}
+	if c.HTTP.Dashboard {
+		if c.HTTP.Listen == "" {
+			fail("http.listen", "не задан адрес HTTP-сервера для веб-панели")
+		}
+		if u, err := url.Parse(c.HTTP.PublicURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			fail("http.public_url", "для веб-панели нужен адрес http(s)://…, задано %q", c.HTTP.PublicURL)
+		}
+	}
+
+	if !isLogLevel(c.LogLevel) {
+		fail("log_level", "ожидается одно из %s, задано %q", strings.Join(LogLevels, ", "), c.LogLevel)
+	}
+	if c.ShutdownTimeout < 1 || c.ShutdownTimeout > 600 {
+		fail("shutdown_timeout", "ожидается от 1 до 600 секунд, задано %d", c.ShutdownTimeout)
+	}
+
+	if c.Defaults.TopUpCooldownHours < 0 {
+		fail("defaults.topup_cooldown_hours", "не может быть отрицательным")
+	}
+	if len(c.Defaults.TopUpAmounts) == 0 {
+		fail("defaults.topup_amounts", "нужна хотя бы одна сумма начисления")
+	}
+	for _, a := range c.Defaults.TopUpAmounts {
+		if a <= 0 {
+			fail("defaults.topup_amounts", "сумма начисления должна быть положительной, задано %d", a)
+		}
+	}
+	if c.Defaults.ManagerWeeklyBudget < 0 {
+		fail("defaults.manager_weekly_budget", "не может быть отрицательным")
+	}
+	if c.Defaults.KudosWeeklyAllowance < 0 {
+		fail("defaults.kudos_weekly_allowance", "не может быть отрицательным")
+	}
+	if c.Defaults.TransferConvertPercent < 0 {
+		fail("defaults.transfer_convert_percent", "не может быть отрицательным")
+	}
+
+	if c.Backup.Keep < 1 {
+		fail("backup.keep", "нужно хранить хотя бы одну копию")
+	}
+	if c.Backup.Dir == "" {
+		fail("backup.dir", "не задан каталог резервных копий")
+	}
+
+	for rest, settings := range c.Restaurants {
+		if rest <= 0 {
+			fail("restaurants", "некорректный номер предприятия %d", rest)
+		}
+		for key, value := range settings {
+			if !database.IsRestSetting(key) {
+				fail(fmt.Sprintf("restaurants.%d.%s", rest, key), "неизвестная настройка (доступны: %s)", strings.Join(restSettingKeys(), ", "))
+			} else if value < 0 {
+				fail(fmt.Sprintf("restaurants.%d.%s", rest, key), "не может быть отрицательным")
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isLogLevel(level string) bool {
+	for _, l := range LogLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+func restSettingKeys() []string {
+	keys := make([]string, len(database.RestSettings))
+	for i, s := range database.RestSettings {
+		keys[i] = s.Key
+	}
+	return keys
+}
+
+// Apply переносит значения по умолчанию в пакеты, которые ими пользуются.
+func (c *Config) Apply() {
+	database.TopUpCooldownHours = c.Defaults.TopUpCooldownHours
+	database.TopUpAmounts = c.Defaults.TopUpAmounts
+	database.ManagerWeeklyBudget = c.Defaults.ManagerWeeklyBudget
+	database.KudosWeeklyAllowance = c.Defaults.KudosWeeklyAllowance
+	database.TransferConvertPercent = c.Defaults.TransferConvertPercent
+	database.RestOverrides = c.Restaurants
+	backup.Dir = c.Backup.Dir
+	backup.Keep = c.Backup.Keep
+	backup.Gzip = c.Backup.Gzip
+	backup.SetPassphrase(c.Backup.Key)
+	web.PublicURL = ""
+	if c.HTTP.Dashboard {
+		web.PublicURL = strings.TrimRight(c.HTTP.PublicURL, "/")
+	}
+}
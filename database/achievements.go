@@ -0,0 +1,233 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"tbViT/i18n"
+	"time"
+)
+
+// События, по которым проверяются достижения
+const (
+	EventTopUp    = "topup"
+	EventPurchase = "purchase"
+	EventKudos    = "kudos" // одобрена благодарность с начислением звёзд
+)
+
+// Achievement — правило достижения. Check проверяет, выполнено ли условие;
+// Bonus — звёзды, начисляемые при получении (0 — без бонуса). Название и описание
+// берутся из каталога по ключам ach.<Code>.title и ach.<Code>.desc.
+type Achievement struct {
+	Code   string
+	Icon   string
+	Bonus  int
+	Events []string
+	Check  func(db *sql.DB, telegramID int64) (bool, error)
+}
+
+// Title — название достижения на языке lang.
+func (a Achievement) Title(lang string) string {
+	return i18n.T(lang, "ach."+a.Code+".title")
+}
+
+// Description — условие достижения на языке lang.
+func (a Achievement) Description(lang string) string {
+	return i18n.T(lang, "ach."+a.Code+".desc")
+}
+
+// Achievements — все достижения в порядке отображения в профиле.
+var Achievements = []Achievement{
+	{
+		Code: "first_purchase", Icon: "🛍",
+		Events: []string{EventPurchase},
+		Check: func(db *sql.DB, id int64) (bool, error) {
+			return countAtLeast(db, 1, `SELECT COUNT(*) FROM transactions WHERE telegram_id=? AND type=?`, id, TxPurchase)
+		},
+	},
+	{
+		Code: "topups_10_month", Icon: "🔥",
+		Bonus:  5,
+		Events: []string{EventTopUp},
+		Check: func(db *sql.DB, id int64) (bool, error) {
+			return countAtLeast(db, 10, `SELECT COUNT(*) FROM transactions
+WHERE telegram_id=? AND type=? AND created_at >= ?`, id, TxTopUp, periodStart(PeriodMonth))
+		},
+	},
+	{
+		Code: "lifetime_100", Icon: "💯",
+		Bonus:  10,
+		Events: []string{EventTopUp, EventKudos},
+		Check: func(db *sql.DB, id int64) (bool, error) {
+			return countAtLeast(db, 100, `SELECT COALESCE(all_time_balance, 0) FROM users WHERE telegram_id=?`, id)
+		},
+	},
+	{
+		// Смены в боте не учитываются, поэтому стабильность считаем по начислениям:
+		// хотя бы одно в каждом из трёх последних календарных месяцев
+		Code: "steady_3_months", Icon: "🗓",
+		Bonus:  10,
+		Events: []string{EventTopUp},
+		Check: func(db *sql.DB, id int64) (bool, error) {
+			return activeMonths(db, id, 3)
+		},
+	},
+}
+
+// activeMonths — были ли начисления в каждом из последних months календарных месяцев,
+// включая текущий.
+func activeMonths(db *sql.DB, telegramID int64, months int) (bool, error) {
+	start := time.Now().UTC()
+	start = time.Date(start.Year(), start.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+	rows, err := db.Query(`SELECT created_at FROM transactions WHERE telegram_id=? AND type=? AND created_at >= ?`,
+		telegramID, TxTopUp, start.Format(timeLayout))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			return false, err
+		}
+		seen[at.UTC().Format("2006-01")] = true
+	}
+	return len(seen) >= months, rows.Err()
+}
+
+func countAtLeast(db *sql.DB, n int, query string, args ...interface{}) (bool, error) {
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		return false, err
+	}
+	return count >= n, nil
+}
+
+// UserAchievement — полученное пользователем достижение.
+type UserAchievement struct {
+	Code      string
+	AwardedAt time.Time
+}
+
+// GetUserAchievements возвращает полученные достижения по коду.
+func GetUserAchievements(db *sql.DB, telegramID int64) (map[string]UserAchievement, error) {
+	rows, err := db.Query(`SELECT code, awarded_at FROM achievements WHERE telegram_id=?`, telegramID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	got := make(map[string]UserAchievement)
+	for rows.Next() {
+		var a UserAchievement
+		if err := rows.Scan(&a.Code, &a.AwardedAt); err != nil {
+			slog.Error("Ошибка скана в GetUserAchievements", "err", err)
+			continue
+		}
+		got[a.Code] = a
+	}
+	return got, rows.Err()
+}
+
+// awardAchievement записывает достижение и начисляет бонус одной транзакцией.
+// Бонус урезается до максимального баланса; возвращается фактически начисленный.
+// ok == false, если достижение уже было получено.
+func awardAchievement(db *sql.DB, telegramID int64, a Achievement) (bonus int, ok bool, err error) {
+	bonus = capCredit(db, telegramID, a.Bonus)
+	comment := a.Title(UserLang(db, telegramID))
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	res, err := tx.Exec(`INSERT INTO achievements (telegram_id, code) VALUES (?, ?)
+ON CONFLICT(telegram_id, code) DO NOTHING`, telegramID, a.Code)
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return 0, false, nil
+	}
+	if bonus > 0 {
+		_, err = tx.Exec(`UPDATE users SET current_balance = current_balance + ?,
+			all_time_balance = COALESCE(all_time_balance, 0) + ? WHERE telegram_id=?`, bonus, bonus, telegramID)
+		if err == nil {
+			err = AddTransaction(tx, telegramID, bonus, TxBonus, 0, comment)
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	countStars(bonus, TxBonus)
+	return bonus, true, nil
+}
+
+// EvaluateAchievements проверяет достижения, связанные с событием, выдаёт новые
+// и ставит работнику уведомление в исходящую очередь. Бонус может сам выполнить
+// условие другого достижения, поэтому проверка повторяется, пока выдаётся новое.
+func EvaluateAchievements(db *sql.DB, telegramID int64, event string) []Achievement {
+	var awarded []Achievement
+	got, err := GetUserAchievements(db, telegramID)
+	if err != nil {
+		slog.Error("Ошибка загрузки достижений", "user_id", telegramID, "err", err)
+		return nil
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, a := range Achievements {
+			if _, ok := got[a.Code]; ok || !a.triggeredBy(event) {
+				continue
+			}
+			done, err := a.Check(db, telegramID)
+			if err != nil {
+				slog.Error("Ошибка проверки достижения", "code", a.Code, "user_id", telegramID, "err", err)
+				continue
+			}
+			if !done {
+				continue
+			}
+			bonus, ok, err := awardAchievement(db, telegramID, a)
+			if err != nil {
+				slog.Error("Ошибка выдачи достижения", "code", a.Code, "user_id", telegramID, "err", err)
+				continue
+			}
+			got[a.Code] = UserAchievement{Code: a.Code}
+			if !ok {
+				continue
+			}
+			awarded = append(awarded, a)
+			if bonus > 0 {
+				changed = true
+			}
+			notifyAchievement(db, telegramID, a, bonus)
+		}
+	}
+	return awarded
+}
+
+func (a Achievement) triggeredBy(event string) bool {
+	for _, e := range a.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func notifyAchievement(db *sql.DB, telegramID int64, a Achievement, bonus int) {
+	lang := UserLang(db, telegramID)
+	text := i18n.T(lang, "ach.new", a.Icon, a.Title(lang), a.Description(lang))
+	if bonus > 0 {
+		text += i18n.T(lang, "ach.bonus", bonus)
+	}
+	if _, err := EnqueueOutbox(db, OutboxMessage{ChatID: telegramID, Kind: BroadcastText, Text: text}); err != nil {
+		slog.Error("Ошибка уведомления о достижении", "code", a.Code, "user_id", telegramID, "err", err)
+	}
+}
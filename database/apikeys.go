@@ -0,0 +1,106 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"time"
+)
+
+// APIKeyPrefix — начало каждого ключа HTTP API; по нему ключ легко узнать в конфигурации
+// и в утечках.
+const APIKeyPrefix = "tbv_"
+
+// APIKey — ключ HTTP API. Ключ предприятия (RestNumber > 0) даёт доступ только к его
+// данным, ключ суперпользователя (RestNumber == 0) — ко всем предприятиям. Действия
+// по ключу выполняются от имени OwnerID: его права проверяются при каждом запросе,
+// он же попадает в журнал аудита. В базе хранится только хеш ключа.
+type APIKey struct {
+	ID         int
+	Name       string
+	RestNumber int
+	OwnerID    int64
+	Revoked    bool
+	LastUsed   time.Time
+	CreatedAt  time.Time
+}
+
+// hashToken — хеш ключа API или токена веб-панели для хранения в базе.
+func hashToken(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey создаёт ключ и возвращает его; показать ключ повторно нельзя.
+func CreateAPIKey(db *sql.DB, name string, restNumber int, ownerID int64) (string, int, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", 0, err
+	}
+	key := APIKeyPrefix + hex.EncodeToString(b)
+	var id int
+	err := db.QueryRow(`INSERT INTO api_keys (name, key_hash, rest_number, owner_id) VALUES (?, ?, ?, ?) RETURNING id`,
+		name, hashToken(key), restNumber, ownerID).Scan(&id)
+	if err != nil {
+		return "", 0, err
+	}
+	slog.Info("Создан ключ API", "key_id", id, "rest", restNumber, "owner_id", ownerID)
+	return key, id, nil
+}
+
+const apiKeyColumns = `id, name, rest_number, owner_id, COALESCE(revoked, 0), COALESCE(last_used, 0), created_at`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (APIKey, error) {
+	var k APIKey
+	var lastUsed int64
+	err := row.Scan(&k.ID, &k.Name, &k.RestNumber, &k.OwnerID, &k.Revoked, &lastUsed, &k.CreatedAt)
+	if lastUsed > 0 {
+		k.LastUsed = time.Unix(lastUsed, 0)
+	}
+	return k, err
+}
+
+// FindAPIKey ищет действующий ключ и отмечает время его использования.
+// Неизвестный или отозванный ключ — sql.ErrNoRows.
+func FindAPIKey(db *sql.DB, key string) (APIKey, error) {
+	k, err := scanAPIKey(db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash=? AND COALESCE(revoked, 0)=0`,
+		hashToken(key)))
+	if err != nil {
+		return APIKey{}, err
+	}
+	db.Exec(`UPDATE api_keys SET last_used=? WHERE id=?`, time.Now().Unix(), k.ID)
+	return k, nil
+}
+
+// ListAPIKeys возвращает все ключи, включая отозванные.
+func ListAPIKeys(db *sql.DB) ([]APIKey, error) {
+	rows, err := db.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+// RevokeAPIKey отзывает ключ; запросы с ним сразу перестают проходить.
+func RevokeAPIKey(db *sql.DB, id int) error {
+	res, err := db.Exec(`UPDATE api_keys SET revoked=1 WHERE id=?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	slog.Info("Ключ API отозван", "key_id", id)
+	return nil
+}
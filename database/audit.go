@@ -0,0 +1,203 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/i18n"
+	"time"
+)
+
+// AuditEntry — запись журнала привилегированных действий.
+type AuditEntry struct {
+	ActorID    int64
+	RestNumber int // 0 — действие на уровне всей сети
+	Action     string
+	Target     string
+	Before     string
+	After      string
+}
+
+// Audit записывает действие в audit_log. Ошибка записи не должна ломать само действие,
+// поэтому она только логируется.
+func Audit(db *sql.DB, e AuditEntry) {
+	_, err := db.Exec(`INSERT INTO audit_log (actor_id, rest_number, action, target, before_value, after_value)
+VALUES (?, ?, ?, ?, ?, ?)`, e.ActorID, e.RestNumber, e.Action, e.Target, e.Before, e.After)
+	if err != nil {
+		slog.Error("Ошибка записи в audit_log", "action", e.Action, "user_id", e.ActorID, "err", err)
+	}
+}
+
+// AuditAction записывает действие в журнал, определяя предприятие по исполнителю.
+func AuditAction(db *sql.DB, actorID int64, action, target, before, after string) {
+	restNumber, _ := GetUserRestID(db, actorID)
+	Audit(db, AuditEntry{
+		ActorID:    actorID,
+		RestNumber: restNumber,
+		Action:     action,
+		Target:     target,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// AuditFilter — условия выборки журнала. Нулевые значения означают «без ограничения».
+type AuditFilter struct {
+	RestNumber int
+	ActorID    int64
+	Target     string
+	From       time.Time
+	To         time.Time // включительно, по дню
+}
+
+type AuditRecord struct {
+	ID        int
+	CreatedAt time.Time
+	AuditEntry
+}
+
+// ParseAuditFilter разбирает фильтр вида "actor=123 target=456 rest=11047 from=2025-01-01 to=2025-01-31".
+func ParseAuditFilter(text string) (AuditFilter, error) {
+	var f AuditFilter
+	for _, part := range strings.Fields(text) {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return f, i18n.NewError("err.audit_filter_pair", part)
+		}
+		var err error
+		switch strings.ToLower(kv[0]) {
+		case "actor":
+			f.ActorID, err = strconv.ParseInt(kv[1], 10, 64)
+		case "rest":
+			f.RestNumber, err = strconv.Atoi(kv[1])
+		case "target":
+			f.Target = kv[1]
+		case "from":
+			f.From, err = time.Parse("2006-01-02", kv[1])
+		case "to":
+			f.To, err = time.Parse("2006-01-02", kv[1])
+		default:
+			return f, i18n.NewError("err.audit_filter_key", kv[0])
+		}
+		if err != nil {
+			return f, i18n.NewError("err.audit_filter_value", part)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return f, i18n.NewError("err.audit_filter_dates")
+	}
+	return f, nil
+}
+
+func (f AuditFilter) String() string {
+	return f.Label(i18n.Default)
+}
+
+// Label — описание фильтра для показа пользователю на языке lang.
+func (f AuditFilter) Label(lang string) string {
+	var parts []string
+	if f.RestNumber != 0 {
+		parts = append(parts, fmt.Sprintf("rest=%d", f.RestNumber))
+	}
+	if f.ActorID != 0 {
+		parts = append(parts, fmt.Sprintf("actor=%d", f.ActorID))
+	}
+	if f.Target != "" {
+		parts = append(parts, "target="+f.Target)
+	}
+	if !f.From.IsZero() {
+		parts = append(parts, "from="+f.From.Format("2006-01-02"))
+	}
+	if !f.To.IsZero() {
+		parts = append(parts, "to="+f.To.Format("2006-01-02"))
+	}
+	if len(parts) == 0 {
+		return i18n.T(lang, "audit.no_filter")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f AuditFilter) where() (string, []interface{}) {
+	where := []string{"1=1"}
+	var args []interface{}
+	if f.RestNumber != 0 {
+		where = append(where, "rest_number=?")
+		args = append(args, f.RestNumber)
+	}
+	if f.ActorID != 0 {
+		where = append(where, "actor_id=?")
+		args = append(args, f.ActorID)
+	}
+	if f.Target != "" {
+		where = append(where, "target=?")
+		args = append(args, f.Target)
+	}
+	// created_at хранится как CURRENT_TIMESTAMP — строка UTC "YYYY-MM-DD HH:MM:SS"
+	if !f.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, f.From.Format("2006-01-02 15:04:05"))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, f.To.AddDate(0, 0, 1).Format("2006-01-02 15:04:05"))
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// ListAudit возвращает страницу журнала (новые записи первыми) и общее число записей.
+func ListAudit(db *sql.DB, f AuditFilter, limit, offset int) ([]AuditRecord, int, error) {
+	where, args := f.where()
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	query := `SELECT id, created_at, actor_id, COALESCE(rest_number, 0), action, COALESCE(target, ''),
+       COALESCE(before_value, ''), COALESCE(after_value, '')
+FROM audit_log WHERE ` + where + ` ORDER BY id DESC`
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var list []AuditRecord
+	for rows.Next() {
+		var r AuditRecord
+		if err := rows.Scan(&r.ID, &r.CreatedAt, &r.ActorID, &r.RestNumber, &r.Action, &r.Target,
+			&r.Before, &r.After); err != nil {
+			slog.Error("Ошибка скана в ListAudit", "err", err)
+			continue
+		}
+		list = append(list, r)
+	}
+	return list, total, rows.Err()
+}
+
+// AuditTable — журнал по фильтру для выгрузки.
+func AuditTable(db *sql.DB, f AuditFilter) (Table, error) {
+	t := Table{
+		Name:    "audit",
+		Header:  []string{"id", "created_at", "actor_id", "rest_number", "action", "target", "before", "after"},
+		Numeric: []string{"id", "actor_id", "rest_number"},
+	}
+	records, _, err := ListAudit(db, f, 0, 0)
+	if err != nil {
+		return t, err
+	}
+	for _, r := range records {
+		t.Rows = append(t.Rows, []string{
+			strconv.Itoa(r.ID),
+			r.CreatedAt.Format("2006-01-02 15:04:05"),
+			strconv.FormatInt(r.ActorID, 10),
+			strconv.Itoa(r.RestNumber),
+			r.Action, r.Target, r.Before, r.After,
+		})
+	}
+	return t, nil
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"tbViT/i18n"
+)
+
+// Виды содержимого рассылки
+const (
+	BroadcastText     = "text"
+	BroadcastPhoto    = "photo"
+	BroadcastDocument = "document"
+)
+
+// Статусы рассылки и доставки получателю
+const (
+	BroadcastDraft     = "draft"
+	BroadcastSending   = "sending"
+	BroadcastDone      = "done"
+	BroadcastCancelled = "cancelled"
+
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliveryBlocked = "blocked"
+)
+
+// Broadcast — объявление для сотрудников предприятия (RestNumber == 0 — вся сеть).
+type Broadcast struct {
+	ID         int
+	AuthorID   int64
+	RestNumber int
+	Role       string // "" — все роли
+	Kind       string
+	Text       string
+	FileID     string
+	Status     string
+}
+
+// BroadcastStats — счётчики доставки по статусам.
+type BroadcastStats struct {
+	Pending int
+	Sent    int
+	Failed  int
+	Blocked int
+}
+
+func (s BroadcastStats) Total() int {
+	return s.Pending + s.Sent + s.Failed + s.Blocked
+}
+
+// broadcastRecipientsWhere — подтверждённые сотрудники, не заблокировавшие бота, кроме автора.
+func broadcastRecipientsWhere(b Broadcast) (string, []interface{}) {
+	where := []string{"verified=1", "COALESCE(blocked, 0)=0", "telegram_id != ?"}
+	args := []interface{}{b.AuthorID}
+	if b.RestNumber != 0 {
+		where = append(where, "rest_number=?")
+		args = append(args, b.RestNumber)
+	}
+	if b.Role != "" {
+		where = append(where, "access_level=?")
+		args = append(args, b.Role)
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// CountBroadcastRecipients — сколько человек получит рассылку.
+func CountBroadcastRecipients(db *sql.DB, b Broadcast) (int, error) {
+	where, args := broadcastRecipientsWhere(b)
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&n)
+	return n, err
+}
+
+// CreateBroadcast сохраняет черновик рассылки.
+func CreateBroadcast(db *sql.DB, b Broadcast) (int, error) {
+	if b.Role != "" && !IsValidAccessLevel(b.Role) {
+		return 0, i18n.NewError("err.invalid_role")
+	}
+	var id int
+	err := db.QueryRow(`INSERT INTO broadcasts (author_id, rest_number, role, kind, text, file_id, status)
+VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`, b.AuthorID, b.RestNumber, b.Role, b.Kind, b.Text, b.FileID, BroadcastDraft).Scan(&id)
+	return id, err
+}
+
+func GetBroadcast(db *sql.DB, id int) (Broadcast, error) {
+	b := Broadcast{ID: id}
+	err := db.QueryRow(`SELECT author_id, rest_number, COALESCE(role, ''), kind, COALESCE(text, ''),
+       COALESCE(file_id, ''), status FROM broadcasts WHERE id=?`, id).
+		Scan(&b.AuthorID, &b.RestNumber, &b.Role, &b.Kind, &b.Text, &b.FileID, &b.Status)
+	return b, err
+}
+
+// StartBroadcast переводит черновик в отправку и фиксирует список получателей.
+// Повторное подтверждение той же рассылки вернёт ошибку.
+func StartBroadcast(db *sql.DB, id int) (int, error) {
+	b, err := GetBroadcast(db, id)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE broadcasts SET status=? WHERE id=? AND status=?`, BroadcastSending, id, BroadcastDraft)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, i18n.NewError("err.broadcast_decided")
+	}
+	where, args := broadcastRecipientsWhere(b)
+	res, err = tx.Exec(`INSERT INTO broadcast_recipients (broadcast_id, telegram_id, status)
+SELECT ?, telegram_id, ? FROM users WHERE `+where, append([]interface{}{id, DeliveryPending}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), tx.Commit()
+}
+
+// CancelBroadcast отменяет черновик.
+func CancelBroadcast(db *sql.DB, id int) error {
+	res, err := db.Exec(`UPDATE broadcasts SET status=? WHERE id=? AND status=?`, BroadcastCancelled, id, BroadcastDraft)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return i18n.NewError("err.broadcast_decided")
+	}
+	return nil
+}
+
+// PendingBroadcastRecipients — получатели, которым ещё не отправлено.
+func PendingBroadcastRecipients(db *sql.DB, id int) ([]int64, error) {
+	rows, err := db.Query(`SELECT telegram_id FROM broadcast_recipients WHERE broadcast_id=? AND status=?`,
+		id, DeliveryPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			slog.Error("Ошибка скана в PendingBroadcastRecipients", "err", err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func SetDeliveryStatus(db *sql.DB, broadcastID int, telegramID int64, status, errText string) error {
+	_, err := db.Exec(`UPDATE broadcast_recipients SET status=?, error=?, sent_at=CURRENT_TIMESTAMP
+WHERE broadcast_id=? AND telegram_id=?`, status, errText, broadcastID, telegramID)
+	return err
+}
+
+func FinishBroadcast(db *sql.DB, id int) error {
+	_, err := db.Exec(`UPDATE broadcasts SET status=?, finished_at=CURRENT_TIMESTAMP WHERE id=?`, BroadcastDone, id)
+	return err
+}
+
+func GetBroadcastStats(db *sql.DB, id int) (BroadcastStats, error) {
+	var s BroadcastStats
+	err := db.QueryRow(`SELECT
+       COALESCE(SUM(CASE WHEN status=? THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN status=? THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN status=? THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN status=? THEN 1 ELSE 0 END), 0)
+FROM broadcast_recipients WHERE broadcast_id=?`,
+		DeliveryPending, DeliverySent, DeliveryFailed, DeliveryBlocked, id).
+		Scan(&s.Pending, &s.Sent, &s.Failed, &s.Blocked)
+	return s, err
+}
+
+// SendingBroadcasts — рассылки, прерванные перезапуском бота.
+func SendingBroadcasts(db *sql.DB) ([]int, error) {
+	rows, err := db.Query(`SELECT id FROM broadcasts WHERE status=?`, BroadcastSending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, rows.Err()
+}
+
+// SetUserBlocked отмечает, что пользователь заблокировал бота (или снова им пользуется).
+func SetUserBlocked(db *sql.DB, telegramID int64, blocked bool) error {
+	_, err := db.Exec(`UPDATE users SET blocked=? WHERE telegram_id=? AND COALESCE(blocked, 0) != ?`,
+		blocked, telegramID, blocked)
+	return err
+}
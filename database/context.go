@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ctxConn — *sql.DB или *sql.Tx.
+type ctxConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// withContext выполняет запросы помощников пакета (queryer, execer) с контекстом
+// обновления: журнал запросов получает его cid, а отмена прерывает запрос.
+type withContext struct {
+	ctx  context.Context
+	conn ctxConn
+}
+
+func (c withContext) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.ExecContext(c.ctx, query, args...)
+}
+
+func (c withContext) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.QueryContext(c.ctx, query, args...)
+}
+
+func (c withContext) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.conn.QueryRowContext(c.ctx, query, args...)
+}
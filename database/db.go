@@ -1,28 +1,30 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"fmt"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
+	"log/slog"
 	"strconv"
-	"strings"
+	"tbViT/i18n"
 	"time"
 )
 
 func ChangeAccess(db *sql.DB, userID int64, accessLevel string) error {
+	if !IsValidAccessLevel(accessLevel) {
+		return i18n.NewError("err.invalid_access_level", accessLevel)
+	}
 	result, err := db.Exec("UPDATE users SET access_level = ? WHERE telegram_id = ?", accessLevel, userID)
 	if err != nil {
-		log.Printf("Ошибка при обновлении access_level для %d: %v", userID, err)
+		slog.Error("Ошибка обновления access_level", "user_id", userID, "err", err)
 		return err
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		log.Printf("Пользователь с telegram_id=%d не найден", userID)
+		slog.Warn("Пользователь не найден", "user_id", userID)
 	} else {
-		log.Printf("Уровень доступа для %d изменён на %s", userID, accessLevel)
+		slog.Info("Уровень доступа изменён", "user_id", userID, "level", accessLevel)
 	}
 
 	return nil
@@ -33,14 +35,14 @@ func UpdateRest(db *sql.DB, userID int64, value string) error {
 	row := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE telegram_id=?)", userID)
 	err := row.Scan(&exists)
 	if err != nil {
-		log.Printf("Ошибка при проверке существования пользователя %d: %v", userID, err)
+		slog.Error("Ошибка проверки существования пользователя", "user_id", userID, "err", err)
 		return err
 	}
 
 	if exists {
 		_, err = db.Exec("UPDATE users SET rest_number=? WHERE telegram_id=?", value, userID)
 		if err != nil {
-			log.Printf("Ошибка при обновлении пользователя %d: %v", userID, err)
+			slog.Error("Ошибка обновления пользователя", "user_id", userID, "err", err)
 			return err
 		}
 	} else {
@@ -49,78 +51,122 @@ func UpdateRest(db *sql.DB, userID int64, value string) error {
 			VALUES(?, ?, ?, ?, ?, ?)`,
 			userID, value, "SuperUser", "999", "admin", 1)
 		if err != nil {
-			log.Printf("Ошибка при создании пользователя %d: %v", userID, err)
+			slog.Error("Ошибка создания пользователя", "user_id", userID, "err", err)
 			return err
 		}
-		log.Printf("Создан новый пользователь: telegram_id=%d, rest_number=%s", userID, value)
+		slog.Info("Создан новый пользователь", "user_id", userID, "rest", value)
 	}
 	return nil
 }
 
 func DeleteUser(db *sql.DB, telegramID int64) error {
+	if err := ensureNotLastAdmin(db, telegramID); err != nil {
+		return err
+	}
 	_, err := db.Exec("DELETE FROM users WHERE telegram_id = ?", telegramID)
 	return err
 }
 
-func SendWorkersString(db *sql.DB, fromID int64) (string, error) {
-	restNum, err := SameRest(db, fromID)
+// ErrLastAdmin возвращается, если операция оставила бы предприятие без администратора.
+var ErrLastAdmin = i18n.NewError("err.last_admin")
+
+// ChangeRole назначает роль сотруднику предприятия администратора по номеру расписания.
+// Назначение админа не снимает прав с текущих админов — для этого есть TransferOwnership.
+// Возвращает telegram_id сотрудника и его прежнюю роль.
+func ChangeRole(db *sql.DB, adminID int64, tableNumber, role string) (int64, string, error) {
+	newUserID, err := findColleague(db, adminID, tableNumber)
 	if err != nil {
-		log.Printf("Ошибка получения rest_number: %v", err)
-		return "", err
+		return 0, "", err
 	}
-	log.Printf("rest_number для %d: %d", fromID, restNum)
+	before, _ := GetAccessLevel(db, newUserID)
+	if role != "admin" {
+		if err := ensureNotLastAdmin(db, newUserID); err != nil {
+			return newUserID, before, err
+		}
+	}
+	_, err = db.Exec(`UPDATE users SET access_level=? WHERE telegram_id=?`, role, newUserID)
+	return newUserID, before, err
+}
 
-	rows, err := db.Query(`SELECT table_number, name, access_level, current_balance
-FROM users WHERE rest_number=? ORDER BY CAST(table_number AS INTEGER) ASC`, int(restNum))
+// SetAccessLevel меняет роль сотрудника, не оставляя предприятие без администратора.
+// Возвращает прежнюю роль.
+func SetAccessLevel(db *sql.DB, userID int64, level string) (string, error) {
+	if !IsValidAccessLevel(level) {
+		return "", i18n.NewError("err.invalid_access_level", level)
+	}
+	before, err := GetAccessLevel(db, userID)
 	if err != nil {
-		log.Printf("Ошибка загрузки списка сотрудников: %v", err)
 		return "", err
 	}
-	defer rows.Close()
-
-	var list strings.Builder
-	for rows.Next() {
-		var num, name, access string
-		var balance int
-		if err := rows.Scan(&num, &name, &access, &balance); err != nil {
-			log.Printf("Ошибка скана в SendWorkersString: %v", err)
-			continue
+	if level != "admin" {
+		if err := ensureNotLastAdmin(db, userID); err != nil {
+			return before, err
 		}
-		list.WriteString(fmt.Sprintf("%s %s|%s|%d🌟\n", num, name, access, balance))
 	}
+	_, err = db.Exec(`UPDATE users SET access_level=? WHERE telegram_id=?`, level, userID)
+	return before, err
+}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Ошибка при итерации по строкам: %v", err)
-		return "", err
+// TransferOwnership делает сотрудника администратором, а текущего админа понижает до менеджера.
+// Возвращает telegram_id нового админа.
+func TransferOwnership(db *sql.DB, oldAdminID int64, tableNumber string) (int64, error) {
+	newUserID, err := findColleague(db, oldAdminID, tableNumber)
+	if err != nil {
+		return 0, err
+	}
+	if newUserID == oldAdminID {
+		return 0, i18n.NewError("err.self_transfer_rights")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec(`UPDATE users SET access_level='admin' WHERE telegram_id=?`, newUserID); err != nil {
+		return 0, err
+	}
+	if _, err = tx.Exec(`UPDATE users SET access_level='manager' WHERE telegram_id=?`, oldAdminID); err != nil {
+		return 0, err
 	}
-	return list.String(), nil
+	return newUserID, tx.Commit()
 }
 
-func ChangeRole(db *sql.DB, oldAdminID int64, tableNumber, role string) error {
-	// Найти пользователя по номеру расписания и тому же предприятию
-	var newUserID int64
+// findColleague ищет пользователя по номеру расписания в том же предприятии, что и userID.
+func findColleague(db *sql.DB, userID int64, tableNumber string) (int64, error) {
+	var colleagueID int64
 	err := db.QueryRow(
 		`SELECT telegram_id FROM users WHERE table_number=? AND rest_number=(
             SELECT rest_number FROM users WHERE telegram_id=?
-        ) LIMIT 1`, tableNumber, oldAdminID,
-	).Scan(&newUserID)
+        ) LIMIT 1`, tableNumber, userID,
+	).Scan(&colleagueID)
+	if err == sql.ErrNoRows {
+		return 0, i18n.NewError("err.user_not_found")
+	}
+	return colleagueID, err
+}
+
+// ensureNotLastAdmin возвращает ErrLastAdmin, если userID — единственный админ своего предприятия.
+func ensureNotLastAdmin(q queryer, userID int64) error {
+	var access string
+	var restNumber int
+	err := q.QueryRow(`SELECT COALESCE(access_level, ''), COALESCE(rest_number, 0) FROM users WHERE telegram_id=?`,
+		userID).Scan(&access, &restNumber)
 	if err == sql.ErrNoRows {
-		return errors.New("Пользователь не найден")
+		return nil
 	}
 	if err != nil {
 		return err
 	}
-	// Применить роль
-	_, err = db.Exec(`UPDATE users SET access_level=? WHERE telegram_id=?`, role, newUserID)
+	if access != "admin" {
+		return nil
+	}
+	var admins int
+	err = q.QueryRow(`SELECT COUNT(*) FROM users WHERE rest_number=? AND access_level='admin'`, restNumber).Scan(&admins)
 	if err != nil {
 		return err
 	}
-	// Если роль — admin, то понижаем старого админа
-	if role == "admin" {
-		_, err = db.Exec(`UPDATE users SET access_level='manager' WHERE telegram_id=?`, oldAdminID)
-		if err != nil {
-			return err
-		}
+	if admins <= 1 {
+		return ErrLastAdmin
 	}
 	return nil
 }
@@ -133,29 +179,22 @@ func GetWorkerInfoValues(db *sql.DB, workerID int64) (string, string, string, in
         FROM users
         WHERE telegram_id = ?`, workerID).Scan(&tableNumber, &name, &access, &balance)
 	if err != nil {
-		log.Printf("ошибка получения информации о сотруднике: %v", err)
+		slog.Error("Ошибка получения информации о сотруднике", "err", err)
 		return "", "", "", 0, err
 	}
 	return tableNumber, name, access, balance, nil
 }
 
-func GetWorkerInfo(db *sql.DB, workerID int64) string {
-	var access, name string
-	var num, balance int
-	_ = db.QueryRow("SELECT table_number, access_level, current_balance, name from users where telegram_id=?",
-		workerID).Scan(&num, &access, &balance, &name)
-	return fmt.Sprintf("Вы выбрали %d %s\nУровень доступа: %s\nТекущий баланс: %d",
-		num, name, access, balance)
-}
-
-func ApplyCorrection(db *sql.DB, workerID int64, field, value string) error {
+func ApplyCorrection(db *sql.DB, actorID, workerID int64, field, value string) error {
 
 	if field == "delete" {
 		// Проверим, что value == "true" или "1" (опционально, для безопасности)
 		// Можно также просто игнорировать value и удалять по workerID
-		_, err := db.Exec("DELETE FROM users WHERE telegram_id = ?", workerID)
-		if err != nil {
-			return errors.New("не удалось удалить пользователя")
+		if err := DeleteUser(db, workerID); err != nil {
+			if err == ErrLastAdmin {
+				return err
+			}
+			return i18n.NewError("err.delete_user")
 		}
 		return nil
 	}
@@ -166,16 +205,16 @@ func ApplyCorrection(db *sql.DB, workerID int64, field, value string) error {
 	case "balance", "tablenumber":
 		n, err := strconv.Atoi(value)
 		if err != nil {
-			return errors.New("значение должно быть целым числом")
+			return i18n.NewError("err.value_not_integer")
 		}
 		if n < 0 {
-			return errors.New("значение должно быть положительным числом или нулём")
+			return i18n.NewError("err.value_negative")
 		}
 	}
 
 	switch field {
 	case "balance":
-		query = "UPDATE users SET current_balance=? WHERE telegram_id=?"
+		return correctBalance(db, actorID, workerID, value)
 	case "name":
 		query = "UPDATE users SET name=? WHERE telegram_id=?"
 	case "tablenumber":
@@ -187,9 +226,56 @@ func ApplyCorrection(db *sql.DB, workerID int64, field, value string) error {
 	return err
 }
 
-func GetAccessLevel(db *sql.DB, userID int64) (string, error) {
+// GetCorrectionValue возвращает текущее значение поля, которое корректирует админ (для журнала).
+func GetCorrectionValue(db *sql.DB, workerID int64, field string) string {
+	tableNumber, name, _, balance, err := GetWorkerInfoValues(db, workerID)
+	if err != nil {
+		return ""
+	}
+	switch field {
+	case "balance":
+		return strconv.Itoa(balance)
+	case "name":
+		return name
+	case "tablenumber":
+		return tableNumber
+	}
+	return ""
+}
+
+// correctBalance выставляет баланс и записывает разницу в историю операций.
+func correctBalance(db *sql.DB, actorID, workerID int64, value string) error {
+	newBalance, _ := strconv.Atoi(value)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldBalance int
+	err = tx.QueryRow("SELECT COALESCE(current_balance, 0) FROM users WHERE telegram_id=?", workerID).Scan(&oldBalance)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec("UPDATE users SET current_balance=? WHERE telegram_id=?", newBalance, workerID); err != nil {
+		return err
+	}
+	delta := newBalance - oldBalance
+	if delta != 0 {
+		if err = AddTransaction(tx, workerID, delta, TxCorrection, actorID, ""); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	countStars(delta, TxCorrection)
+	return nil
+}
+
+func GetAccessLevel(q queryer, userID int64) (string, error) {
 	var accessLevel string
-	err := db.QueryRow("SELECT access_level FROM users WHERE telegram_id=?", userID).Scan(&accessLevel)
+	err := q.QueryRow("SELECT access_level FROM users WHERE telegram_id=?", userID).Scan(&accessLevel)
 	if err != nil {
 		return "", err
 	}
@@ -205,87 +291,49 @@ func GetUserDep(db *sql.DB, telegramID int64) (string, error) {
 	return dep, err
 }
 
-func SendWorkersList(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, status string, dep string, page int) error {
-	const pageSize = 15
-
-	// Считаем общее количество работников
-	var total int
-	err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE rest_number=? AND access_level='worker' AND verified=1`, dep).Scan(&total)
+// GetAdminIDs возвращает всех админов предприятия, в котором работает userID.
+func GetAdminIDs(db *sql.DB, userID int64) ([]int64, error) {
+	rn, err := SameRest(db, userID)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения количества работников."))
-		return err
+		return nil, err
 	}
+	return GetRestAdminIDs(db, int(rn))
+}
 
-	offset := page * pageSize
+// GetRestAdminIDs возвращает всех админов предприятия по его номеру.
+func GetRestAdminIDs(db *sql.DB, restNumber int) ([]int64, error) {
+	return RestAdminIDs(db, restNumber)
+}
 
-	rows, err := db.Query(
-		`SELECT telegram_id, name, table_number
-         FROM users
-         WHERE rest_number=? AND access_level='worker' AND verified=1
-         ORDER BY CAST(table_number AS INTEGER) ASC
-         LIMIT ? OFFSET ?`, dep, pageSize, offset,
-	)
+// RestAdminIDs — GetRestAdminIDs внутри транзакции (*sql.Tx) или на *sql.DB:
+// регистрация ищет админов в той же транзакции, что и сохраняет заявку.
+func RestAdminIDs(q queryer, restNumber int) ([]int64, error) {
+	rows, err := q.Query(`SELECT telegram_id FROM users WHERE rest_number=? AND access_level='admin'`, restNumber)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения работников."))
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
-	buttons := [][]tgbotapi.InlineKeyboardButton{}
+	var ids []int64
 	for rows.Next() {
-		var workerID int64
-		var name, tableNum string
-		if err := rows.Scan(&workerID, &name, &tableNum); err != nil {
-			continue
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
-		btnText := fmt.Sprintf("%s %s", tableNum, name)
-		callbackData := fmt.Sprintf("%s:%d", status, workerID)
-		buttons = append(buttons, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(btnText, callbackData),
-		))
-	}
-	if len(buttons) == 0 {
-		bot.Send(tgbotapi.NewMessage(chatID, "❌ В вашем предприятии нет работников."))
-		return nil
-	}
-
-	// Кнопки пагинации:
-	paginationButtons := []tgbotapi.InlineKeyboardButton{}
-	if page > 0 {
-		paginationButtons = append(paginationButtons,
-			tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("topup_select_worker:%d:%s:%s", page-1, status, dep)),
-		)
+		ids = append(ids, id)
 	}
-	if offset+pageSize < total {
-		paginationButtons = append(paginationButtons,
-			tgbotapi.NewInlineKeyboardButtonData("➡️ Дальше", fmt.Sprintf("topup_select_worker:%d:%s:%s", page+1, status, dep)),
-		)
-	}
-	if len(paginationButtons) > 0 {
-		buttons = append(buttons, paginationButtons)
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
-
-	replyMsg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Выберите работника (страница %d):", page+1))
-	replyMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(buttons...)
-	bot.Send(replyMsg)
-	return nil
-}
-
-func GetAdminID(db *sql.DB, userID int64) (int64, error) {
-	var adminTelegramID int64
-	rn, err := SameRest(db, userID)
-	err = db.QueryRow(
-		`SELECT telegram_id FROM users WHERE rest_number=? AND access_level='admin' LIMIT 1`, rn,
-	).Scan(&adminTelegramID)
-	if err != nil {
-		log.Println("Нет админа для предприятия:", rn, err)
+	if len(ids) == 0 {
+		slog.Warn("Нет админа для предприятия", "rest", restNumber)
 	}
-	return adminTelegramID, nil
+	return ids, nil
 }
 
-func GetBalance(db *sql.DB, userID int64) (int, error) {
+func GetBalance(q queryer, userID int64) (int, error) {
 	var balance int
-	err := db.QueryRow("SELECT current_balance FROM users WHERE telegram_id=?",
+	err := q.QueryRow("SELECT current_balance FROM users WHERE telegram_id=?",
 		userID).Scan(&balance)
 	if err != nil {
 		return 0, err
@@ -303,52 +351,120 @@ func SameRest(db *sql.DB, userID int64) (int64, error) {
 	return rn, nil
 }
 
-func CanManagerChangeBalance(db *sql.DB, workerID int64) (bool, string) {
-	var lastTs int64
-	err := db.QueryRow("SELECT last_ts FROM users WHERE telegram_id=?",
-		workerID).Scan(&lastTs)
+// CanManagerChangeBalance проверяет паузу между начислениями одному работнику.
+func CanManagerChangeBalance(q queryer, workerID int64) error {
+	var lastTs sql.NullInt64
+	var restNumber int
+	err := q.QueryRow("SELECT last_ts, CAST(COALESCE(rest_number, 0) AS INTEGER) FROM users WHERE telegram_id=?",
+		workerID).Scan(&lastTs, &restNumber)
 	if err != nil && err != sql.ErrNoRows {
-		return false, "DB error"
+		return err
 	}
-	if lastTs == 0 {
-		return true, ""
+	if lastTs.Int64 == 0 {
+		return nil
 	}
-	epl := time.Now().Unix() - lastTs
-	if epl < 12*3600 {
-		left := 12*3600 - epl
+	cooldown := int64(GetRestSetting(q, restNumber, "topup_cooldown_hours")) * 3600
+	epl := time.Now().Unix() - lastTs.Int64
+	if epl < cooldown {
+		left := cooldown - epl
 		hours := left / 3600
 		mins := (left % 3600) / 60
-		return false, fmt.Sprintf("❗ Cooldown: %d housrs  %d mins", hours, mins)
+		return i18n.NewError("topup.cooldown", hours, mins)
 	}
-	return true, ""
+	return nil
 }
 
-func TopUpBalance(db *sql.DB, workerID int64, amount int) (string, bool, error) {
-	ok, msg := CanManagerChangeBalance(db, workerID)
-	if !ok {
-		return msg, false, nil
-	} else {
-		tx, err := db.Begin()
-		if err != nil {
-			return "Miss begin transaction", false, err
-		}
-		//rising balance
-		_, err = tx.Exec("UPDATE users SET current_balance = current_balance + ? WHERE telegram_id=?",
-			amount, workerID)
-		if err != nil {
-			tx.Rollback()
-			return "Err updating balance", false, err
+// CanManagerSpend проверяет недельный бюджет менеджера. Админов бюджет не ограничивает.
+func CanManagerSpend(q queryer, actorID int64, amount int) error {
+	level, err := GetAccessLevel(q, actorID)
+	if err != nil {
+		return err
+	}
+	if level != "manager" {
+		return nil
+	}
+	left, budget, limited, err := ManagerBudgetLeft(q, actorID)
+	if err != nil {
+		return err
+	}
+	if limited && amount > left {
+		return i18n.NewError("topup.budget_exceeded", left, budget)
+	}
+	return nil
+}
+
+// TopUpAmounts — суммы начисления, которые менеджер выбирает кнопками.
+var TopUpAmounts = []int{1, 2}
+
+// IsTopUpAmount — допустима ли сумма начисления.
+func IsTopUpAmount(amount int) bool {
+	for _, a := range TopUpAmounts {
+		if a == amount {
+			return true
 		}
-		//buf time operation
-		now := time.Now().Unix()
-		_, err = tx.Exec("UPDATE users SET last_ts=? WHERE telegram_id=?",
-			now, workerID)
-		if err != nil {
-			tx.Rollback()
-			return "Err buf operation", false, err
+	}
+	return false
+}
+
+// TopUpBalance начисляет работнику amount звёзд от имени actorID и возвращает
+// новый баланс. Нарушение правил (сумма, пауза, бюджет менеджера, максимальный
+// баланс) возвращается как *i18n.Error.
+func TopUpBalance(db *sql.DB, actorID, workerID int64, amount int) (int, error) {
+	return TopUpBalanceContext(context.Background(), db, actorID, workerID, amount)
+}
+
+// TopUpBalanceContext — TopUpBalance с контекстом обновления для запросов к базе.
+func TopUpBalanceContext(ctx context.Context, db *sql.DB, actorID, workerID int64, amount int) (int, error) {
+	if !IsTopUpAmount(amount) {
+		return 0, i18n.NewError("topup.err_amount")
+	}
+	q := withContext{ctx, db}
+	restNumber, err := GetUserRestID(q, workerID)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().Unix()
+	cooldown := int64(GetRestSetting(q, restNumber, "topup_cooldown_hours")) * 3600
+
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer sqlTx.Rollback()
+	tx := withContext{ctx, sqlTx}
+	// Пауза проверяется самим UPDATE: из двух параллельных начислений пройдёт одно
+	res, err := tx.Exec(`UPDATE users SET current_balance = current_balance + ?,
+		all_time_balance = COALESCE(all_time_balance, 0) + ?, last_ts=?
+		WHERE telegram_id=? AND (last_ts IS NULL OR last_ts=0 OR last_ts <= ?)`,
+		amount, amount, now, workerID, now-cooldown)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if err = CanManagerChangeBalance(tx, workerID); err != nil {
+			return 0, err
 		}
-		tx.Commit()
-		cb, _ := GetBalance(db, workerID)
-		return fmt.Sprintf("Balance is topped up on: %d, current balance: %d", amount, cb), ok, nil
+		return 0, sql.ErrNoRows
+	}
+	// Бюджет и максимальный баланс проверяются в той же транзакции, после блокировки строки работника
+	if err = CanManagerSpend(tx, actorID, amount); err != nil {
+		return 0, err
+	}
+	balance, err := GetBalance(tx, workerID)
+	if err != nil {
+		return 0, err
+	}
+	if limit := GetRestSetting(tx, restNumber, "max_balance"); limit > 0 && balance > limit {
+		return 0, i18n.NewError("topup.max_balance", max(0, limit-balance+amount))
+	}
+	//history
+	if err = AddTransaction(tx, workerID, amount, TxTopUp, actorID, ""); err != nil {
+		return 0, err
+	}
+	if err = sqlTx.Commit(); err != nil {
+		return 0, err
 	}
+	countStars(amount, TxTopUp)
+	EvaluateAchievements(db, workerID, EventTopUp)
+	return balance, nil
 }
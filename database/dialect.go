@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+)
+
+// Поддерживаемые хранилища (DB_DRIVER)
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// DefaultSQLitePath — файл базы SQLite, если DB_DSN не задан.
+const DefaultSQLitePath = "botdata.db"
+
+// timeLayout — формат меток времени в параметрах запросов: так CURRENT_TIMESTAMP
+// хранится в SQLite, и в таком же виде его принимает PostgreSQL.
+const timeLayout = "2006-01-02 15:04:05"
+
+// NormalizeDriver приводит имя драйвера к одному из Driver*; пустое — SQLite.
+func NormalizeDriver(driver string) (string, error) {
+	switch driver {
+	case "", DriverSQLite, "sqlite3":
+		return DriverSQLite, nil
+	case DriverPostgres, "postgresql", "pg":
+		return DriverPostgres, nil
+	}
+	return "", fmt.Errorf("неизвестный драйвер БД %q (ожидается sqlite или postgres)", driver)
+}
+
+// Open открывает базу и настраивает соединение под выбранный драйвер.
+// Все запросы пакета пишутся с плейсхолдерами ? — для PostgreSQL они
+// переводятся в $1, $2… на уровне соединения.
+func Open(driver, dsn string) (*sql.DB, error) {
+	driver, err := NormalizeDriver(driver)
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverPostgres {
+		return openPostgres(dsn)
+	}
+	return openSQLite(dsn)
+}
+
+func openSQLite(path string) (*sql.DB, error) {
+	if path == "" {
+		path = DefaultSQLitePath
+	}
+	// --- Настройки SQLite для надежности и производительности ---
+	// busy_timeout и synchronous действуют на одно соединение, поэтому задаются в DSN:
+	// драйвер выполняет их при открытии каждого соединения пула, а не только первого.
+	// busy_timeout — время ожидания при блокировке базы данных (в миллисекундах):
+	// SQLite подождёт, пока блокировка не снимется, вместо немедленного возврата ошибки.
+	// synchronous=FULL гарантирует, что данные будут записаны на диск перед тем, как
+	// операция COMMIT будет считаться завершенной: надежнее ценой скорости записи.
+	db := sql.OpenDB(traceConnector{dsnConnector{sqliteDSN(path), &sqlite.Driver{}}})
+
+	// Включает режим WAL (Write-Ahead Logging)
+	// WAL улучшает производительность одновременного доступа (чтение/запись) и надежность.
+	// Режим хранится в самом файле базы, поэтому достаточно задать его один раз.
+	if _, err := db.Exec("PRAGMA journal_mode=WAL;"); err != nil {
+		slog.Warn("Не удалось включить journal_mode=WAL", "err", err)
+	}
+	return db, nil
+}
+
+// sqlitePragmas выполняются драйвером на каждом новом соединении.
+var sqlitePragmas = []string{"busy_timeout(10000)", "synchronous(FULL)"}
+
+// sqliteDSN дописывает к пути базы sqlitePragmas в виде параметров _pragma.
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	for _, p := range sqlitePragmas {
+		path += sep + "_pragma=" + p
+		sep = "&"
+	}
+	return path
+}
+
+// dsnConnector — driver.Connector для драйвера без OpenConnector.
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+func openPostgres(dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("для PostgreSQL нужен DB_DSN")
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(traceConnector{pgConnector{connector}})
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// since — момент days дней назад в формате timeLayout (UTC).
+func since(days int) string {
+	return time.Now().UTC().AddDate(0, 0, -days).Format(timeLayout)
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+)
+
+// TopPerformer — сотрудник в топе недельного дайджеста.
+type TopPerformer struct {
+	Name        string
+	TableNumber string
+	Stars       int
+}
+
+// RestDigest — сводка по предприятию за период для админов.
+type RestDigest struct {
+	RestNumber     int
+	StarsIssued    int
+	TopUps         int
+	OrdersAccepted int
+	OrdersDenied   int
+	OrdersOpen     int
+	Top            []TopPerformer
+}
+
+// WorkerDigest — сводка по балансу сотрудника за период.
+type WorkerDigest struct {
+	Earned  int
+	Spent   int
+	Balance int
+}
+
+// DigestRecipient — получатель дайджеста.
+type DigestRecipient struct {
+	TelegramID  int64
+	RestNumber  int
+	AccessLevel string
+}
+
+// GetRestDigest собирает сводку по предприятию за последние days дней.
+func GetRestDigest(db *sql.DB, restNumber, days int) (RestDigest, error) {
+	d := RestDigest{RestNumber: restNumber}
+	err := db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM transactions
+WHERE rest_number=? AND type=? AND created_at >= ?`,
+		restNumber, TxTopUp, since(days)).Scan(&d.TopUps, &d.StarsIssued)
+	if err != nil {
+		return d, err
+	}
+	err = db.QueryRow(`SELECT
+       COALESCE(SUM(CASE WHEN status='accept' AND decided_at >= ? THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN status='deny' AND decided_at >= ? THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN status='в сборке' THEN 1 ELSE 0 END), 0)
+FROM orders WHERE rest_number=?`, since(days), since(days), restNumber).
+		Scan(&d.OrdersAccepted, &d.OrdersDenied, &d.OrdersOpen)
+	if err != nil {
+		return d, err
+	}
+
+	rows, err := db.Query(`SELECT COALESCE(u.name, ''), COALESCE(u.table_number, ''), SUM(t.amount) AS stars
+FROM transactions t JOIN users u ON u.telegram_id = t.telegram_id
+WHERE t.rest_number=? AND t.type=? AND t.created_at >= ?
+GROUP BY t.telegram_id, u.name, u.table_number ORDER BY stars DESC LIMIT 3`, restNumber, TxTopUp, since(days))
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p TopPerformer
+		if err := rows.Scan(&p.Name, &p.TableNumber, &p.Stars); err != nil {
+			slog.Error("Ошибка скана в GetRestDigest", "err", err)
+			continue
+		}
+		d.Top = append(d.Top, p)
+	}
+	return d, rows.Err()
+}
+
+// GetWorkerDigest собирает заработок и траты сотрудника за последние days дней.
+// Заработок — операции из EarningTypes; траты — покупки за вычетом возвратов
+// и сгоревшие звёзды.
+func GetWorkerDigest(db *sql.DB, telegramID int64, days int) (WorkerDigest, error) {
+	var d WorkerDigest
+	in, args := earningTypesIn()
+	args = append(args, TxPurchase, TxRefund, TxExpiry, telegramID, since(days))
+	err := db.QueryRow(`SELECT
+       COALESCE(SUM(CASE WHEN type IN `+in+` THEN amount ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN type IN (?, ?, ?) THEN -amount ELSE 0 END), 0)
+FROM transactions WHERE telegram_id=? AND created_at >= ?`, args...).Scan(&d.Earned, &d.Spent)
+	if err != nil {
+		return d, err
+	}
+	d.Balance, err = GetBalance(db, telegramID)
+	return d, err
+}
+
+// ListDigestRecipients — подтверждённые сотрудники незамороженных предприятий.
+func ListDigestRecipients(db *sql.DB) ([]DigestRecipient, error) {
+	rows, err := db.Query(`SELECT telegram_id, CAST(COALESCE(rest_number, 0) AS INTEGER), COALESCE(access_level, '')
+FROM users
+WHERE verified=1 AND rest_number NOT IN (SELECT rest_number FROM restaurants WHERE frozen=1)
+ORDER BY rest_number`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []DigestRecipient
+	for rows.Next() {
+		var r DigestRecipient
+		if err := rows.Scan(&r.TelegramID, &r.RestNumber, &r.AccessLevel); err != nil {
+			slog.Error("Ошибка скана в ListDigestRecipients", "err", err)
+			continue
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetWorkerDigest(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	const userID = int64(100)
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, current_balance) VALUES (?, 1, 1, 7)`, userID); err != nil {
+		t.Fatal(err)
+	}
+	ops := []struct {
+		amount int
+		typ    string
+	}{
+		{3, TxTopUp}, {2, TxBonus}, {1, TxKudos}, // заработок
+		{-5, TxPurchase}, {2, TxRefund}, {-1, TxExpiry}, // траты: 5 − 2 + 1
+		{4, TxCorrection}, {-3, TxTransfer}, // не заработок и не траты
+	}
+	for _, op := range ops {
+		if err := AddTransaction(db, userID, op.amount, op.typ, 1, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Операция до начала периода не учитывается
+	old := time.Now().UTC().AddDate(0, 0, -10).Format(timeLayout)
+	if _, err := db.Exec(`INSERT INTO transactions (telegram_id, rest_number, amount, type, created_at) VALUES (?, 1, 50, ?, ?)`,
+		userID, TxTopUp, old); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := GetWorkerDigest(db, userID, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Earned != 6 || d.Spent != 4 || d.Balance != 7 {
+		t.Errorf("дайджест %+v, want Earned 6, Spent 4, Balance 7", d)
+	}
+}
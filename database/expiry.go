@@ -0,0 +1,313 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"tbViT/i18n"
+	"time"
+)
+
+// ExpiryWarnDays — за сколько дней до сгорания звёзд предупреждать работника.
+var ExpiryWarnDays = 14
+
+// StarLot — непотраченный остаток одного начисления. Opening — остаток, начисленный
+// до появления истории операций: он тратится первым и не сгорает.
+type StarLot struct {
+	Amount   int
+	EarnedAt time.Time
+	Opening  bool
+}
+
+// queryer — чтение, общее для *sql.DB и *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// UnspentLots восстанавливает по истории операций, какие начисления ещё не потрачены.
+// Списания гасят самые старые начисления первыми (FIFO). Разница между балансом и
+// суммой истории — звёзды, начисленные до её появления, — идёт первым лотом.
+// Возврат за отменённый заказ восстанавливает лоты, потраченные на покупки, с их
+// прежними датами, поэтому отмена заказа не продлевает срок жизни звёзд.
+func UnspentLots(db *sql.DB, telegramID int64) ([]StarLot, error) {
+	return unspentLots(db, telegramID)
+}
+
+func unspentLots(q queryer, telegramID int64) ([]StarLot, error) {
+	type op struct {
+		amount int
+		txType string
+		at     time.Time
+	}
+	rows, err := q.Query(`SELECT amount, type, created_at FROM transactions WHERE telegram_id=? ORDER BY id`, telegramID)
+	if err != nil {
+		return nil, err
+	}
+	var ops []op
+	sum := 0
+	for rows.Next() {
+		var o op
+		if err := rows.Scan(&o.amount, &o.txType, &o.at); err != nil {
+			slog.Error("Ошибка скана в UnspentLots", "err", err)
+			continue
+		}
+		ops = append(ops, o)
+		sum += o.amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var balance int
+	err = q.QueryRow(`SELECT COALESCE(current_balance, 0) FROM users WHERE telegram_id=?`, telegramID).Scan(&balance)
+	if err != nil {
+		return nil, err
+	}
+
+	var lots []StarLot
+	if opening := balance - sum; opening > 0 {
+		lots = append(lots, StarLot{Amount: opening, Opening: true})
+	}
+	// Потраченные на покупки части лотов в порядке списания — из них восстанавливаются возвраты
+	var bought []StarLot
+	for _, o := range ops {
+		switch {
+		case o.amount > 0 && o.txType == TxRefund:
+			rest := o.amount
+			for rest > 0 && len(bought) > 0 {
+				last := &bought[len(bought)-1]
+				part := *last
+				if part.Amount > rest {
+					part.Amount = rest
+				}
+				last.Amount -= part.Amount
+				if last.Amount == 0 {
+					bought = bought[:len(bought)-1]
+				}
+				lots = restoreLot(lots, part)
+				rest -= part.Amount
+			}
+			if rest > 0 {
+				lots = append(lots, StarLot{Amount: rest, EarnedAt: o.at})
+			}
+		case o.amount > 0:
+			lots = append(lots, StarLot{Amount: o.amount, EarnedAt: o.at})
+		default:
+			// Сгорание гасит сгоревшие лоты, а начальный остаток не сгорает и остаётся первым
+			first := 0
+			if o.txType == TxExpiry && len(lots) > 0 && lots[0].Opening {
+				first = 1
+			}
+			debit := -o.amount
+			for debit > 0 && len(lots) > first {
+				part := lots[first]
+				if part.Amount > debit {
+					part.Amount = debit
+					lots[first].Amount -= debit
+				} else {
+					lots = append(lots[:first], lots[first+1:]...)
+				}
+				debit -= part.Amount
+				if o.txType == TxPurchase {
+					bought = append(bought, part)
+				}
+			}
+		}
+	}
+	return lots, nil
+}
+
+// restoreLot возвращает часть лота на её место в очереди FIFO.
+func restoreLot(lots []StarLot, l StarLot) []StarLot {
+	i := 0
+	for i < len(lots) && (lots[i].Opening || !l.Opening && !lots[i].EarnedAt.After(l.EarnedAt)) {
+		i++
+	}
+	if i > 0 && lots[i-1].Opening == l.Opening && lots[i-1].EarnedAt.Equal(l.EarnedAt) {
+		lots[i-1].Amount += l.Amount
+		return lots
+	}
+	return append(lots[:i], append([]StarLot{l}, lots[i:]...)...)
+}
+
+// sumLots — сумма остатков, начисленных в полуинтервале (from, to]. Лот Opening не сгорает.
+func sumLots(lots []StarLot, from, to time.Time) int {
+	sum := 0
+	for _, l := range lots {
+		if !l.Opening && l.EarnedAt.After(from) && !l.EarnedAt.After(to) {
+			sum += l.Amount
+		}
+	}
+	return sum
+}
+
+// ExpiryCutoff — звёзды, начисленные не позже этого момента, сгорают при months > 0.
+func ExpiryCutoff(now time.Time, months int) time.Time {
+	return now.UTC().AddDate(0, -months, 0)
+}
+
+// NextExpiry — ближайшее сгорание: сколько звёзд и когда. amount == 0 — сгорать нечему.
+func NextExpiry(db *sql.DB, telegramID int64, months int) (amount int, at time.Time, err error) {
+	lots, err := UnspentLots(db, telegramID)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	for _, l := range lots {
+		if l.Opening {
+			continue
+		}
+		day := l.EarnedAt.AddDate(0, months, 0)
+		if amount == 0 {
+			at = day
+		}
+		if day.Format("2006-01-02") == at.Format("2006-01-02") {
+			amount += l.Amount
+		}
+	}
+	return amount, at, nil
+}
+
+// ExpireStars списывает звёзды, которые уже должны сгореть, и возвращает их число.
+// Расчёт и списание идут в одной транзакции, а баланс уменьшается только если его
+// хватает: покупка, сделанная между расчётом и списанием, не уведёт баланс в минус.
+// Если баланс успел уменьшиться, списание откладывается до следующего запуска.
+func ExpireStars(db *sql.DB, telegramID int64, months int, now time.Time) (int, error) {
+	comment := i18n.T(UserLang(db, telegramID), "expiry.tx_comment", months)
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	lots, err := unspentLots(tx, telegramID)
+	if err != nil {
+		return 0, err
+	}
+	amount := sumLots(lots, time.Time{}, ExpiryCutoff(now, months))
+	var balance int
+	if err := tx.QueryRow(`SELECT COALESCE(current_balance, 0) FROM users WHERE telegram_id=?`, telegramID).Scan(&balance); err != nil {
+		return 0, err
+	}
+	if amount > balance {
+		amount = balance
+	}
+	if amount <= 0 {
+		return 0, nil
+	}
+	res, err := tx.Exec(`UPDATE users SET current_balance = current_balance - ? WHERE telegram_id=? AND current_balance >= ?`,
+		amount, telegramID, amount)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return 0, err
+	}
+	if err := AddTransaction(tx, telegramID, -amount, TxExpiry, 0, comment); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	countStars(-amount, TxExpiry)
+	return amount, nil
+}
+
+// ExpiringSoon — сколько звёзд сгорит в ближайшие warnDays дней и ещё не было
+// предупреждения. Граница предупреждений хранится в users.expiry_warned_until.
+func ExpiringSoon(db *sql.DB, telegramID int64, months, warnDays int, now time.Time) (int, time.Time, error) {
+	var warnedUntil int64
+	err := db.QueryRow(`SELECT COALESCE(expiry_warned_until, 0) FROM users WHERE telegram_id=?`, telegramID).Scan(&warnedUntil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	lots, err := UnspentLots(db, telegramID)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	from := ExpiryCutoff(now, months)
+	if w := time.Unix(warnedUntil, 0); w.After(from) {
+		from = w
+	}
+	to := ExpiryCutoff(now.AddDate(0, 0, warnDays), months)
+	return sumLots(lots, from, to), to, nil
+}
+
+// SetExpiryWarned запоминает, до какого момента начислений работник уже предупреждён.
+func SetExpiryWarned(db *sql.DB, telegramID int64, until time.Time) error {
+	_, err := db.Exec(`UPDATE users SET expiry_warned_until=? WHERE telegram_id=?`, until.Unix(), telegramID)
+	return err
+}
+
+// BalanceHolder — работник с положительным балансом для задачи сгорания.
+type BalanceHolder struct {
+	TelegramID int64
+	RestNumber int
+	Balance    int
+}
+
+// ListBalanceHolders — подтверждённые пользователи с положительным балансом.
+func ListBalanceHolders(db *sql.DB) ([]BalanceHolder, error) {
+	rows, err := db.Query(`SELECT telegram_id, CAST(COALESCE(rest_number, 0) AS INTEGER), current_balance
+FROM users WHERE verified=1 AND current_balance > 0 ORDER BY rest_number`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []BalanceHolder
+	for rows.Next() {
+		var h BalanceHolder
+		if err := rows.Scan(&h.TelegramID, &h.RestNumber, &h.Balance); err != nil {
+			slog.Error("Ошибка скана в ListBalanceHolders", "err", err)
+			continue
+		}
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+// BalanceRoom — сколько ещё можно начислить до максимального баланса предприятия.
+// limited == false — максимум не задан.
+func BalanceRoom(q queryer, telegramID int64) (room int, limited bool, err error) {
+	restNumber, err := GetUserRestID(q, telegramID)
+	if err != nil {
+		return 0, false, err
+	}
+	max := GetRestSetting(q, restNumber, "max_balance")
+	if max == 0 {
+		return 0, false, nil
+	}
+	balance, err := GetBalance(q, telegramID)
+	if err != nil {
+		return 0, false, err
+	}
+	room = max - balance
+	if room < 0 {
+		room = 0
+	}
+	return room, true, nil
+}
+
+// CanReceive проверяет, что начисление не превысит максимальный баланс предприятия.
+func CanReceive(q queryer, workerID int64, amount int) error {
+	room, limited, err := BalanceRoom(q, workerID)
+	if err != nil {
+		return err
+	}
+	if limited && amount > room {
+		return i18n.NewError("topup.max_balance", room)
+	}
+	return nil
+}
+
+// capCredit урезает автоматическое начисление (бонус, благодарность) до максимального баланса.
+func capCredit(q queryer, telegramID int64, amount int) int {
+	room, limited, err := BalanceRoom(q, telegramID)
+	if err != nil {
+		slog.Error("Ошибка проверки максимального баланса", "user_id", telegramID, "err", err)
+		return amount
+	}
+	if limited && amount > room {
+		return room
+	}
+	return amount
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnspentLots(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	const userID = int64(100)
+	// Баланс 25: 5 звёзд начислены до истории операций, остальное — по истории ниже
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, current_balance) VALUES (?, 1, 1, 25)`, userID); err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 12, 0, 0, 0, time.UTC) }
+	ops := []struct {
+		amount int
+		txType string
+		at     time.Time
+	}{
+		{10, TxTopUp, day(1)},
+		{10, TxTopUp, day(2)},
+		{-12, TxPurchase, day(3)}, // 5 из начального остатка и 7 из лота 1 января
+		{-3, TxExpiry, day(4)},    // 3 из лота 1 января
+		{12, TxRefund, day(5)},    // покупка отменена: лоты возвращаются со своими датами
+		{3, TxKudos, day(6)},
+	}
+	for _, o := range ops {
+		_, err := db.Exec(`INSERT INTO transactions (telegram_id, amount, type, created_at) VALUES (?, ?, ?, ?)`,
+			userID, o.amount, o.txType, o.at.Format(timeLayout))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// История: 10+10-12-3+12+3 = 20, баланс 25 — начальный остаток 5
+
+	lots, err := UnspentLots(db, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StarLot{
+		{Amount: 5, Opening: true},
+		{Amount: 7, EarnedAt: day(1)},
+		{Amount: 10, EarnedAt: day(2)},
+		{Amount: 3, EarnedAt: day(6)},
+	}
+	if len(lots) != len(want) {
+		t.Fatalf("лоты %+v, want %+v", lots, want)
+	}
+	for i := range want {
+		if lots[i].Amount != want[i].Amount || lots[i].Opening != want[i].Opening || !lots[i].EarnedAt.Equal(want[i].EarnedAt) {
+			t.Errorf("лот %d = %+v, want %+v", i, lots[i], want[i])
+		}
+	}
+
+	// Через два месяца после 1 января сгорает только его остаток
+	now := day(1).AddDate(0, 2, 0).Add(time.Hour)
+	amount, err := ExpireStars(db, userID, 2, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount != 7 {
+		t.Fatalf("сгорело %d, want 7", amount)
+	}
+	if b, _ := GetBalance(db, userID); b != 18 {
+		t.Errorf("баланс %d, want 18", b)
+	}
+	// Повторный запуск ничего не списывает
+	if amount, err := ExpireStars(db, userID, 2, now); err != nil || amount != 0 {
+		t.Errorf("повторное сгорание %d, %v", amount, err)
+	}
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"tbViT/i18n"
+	"time"
+)
+
+// Table — табличные данные для выгрузки в CSV/XLSX.
+// Numeric — колонки, числовые по схеме: только их значения выгружаются числами.
+type Table struct {
+	Name    string
+	Header  []string
+	Rows    [][]string
+	Numeric []string
+}
+
+// Period — интервал выгрузки. Нулевые границы означают «без ограничения», To включительно по дню.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParsePeriod разбирает период: число дней ("30"), "0" — всё время, или диапазон "20250101-20250131".
+func ParsePeriod(value string) (Period, bool) {
+	var p Period
+	if days, err := strconv.Atoi(value); err == nil && days >= 0 {
+		if days > 0 {
+			p.From = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+		}
+		return p, true
+	}
+	bounds := strings.SplitN(value, "-", 2)
+	if len(bounds) != 2 {
+		return p, false
+	}
+	from, err1 := time.Parse("20060102", bounds[0])
+	to, err2 := time.Parse("20060102", bounds[1])
+	if err1 != nil || err2 != nil || to.Before(from) {
+		return p, false
+	}
+	return Period{From: from, To: to}, true
+}
+
+func (p Period) String() string {
+	return p.Label(i18n.Default)
+}
+
+// Label — период для показа пользователю на языке lang.
+func (p Period) Label(lang string) string {
+	switch {
+	case p.From.IsZero() && p.To.IsZero():
+		return i18n.T(lang, "period.all")
+	case p.To.IsZero():
+		return i18n.T(lang, "period.since", p.From.Format("2006-01-02"))
+	}
+	return p.From.Format("2006-01-02") + " — " + p.To.Format("2006-01-02")
+}
+
+// where добавляет к условию ограничения по колонке времени column.
+func (p Period) where(column string, where []string, args []interface{}) ([]string, []interface{}) {
+	if !p.From.IsZero() {
+		where = append(where, column+" >= ?")
+		args = append(args, p.From.Format("2006-01-02 15:04:05"))
+	}
+	if !p.To.IsZero() {
+		where = append(where, column+" < ?")
+		args = append(args, p.To.AddDate(0, 0, 1).Format("2006-01-02 15:04:05"))
+	}
+	return where, args
+}
+
+// restWhere ограничивает выборку предприятием; restNumber == 0 — вся сеть.
+func restWhere(column string, restNumber int) ([]string, []interface{}) {
+	if restNumber == 0 {
+		return []string{"1=1"}, nil
+	}
+	return []string{column + "=?"}, []interface{}{restNumber}
+}
+
+func queryTable(db *sql.DB, t Table, query string, args ...interface{}) (Table, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		values := make([]sql.NullString, len(t.Header))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return t, err
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = v.String
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, rows.Err()
+}
+
+// StaffTable — сотрудники с балансами.
+func StaffTable(db *sql.DB, restNumber int) (Table, error) {
+	where, args := restWhere("rest_number", restNumber)
+	t := Table{
+		Name:    "staff",
+		Header:  []string{"rest_number", "table_number", "name", "username", "telegram_id", "access_level", "current_balance"},
+		Numeric: []string{"rest_number", "telegram_id", "current_balance"},
+	}
+	return queryTable(db, t, `SELECT rest_number, table_number, name, username, telegram_id, access_level,
+       COALESCE(current_balance, 0)
+FROM users WHERE verified=1 AND `+strings.Join(where, " AND ")+`
+ORDER BY rest_number, LENGTH(table_number), table_number`, args...)
+}
+
+// OrdersTable — заказы за период.
+func OrdersTable(db *sql.DB, restNumber int, p Period) (Table, error) {
+	where, args := restWhere("o.rest_number", restNumber)
+	where, args = p.where("o.created_at", where, args)
+	t := Table{
+		Name:    "orders",
+		Header:  []string{"id", "created_at", "rest_number", "table_number", "name", "product", "price", "status"},
+		Numeric: []string{"id", "rest_number", "price"},
+	}
+	return queryTable(db, t, `SELECT o.id, o.created_at, o.rest_number, u.table_number, u.name, o.product_name,
+       o.price, o.status
+FROM orders o LEFT JOIN users u ON u.telegram_id = o.telegram_id
+WHERE `+strings.Join(where, " AND ")+` ORDER BY o.created_at`, args...)
+}
+
+// TopUpsTable — начисления за период в разрезе менеджеров.
+func TopUpsTable(db *sql.DB, restNumber int, p Period) (Table, error) {
+	where, args := restWhere("t.rest_number", restNumber)
+	where = append(where, "t.type=?")
+	args = append(args, TxTopUp)
+	where, args = p.where("t.created_at", where, args)
+	t := Table{
+		Name:    "topups",
+		Header:  []string{"rest_number", "manager_id", "table_number", "name", "topups", "stars"},
+		Numeric: []string{"rest_number", "manager_id", "topups", "stars"},
+	}
+	return queryTable(db, t, `SELECT t.rest_number, t.actor_id, u.table_number, u.name, COUNT(*), SUM(t.amount)
+FROM transactions t LEFT JOIN users u ON u.telegram_id = t.actor_id
+WHERE `+strings.Join(where, " AND ")+`
+GROUP BY t.rest_number, t.actor_id, u.table_number, u.name ORDER BY t.rest_number, SUM(t.amount) DESC`, args...)
+}
+
+// StockTable — остатки товаров в магазине.
+func StockTable(db *sql.DB, restNumber int) (Table, error) {
+	where, args := restWhere("rest_number", restNumber)
+	t := Table{
+		Name:    "stock",
+		Header:  []string{"rest_number", "id", "product", "price", "remains"},
+		Numeric: []string{"rest_number", "id", "price", "remains"},
+	}
+	return queryTable(db, t, `SELECT rest_number, id, product, price, remains
+FROM shop WHERE `+strings.Join(where, " AND ")+` ORDER BY rest_number, product`, args...)
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// Job — задача планировщика. Время хранится в unix-секундах, 0 — ещё не было/не назначено.
+type Job struct {
+	Name      string
+	Spec      string
+	NextRun   int64
+	LastRun   int64
+	LastError string
+}
+
+// RegisterJob заносит задачу с расписанием по умолчанию. Если задача уже есть,
+// сохранённое расписание не трогаем: его могли поменять из консоли.
+func RegisterJob(db *sql.DB, name, spec string) error {
+	_, err := db.Exec(`INSERT INTO jobs (name, spec) VALUES (?, ?) ON CONFLICT(name) DO NOTHING`, name, spec)
+	return err
+}
+
+func GetJob(db *sql.DB, name string) (Job, error) {
+	j := Job{Name: name}
+	err := db.QueryRow(`SELECT spec, COALESCE(next_run, 0), COALESCE(last_run, 0), COALESCE(last_error, '')
+FROM jobs WHERE name=?`, name).Scan(&j.Spec, &j.NextRun, &j.LastRun, &j.LastError)
+	return j, err
+}
+
+func ListJobs(db *sql.DB) ([]Job, error) {
+	rows, err := db.Query(`SELECT name, spec, COALESCE(next_run, 0), COALESCE(last_run, 0), COALESCE(last_error, '')
+FROM jobs ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Job
+	for rows.Next() {
+		var j Job
+		if err := rows.Scan(&j.Name, &j.Spec, &j.NextRun, &j.LastRun, &j.LastError); err != nil {
+			slog.Error("Ошибка скана в ListJobs", "err", err)
+			continue
+		}
+		list = append(list, j)
+	}
+	return list, rows.Err()
+}
+
+// ScheduleJob назначает следующий запуск, если он ещё не назначен (next_run пустой).
+func ScheduleJob(db *sql.DB, name string, next time.Time) error {
+	_, err := db.Exec(`UPDATE jobs SET next_run=? WHERE name=? AND COALESCE(next_run, 0)=0`, next.Unix(), name)
+	return err
+}
+
+// ClaimJob забирает запуск задачи: переносит next_run на следующий срок, только если
+// в базе всё ещё лежит ожидаемый due. Так один срок выполняется ровно один раз —
+// и после перезапуска бота, и при нескольких запущенных экземплярах.
+func ClaimJob(db *sql.DB, name string, due int64, next, now time.Time) (bool, error) {
+	res, err := db.Exec(`UPDATE jobs SET next_run=?, last_run=? WHERE name=? AND next_run=?`,
+		next.Unix(), now.Unix(), name, due)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// FinishJob сохраняет результат последнего запуска (пустая строка — успешно).
+func FinishJob(db *sql.DB, name, errText string) {
+	if _, err := db.Exec(`UPDATE jobs SET last_error=? WHERE name=?`, errText, name); err != nil {
+		slog.Error("Ошибка сохранения результата задачи", "job", name, "err", err)
+	}
+}
+
+// SetJobSpec меняет расписание задачи и сбрасывает следующий запуск — его пересчитает планировщик.
+func SetJobSpec(db *sql.DB, name, spec string) error {
+	_, err := db.Exec(`UPDATE jobs SET spec=?, next_run=0 WHERE name=?`, spec, name)
+	return err
+}
+
+// RunJobNow ставит задачу на ближайший тик планировщика.
+func RunJobNow(db *sql.DB, name string) error {
+	_, err := db.Exec(`UPDATE jobs SET next_run=? WHERE name=?`, time.Now().Unix(), name)
+	return err
+}
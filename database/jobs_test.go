@@ -0,0 +1,63 @@
+package database
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Один срок задачи забирает ровно один из одновременных захватов.
+func TestClaimJob(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	if err := RegisterJob(db, "digest", "0 9 * * 1"); err != nil {
+		t.Fatal(err)
+	}
+	due := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	if err := ScheduleJob(db, "digest", due); err != nil {
+		t.Fatal(err)
+	}
+	// Назначенный срок ScheduleJob не перезаписывает
+	if err := ScheduleJob(db, "digest", due.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if j, err := GetJob(db, "digest"); err != nil || j.NextRun != due.Unix() {
+		t.Fatalf("next_run = %d, %v; want %d", j.NextRun, err, due.Unix())
+	}
+
+	now := due.Add(time.Minute)
+	next := due.AddDate(0, 0, 7)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claimed := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := ClaimJob(db, "digest", due.Unix(), next, now)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				claimed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if claimed != 1 {
+		t.Fatalf("срок захвачен %d раз, want 1", claimed)
+	}
+	j, err := GetJob(db, "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.NextRun != next.Unix() || j.LastRun != now.Unix() {
+		t.Errorf("next_run %d, last_run %d; want %d, %d", j.NextRun, j.LastRun, next.Unix(), now.Unix())
+	}
+	// Устаревший срок больше не захватывается
+	if ok, err := ClaimJob(db, "digest", due.Unix(), next, now); err != nil || ok {
+		t.Errorf("повторный захват: %v, %v", ok, err)
+	}
+}
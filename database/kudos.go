@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"tbViT/i18n"
+	"time"
+)
+
+// Статусы благодарностей
+const (
+	KudosPending  = "pending"
+	KudosApproved = "approved"
+	KudosRejected = "rejected"
+)
+
+// KudosMaxLength — максимальная длина сообщения благодарности в символах.
+const KudosMaxLength = 200
+
+// KudosWeeklyAllowance — сколько благодарностей работник может отправить за календарную
+// неделю по умолчанию. Переопределяется настройками предприятия.
+var KudosWeeklyAllowance = 3
+
+// ErrKudosDecided — благодарность уже рассмотрена другим админом.
+var ErrKudosDecided = i18n.NewError("err.kudos_decided")
+
+type Kudos struct {
+	ID         int
+	FromID     int64
+	FromName   string
+	ToID       int64
+	ToName     string
+	RestNumber int
+	Message    string
+	Status     string
+	Stars      int
+	CreatedAt  time.Time
+}
+
+const kudosColumns = `k.id, k.from_id, COALESCE(f.name, ''), k.to_id, COALESCE(t.name, ''),
+CAST(COALESCE(k.rest_number, 0) AS INTEGER), COALESCE(k.message, ''), k.status, COALESCE(k.stars, 0), k.created_at
+FROM kudos k LEFT JOIN users f ON f.telegram_id = k.from_id LEFT JOIN users t ON t.telegram_id = k.to_id`
+
+func kudosFromRows(rows *sql.Rows) ([]Kudos, error) {
+	defer rows.Close()
+	var list []Kudos
+	for rows.Next() {
+		var k Kudos
+		if err := rows.Scan(&k.ID, &k.FromID, &k.FromName, &k.ToID, &k.ToName, &k.RestNumber,
+			&k.Message, &k.Status, &k.Stars, &k.CreatedAt); err != nil {
+			slog.Error("Ошибка скана благодарности", "err", err)
+			continue
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+// KudosLeft — сколько благодарностей работник ещё может отправить на этой неделе.
+// Отклонённые админом в лимит не засчитываются.
+func KudosLeft(db *sql.DB, fromID int64) (left, allowance int, err error) {
+	restNumber, err := GetUserRestID(db, fromID)
+	if err != nil {
+		return 0, 0, err
+	}
+	allowance = GetRestSetting(db, restNumber, "kudos_weekly_allowance")
+	var sent int
+	err = db.QueryRow(`SELECT COUNT(*) FROM kudos WHERE from_id=? AND status!=? AND created_at >= ?`,
+		fromID, KudosRejected, periodStart(PeriodWeek)).Scan(&sent)
+	if err != nil {
+		return 0, allowance, err
+	}
+	if sent >= allowance {
+		return 0, allowance, nil
+	}
+	return allowance - sent, allowance, nil
+}
+
+// CreateKudos сохраняет благодарность на модерацию.
+func CreateKudos(db *sql.DB, fromID, toID int64, message string) (int, error) {
+	if fromID == toID {
+		return 0, i18n.NewError("err.kudos_self")
+	}
+	restNumber, err := GetUserRestID(db, fromID)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = db.QueryRow(`INSERT INTO kudos (from_id, to_id, rest_number, message, status) VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		fromID, toID, restNumber, message, KudosPending).Scan(&id)
+	return id, err
+}
+
+func GetKudos(q queryer, id int) (Kudos, error) {
+	rows, err := q.Query(`SELECT `+kudosColumns+` WHERE k.id=?`, id)
+	if err != nil {
+		return Kudos{}, err
+	}
+	list, err := kudosFromRows(rows)
+	if err != nil {
+		return Kudos{}, err
+	}
+	if len(list) == 0 {
+		return Kudos{}, sql.ErrNoRows
+	}
+	return list[0], nil
+}
+
+// PendingKudos — благодарности предприятия, ожидающие модерации (старые первыми).
+func PendingKudos(db *sql.DB, restNumber int) ([]Kudos, error) {
+	rows, err := db.Query(`SELECT `+kudosColumns+` WHERE k.rest_number=? AND k.status=? ORDER BY k.id`,
+		restNumber, KudosPending)
+	if err != nil {
+		return nil, err
+	}
+	return kudosFromRows(rows)
+}
+
+// ApproveKudos одобряет благодарность и, если на предприятии включена конвертация,
+// начисляет получателю звёзды в пределах максимального баланса и проверяет его
+// достижения. Возвращает начисленную сумму.
+func ApproveKudos(db *sql.DB, id int, adminID int64) (int, error) {
+	return ApproveKudosContext(context.Background(), db, id, adminID)
+}
+
+// ApproveKudosContext — ApproveKudos с контекстом обновления для запросов к базе.
+func ApproveKudosContext(ctx context.Context, db *sql.DB, id int, adminID int64) (int, error) {
+	q := withContext{ctx, db}
+	k, err := GetKudos(q, id)
+	if err != nil {
+		return 0, err
+	}
+	stars := capCredit(q, k.ToID, GetRestSetting(q, k.RestNumber, "kudos_star_rate"))
+
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	tx := withContext{ctx, sqlTx}
+	res, err := tx.Exec(`UPDATE kudos SET status=?, stars=?, decided_by=?, decided_at=CURRENT_TIMESTAMP
+WHERE id=? AND status=?`, KudosApproved, stars, adminID, id, KudosPending)
+	if err != nil {
+		sqlTx.Rollback()
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		sqlTx.Rollback()
+		return 0, ErrKudosDecided
+	}
+	if stars > 0 {
+		_, err = tx.Exec(`UPDATE users SET current_balance = current_balance + ?,
+			all_time_balance = COALESCE(all_time_balance, 0) + ? WHERE telegram_id=?`, stars, stars, k.ToID)
+		if err == nil {
+			err = AddTransaction(tx, k.ToID, stars, TxKudos, k.FromID, k.Message)
+		}
+		if err != nil {
+			sqlTx.Rollback()
+			return 0, err
+		}
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return 0, err
+	}
+	if stars > 0 {
+		countStars(stars, TxKudos)
+		EvaluateAchievements(db, k.ToID, EventKudos)
+	}
+	return stars, nil
+}
+
+// RejectKudos отклоняет благодарность; отправителю возвращается одна из недельного лимита.
+func RejectKudos(db *sql.DB, id int, adminID int64) error {
+	res, err := db.Exec(`UPDATE kudos SET status=?, decided_by=?, decided_at=CURRENT_TIMESTAMP
+WHERE id=? AND status=?`, KudosRejected, adminID, id, KudosPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrKudosDecided
+	}
+	return nil
+}
+
+// CountReceivedKudos — сколько одобренных благодарностей получил работник.
+func CountReceivedKudos(db *sql.DB, telegramID int64) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM kudos WHERE to_id=? AND status=?`, telegramID, KudosApproved).Scan(&n)
+	return n, err
+}
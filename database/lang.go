@@ -0,0 +1,41 @@
+package database
+
+import (
+	"database/sql"
+	"tbViT/i18n"
+)
+
+// UserLang — язык интерфейса пользователя; если не выбран — язык по умолчанию.
+func UserLang(db *sql.DB, telegramID int64) string {
+	var lang sql.NullString
+	db.QueryRow(`SELECT language FROM users WHERE telegram_id=?`, telegramID).Scan(&lang)
+	if !lang.Valid || lang.String == "" {
+		return i18n.Default
+	}
+	return i18n.Normalize(lang.String)
+}
+
+// SetUserLang сохраняет выбранный пользователем язык.
+func SetUserLang(db *sql.DB, telegramID int64, lang string) error {
+	_, err := db.Exec(`UPDATE users SET language=? WHERE telegram_id=?`, i18n.Normalize(lang), telegramID)
+	return err
+}
+
+// InitUserLang проставляет язык из language_code Telegram, если пользователь его ещё не выбирал.
+func InitUserLang(db *sql.DB, telegramID int64, languageCode string) {
+	if languageCode == "" {
+		return
+	}
+	db.Exec(`UPDATE users SET language=? WHERE telegram_id=? AND (language IS NULL OR language='')`,
+		i18n.Normalize(languageCode), telegramID)
+}
+
+// Tr — текст по ключу на языке пользователя telegramID.
+func Tr(db *sql.DB, telegramID int64, key string, args ...interface{}) string {
+	return i18n.T(UserLang(db, telegramID), key, args...)
+}
+
+// TrN — текст с формой множественного числа на языке пользователя telegramID.
+func TrN(db *sql.DB, telegramID int64, key string, n int, args ...interface{}) string {
+	return i18n.N(UserLang(db, telegramID), key, n, args...)
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Периоды рейтинга
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodAll   = "all"
+)
+
+// EarningTypes — операции, которые считаются заработком для рейтинга и all_time_balance.
+var EarningTypes = []string{TxTopUp, TxBonus, TxKudos}
+
+// LeaderboardEntry — строка рейтинга.
+type LeaderboardEntry struct {
+	Rank        int
+	TelegramID  int64
+	Name        string
+	TableNumber string
+	RestNumber  int
+	Stars       int
+}
+
+// periodStart — начало текущей календарной недели (с понедельника) или месяца в UTC.
+func periodStart(period string) string {
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if period == PeriodMonth {
+		return day.AddDate(0, 0, 1-day.Day()).Format(timeLayout)
+	}
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7).Format(timeLayout)
+}
+
+func earningTypesIn() (string, []interface{}) {
+	args := make([]interface{}, len(EarningTypes))
+	for i, t := range EarningTypes {
+		args[i] = t
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?,", len(EarningTypes)), ",") + ")", args
+}
+
+// Leaderboard возвращает рейтинг за период по предприятию (restNumber == 0 — вся сеть).
+// Скрывшиеся из рейтинга сотрудники в него не попадают.
+func Leaderboard(db *sql.DB, period string, restNumber int) ([]LeaderboardEntry, error) {
+	where := []string{"u.verified=1", "COALESCE(u.leaderboard_hidden, 0)=0"}
+	var args []interface{}
+	var query string
+
+	if period == PeriodAll {
+		where = append(where, "COALESCE(u.all_time_balance, 0) > 0")
+		if restNumber != 0 {
+			where = append(where, "u.rest_number=?")
+			args = append(args, restNumber)
+		}
+		query = `SELECT u.telegram_id, COALESCE(u.name, ''), COALESCE(u.table_number, ''),
+       CAST(COALESCE(u.rest_number, 0) AS INTEGER), COALESCE(u.all_time_balance, 0) AS stars
+FROM users u WHERE ` + strings.Join(where, " AND ") + ` ORDER BY stars DESC, u.name`
+	} else {
+		in, typeArgs := earningTypesIn()
+		args = append(args, typeArgs...)
+		args = append(args, periodStart(period))
+		if restNumber != 0 {
+			where = append(where, "u.rest_number=?")
+			args = append(args, restNumber)
+		}
+		query = `SELECT u.telegram_id, COALESCE(u.name, ''), COALESCE(u.table_number, ''),
+       CAST(COALESCE(u.rest_number, 0) AS INTEGER), SUM(t.amount) AS stars
+FROM transactions t JOIN users u ON u.telegram_id = t.telegram_id
+WHERE t.type IN ` + in + ` AND t.created_at >= ? AND ` + strings.Join(where, " AND ") + `
+GROUP BY u.telegram_id, u.name, u.table_number, u.rest_number HAVING SUM(t.amount) > 0 ORDER BY stars DESC, u.name`
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []LeaderboardEntry
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.TelegramID, &e.Name, &e.TableNumber, &e.RestNumber, &e.Stars); err != nil {
+			slog.Error("Ошибка скана в Leaderboard", "err", err)
+			continue
+		}
+		// Одинаковый результат — одинаковое место
+		e.Rank = len(list) + 1
+		if len(list) > 0 && list[len(list)-1].Stars == e.Stars {
+			e.Rank = list[len(list)-1].Rank
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// UserEarnings — заработок пользователя за период (для тех, кто скрыт из рейтинга).
+func UserEarnings(db *sql.DB, telegramID int64, period string) (int, error) {
+	var stars int
+	if period == PeriodAll {
+		err := db.QueryRow(`SELECT COALESCE(all_time_balance, 0) FROM users WHERE telegram_id=?`, telegramID).Scan(&stars)
+		return stars, err
+	}
+	in, args := earningTypesIn()
+	err := db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transactions
+WHERE telegram_id=? AND type IN `+in+` AND created_at >= ?`,
+		append(append([]interface{}{telegramID}, args...), periodStart(period))...).Scan(&stars)
+	return stars, err
+}
+
+func IsLeaderboardHidden(db *sql.DB, telegramID int64) bool {
+	var hidden bool
+	db.QueryRow(`SELECT COALESCE(leaderboard_hidden, 0) FROM users WHERE telegram_id=?`, telegramID).Scan(&hidden)
+	return hidden
+}
+
+func SetLeaderboardHidden(db *sql.DB, telegramID int64, hidden bool) error {
+	_, err := db.Exec(`UPDATE users SET leaderboard_hidden=? WHERE telegram_id=?`, hidden, telegramID)
+	return err
+}
+
+// BackfillAllTimeBalance заполняет all_time_balance по истории операций там, где он ещё не считался.
+func BackfillAllTimeBalance(db *sql.DB) error {
+	in, args := earningTypesIn()
+	_, err := db.Exec(`UPDATE users SET all_time_balance = (
+    SELECT COALESCE(SUM(amount), 0) FROM transactions t
+    WHERE t.telegram_id = users.telegram_id AND t.type IN `+in+`)
+WHERE all_time_balance IS NULL`, args...)
+	return err
+}
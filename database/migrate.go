@@ -0,0 +1,296 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// migration — версия схемы. Запросы пишутся на диалекте SQLite, для PostgreSQL
+// типы переводятся в ddl. Версии применяются по порядку, каждая — одной транзакцией.
+type migration struct {
+	Version int
+	Name    string
+	Stmts   []string
+}
+
+var migrations = []migration{
+	{1, "базовая схема", []string{
+		`CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		telegram_id INTEGER UNIQUE NOT NULL,
+		username TEXT,
+		name TEXT,
+		table_number TEXT,
+		rest_number INTEGER,
+		access_level TEXT,
+		verified INTEGER,
+		reg_state TEXT,
+		current_balance INTEGER,
+		all_time_balance INTEGER,
+		last_ts INTEGER,
+		tmp_field INTEGER,
+		special_roll TEXT,
+		registration_start_time DATETIME
+	)`,
+		`CREATE TABLE IF NOT EXISTS shop (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		product TEXT NOT NULL,
+		price INTEGER NOT NULL,
+		remains INTEGER,
+		rest_number INTEGER
+	)`,
+		`CREATE TABLE IF NOT EXISTS orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		telegram_id INTEGER NOT NULL,
+		product_name TEXT,
+		status TEXT,
+		product_id INTEGER,
+		rest_number INTEGER,
+		price INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+		`CREATE TABLE IF NOT EXISTS transfers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		telegram_id INTEGER NOT NULL,
+		from_rest INTEGER,
+		to_rest INTEGER,
+		balance_mode TEXT,
+		orders_mode TEXT,
+		status TEXT,
+		initiator_id INTEGER,
+		decided_by INTEGER,
+		balance_before INTEGER,
+		balance_after INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		decided_at TIMESTAMP
+	)`,
+		`CREATE TABLE IF NOT EXISTS restaurants (
+		rest_number INTEGER PRIMARY KEY,
+		frozen INTEGER DEFAULT 0
+	)`,
+		`CREATE TABLE IF NOT EXISTS super_users (
+		telegram_id INTEGER PRIMARY KEY,
+		source TEXT,
+		added_by INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER NOT NULL,
+		rest_number INTEGER,
+		action TEXT NOT NULL,
+		target TEXT,
+		before_value TEXT,
+		after_value TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+		`CREATE TABLE IF NOT EXISTS transactions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		telegram_id INTEGER NOT NULL,
+		rest_number INTEGER,
+		amount INTEGER NOT NULL,
+		balance_after INTEGER,
+		type TEXT NOT NULL,
+		actor_id INTEGER,
+		comment TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+		`CREATE INDEX IF NOT EXISTS idx_transactions_user ON transactions (telegram_id, created_at)`,
+		`CREATE TABLE IF NOT EXISTS jobs (
+		name TEXT PRIMARY KEY,
+		spec TEXT NOT NULL,
+		next_run INTEGER,
+		last_run INTEGER,
+		last_error TEXT
+	)`,
+		`CREATE TABLE IF NOT EXISTS broadcasts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		author_id INTEGER NOT NULL,
+		rest_number INTEGER,
+		role TEXT,
+		kind TEXT NOT NULL,
+		text TEXT,
+		file_id TEXT,
+		status TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		finished_at TIMESTAMP
+	)`,
+		`CREATE TABLE IF NOT EXISTS broadcast_recipients (
+		broadcast_id INTEGER NOT NULL,
+		telegram_id INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		error TEXT,
+		sent_at TIMESTAMP,
+		PRIMARY KEY (broadcast_id, telegram_id)
+	)`,
+		`CREATE TABLE IF NOT EXISTS outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		chat_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		text TEXT,
+		file_id TEXT,
+		parse_mode TEXT,
+		reply_markup TEXT,
+		status TEXT NOT NULL,
+		attempts INTEGER DEFAULT 0,
+		next_attempt_at INTEGER DEFAULT 0,
+		last_error TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP
+	)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_due ON outbox (status, next_attempt_at)`,
+		`CREATE TABLE IF NOT EXISTS achievements (
+		telegram_id INTEGER NOT NULL,
+		code TEXT NOT NULL,
+		awarded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (telegram_id, code)
+	)`,
+		`CREATE TABLE IF NOT EXISTS kudos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_id INTEGER NOT NULL,
+		to_id INTEGER NOT NULL,
+		rest_number INTEGER,
+		message TEXT,
+		status TEXT NOT NULL,
+		stars INTEGER DEFAULT 0,
+		decided_by INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		decided_at TIMESTAMP
+	)`,
+	}},
+	// Колонки, которые раньше добавлялись ALTER при каждом старте; на базах,
+	// созданных до миграций, часть из них уже есть
+	{2, "дополнительные колонки", []string{
+		`ALTER TABLE orders ADD COLUMN decided_at TIMESTAMP`,
+		`ALTER TABLE restaurants ADD COLUMN manager_budget INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN order_remind_hours INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN order_escalate_hours INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN order_autocancel_hours INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN approval_remind_hours INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN approval_escalate_hours INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN kudos_weekly_allowance INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN kudos_star_rate INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN stars_expire_months INTEGER`,
+		`ALTER TABLE restaurants ADD COLUMN max_balance INTEGER`,
+		`ALTER TABLE orders ADD COLUMN sla_stage INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN submitted_at TIMESTAMP`,
+		`ALTER TABLE users ADD COLUMN sla_stage INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN blocked INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN leaderboard_hidden INTEGER DEFAULT 0`,
+		`ALTER TABLE users ADD COLUMN expiry_warned_until INTEGER`,
+		`ALTER TABLE users ADD COLUMN language TEXT`,
+	}},
+	// Пустая строка вместо NULL в числовых колонках — наследие старой регистрации;
+	// PostgreSQL такое значение не примет
+	{3, "NULL вместо пустых строк", []string{
+		`UPDATE users SET rest_number=NULL WHERE CAST(rest_number AS TEXT)=''`,
+		`UPDATE users SET tmp_field=NULL WHERE CAST(tmp_field AS TEXT)=''`,
+	}},
+	{4, "пауза между начислениями по предприятиям", []string{
+		`ALTER TABLE restaurants ADD COLUMN topup_cooldown_hours INTEGER`,
+	}},
+	{5, "ключи HTTP API", []string{
+		`CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		key_hash TEXT UNIQUE NOT NULL,
+		rest_number INTEGER NOT NULL DEFAULT 0,
+		owner_id INTEGER NOT NULL,
+		revoked INTEGER DEFAULT 0,
+		last_used INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	}},
+	{6, "сессии веб-панели", []string{
+		`CREATE TABLE IF NOT EXISTS web_sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		token_hash TEXT UNIQUE NOT NULL,
+		kind TEXT NOT NULL,
+		telegram_id INTEGER NOT NULL,
+		rest_number INTEGER NOT NULL DEFAULT 0,
+		expires_at INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	}},
+}
+
+var (
+	reSerial   = regexp.MustCompile(`INTEGER PRIMARY KEY AUTOINCREMENT`)
+	reInteger  = regexp.MustCompile(`\bINTEGER\b`)
+	reDatetime = regexp.MustCompile(`\bDATETIME\b`)
+	reAddCol   = regexp.MustCompile(`\bADD COLUMN\b`)
+)
+
+// ddl переводит запрос миграции на диалект драйвера.
+func ddl(driver, stmt string) string {
+	if driver != DriverPostgres {
+		return stmt
+	}
+	stmt = reSerial.ReplaceAllString(stmt, "BIGSERIAL PRIMARY KEY")
+	// Telegram ID не помещаются в 32 бита
+	stmt = reInteger.ReplaceAllString(stmt, "BIGINT")
+	stmt = reDatetime.ReplaceAllString(stmt, "TIMESTAMP")
+	return reAddCol.ReplaceAllString(stmt, "ADD COLUMN IF NOT EXISTS")
+}
+
+// Migrate доводит схему базы до последней версии.
+func Migrate(db *sql.DB, driver string) error {
+	driver, err := NormalizeDriver(driver)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ddl(driver, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`))
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(db, driver, m); err != nil {
+			return fmt.Errorf("миграция %d (%s): %w", m.Version, m.Name, err)
+		}
+		slog.Info("Применена миграция", "version", m.Version, "migration", m.Name)
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, driver string, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range m.Stmts {
+		if _, err := tx.Exec(ddl(driver, stmt)); err != nil {
+			// В SQLite нет ADD COLUMN IF NOT EXISTS: уже существующая колонка — не ошибка
+			if driver == DriverSQLite && strings.Contains(err.Error(), "duplicate column") {
+				continue
+			}
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// SchemaVersion — последняя применённая версия схемы.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var v int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v)
+	return v, err
+}
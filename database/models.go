@@ -0,0 +1,45 @@
+package database
+
+import "time"
+
+// Статусы заказов (orders.status)
+const (
+	OrderOpen     = "в сборке"
+	OrderAccepted = "accept"
+	OrderDenied   = "deny"
+)
+
+// User — пользователь бота (строка users).
+type User struct {
+	TelegramID  int64
+	Username    string
+	Name        string
+	TableNumber string
+	RestNumber  int
+	AccessLevel string
+	Verified    bool
+	Balance     int
+	Blocked     bool
+}
+
+// Product — товар магазина предприятия (строка shop).
+type Product struct {
+	ID         int
+	RestNumber int
+	Name       string
+	Price      int
+	Remains    int
+}
+
+// Order — заказ товара вместе с именем и номером покупателя.
+type Order struct {
+	ID         int
+	TelegramID int64
+	BuyerName  string
+	BuyerTable string
+	Product    string
+	Price      int
+	Status     string
+	RestNumber int
+	CreatedAt  time.Time
+}
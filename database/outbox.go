@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// Статусы сообщений исходящей очереди
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead" // исчерпаны попытки или пользователь заблокировал бота
+)
+
+// OutboxMessage — сообщение в исходящей очереди. ReplyMarkup — JSON инлайн-клавиатуры.
+type OutboxMessage struct {
+	ID          int64
+	ChatID      int64
+	Kind        string // text, photo, document — как у рассылок
+	Text        string
+	FileID      string
+	ParseMode   string
+	ReplyMarkup string
+	Attempts    int
+}
+
+// OutboxStats — размер очереди по статусам.
+type OutboxStats struct {
+	Pending int
+	Sent    int
+	Dead    int
+}
+
+func EnqueueOutbox(db *sql.DB, m OutboxMessage) (int64, error) {
+	var id int64
+	err := db.QueryRow(`INSERT INTO outbox (chat_id, kind, text, file_id, parse_mode, reply_markup, status, next_attempt_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`, m.ChatID, m.Kind, m.Text, m.FileID, m.ParseMode, m.ReplyMarkup,
+		OutboxPending, time.Now().Unix()).Scan(&id)
+	return id, err
+}
+
+// DueOutbox — сообщения, которые пора отправить, в порядке постановки в очередь.
+// Пока более раннее сообщение чата ждёт повтора, следующие в этот чат не выбираются:
+// порядок сообщений в чате сохраняется.
+func DueOutbox(db *sql.DB, now time.Time, limit int) ([]OutboxMessage, error) {
+	rows, err := db.Query(`SELECT id, chat_id, kind, COALESCE(text, ''), COALESCE(file_id, ''), COALESCE(parse_mode, ''),
+       COALESCE(reply_markup, ''), attempts
+FROM outbox o WHERE status=? AND next_attempt_at <= ?
+  AND NOT EXISTS (SELECT 1 FROM outbox e WHERE e.chat_id = o.chat_id AND e.status=? AND e.id < o.id AND e.next_attempt_at > ?)
+ORDER BY id LIMIT ?`, OutboxPending, now.Unix(), OutboxPending, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		if err := rows.Scan(&m.ID, &m.ChatID, &m.Kind, &m.Text, &m.FileID, &m.ParseMode, &m.ReplyMarkup, &m.Attempts); err != nil {
+			slog.Error("Ошибка скана в DueOutbox", "err", err)
+			continue
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+func MarkOutboxSent(db *sql.DB, id int64) error {
+	_, err := db.Exec(`UPDATE outbox SET status=?, sent_at=CURRENT_TIMESTAMP, last_error=NULL WHERE id=?`, OutboxSent, id)
+	return err
+}
+
+// RetryOutbox откладывает сообщение до next; attempts — уже сделанные неудачные попытки.
+func RetryOutbox(db *sql.DB, id int64, attempts int, next time.Time, errText string) error {
+	_, err := db.Exec(`UPDATE outbox SET attempts=?, next_attempt_at=?, last_error=? WHERE id=?`,
+		attempts, next.Unix(), errText, id)
+	return err
+}
+
+func MarkOutboxDead(db *sql.DB, id int64, attempts int, errText string) error {
+	_, err := db.Exec(`UPDATE outbox SET status=?, attempts=?, last_error=? WHERE id=?`, OutboxDead, attempts, errText, id)
+	return err
+}
+
+// RequeueDeadOutbox возвращает недоставленные сообщения в очередь, кроме адресатов,
+// заблокировавших бота.
+func RequeueDeadOutbox(db *sql.DB) (int, error) {
+	res, err := db.Exec(`UPDATE outbox SET status=?, attempts=0, next_attempt_at=?
+WHERE status=? AND chat_id NOT IN (SELECT telegram_id FROM users WHERE blocked=1)`,
+		OutboxPending, time.Now().Unix(), OutboxDead)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func GetOutboxStats(db *sql.DB) (OutboxStats, error) {
+	var s OutboxStats
+	err := db.QueryRow(`SELECT
+       COALESCE(SUM(CASE WHEN status=? THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN status=? THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(CASE WHEN status=? THEN 1 ELSE 0 END), 0)
+FROM outbox`, OutboxPending, OutboxSent, OutboxDead).Scan(&s.Pending, &s.Sent, &s.Dead)
+	return s, err
+}
+
+// PurgeSentOutbox удаляет доставленные сообщения старше days дней.
+func PurgeSentOutbox(db *sql.DB, days int) error {
+	_, err := db.Exec(`DELETE FROM outbox WHERE status=? AND sent_at < ?`, OutboxSent, since(days))
+	return err
+}
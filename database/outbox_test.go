@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+// Пока раннее сообщение чата ждёт повтора, следующие в тот же чат не выбираются.
+func TestDueOutboxChatOrder(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	var ids []int64
+	for _, chatID := range []int64{1, 1, 2} {
+		id, err := EnqueueOutbox(db, OutboxMessage{ChatID: chatID, Kind: BroadcastText, Text: "привет"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	now := time.Now()
+	if err := RetryOutbox(db, ids[0], 1, now.Add(time.Minute), "сбой"); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := DueOutbox(db, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].ID != ids[2] {
+		t.Fatalf("к отправке %+v, want только сообщение %d в чат 2", due, ids[2])
+	}
+
+	// Когда срок повтора наступил, чат отправляется по порядку
+	due, err = DueOutbox(db, now.Add(2*time.Minute), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 3 || due[0].ID != ids[0] || due[1].ID != ids[1] {
+		t.Fatalf("к отправке %+v, want все три по порядку", due)
+	}
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`SELECT 1`, `SELECT 1`},
+		{`SELECT * FROM users WHERE telegram_id=?`, `SELECT * FROM users WHERE telegram_id=$1`},
+		{`UPDATE shop SET product=?, price=? WHERE id=?`, `UPDATE shop SET product=$1, price=$2 WHERE id=$3`},
+		{`SELECT '?' FROM t WHERE a=?`, `SELECT '?' FROM t WHERE a=$1`},
+		{`SELECT "col?" FROM t WHERE a=? AND b='it''s ?'`, `SELECT "col?" FROM t WHERE a=$1 AND b='it''s ?'`},
+		{`INSERT INTO t (a, b) VALUES (?, ?) RETURNING id`, `INSERT INTO t (a, b) VALUES ($1, $2) RETURNING id`},
+	}
+	for _, tt := range tests {
+		if got := rebind(tt.in); got != tt.want {
+			t.Errorf("rebind(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPgArgs(t *testing.T) {
+	now := time.Now()
+	args := []driver.NamedValue{
+		{Ordinal: 1, Value: true},
+		{Ordinal: 2, Value: false},
+		{Ordinal: 3, Value: int64(7)},
+		{Ordinal: 4, Value: "true"},
+		{Ordinal: 5, Value: now},
+		{Ordinal: 6, Value: nil},
+	}
+	want := []driver.Value{int64(1), int64(0), int64(7), "true", now, nil}
+	got := pgArgs(args)
+	for i := range want {
+		if got[i].Value != want[i] {
+			t.Errorf("аргумент %d = %#v, want %#v", i+1, got[i].Value, want[i])
+		}
+	}
+}
+
+func TestDDL(t *testing.T) {
+	tests := []struct {
+		driver, in, want string
+	}{
+		{DriverSQLite, `id INTEGER PRIMARY KEY AUTOINCREMENT, ts DATETIME`, `id INTEGER PRIMARY KEY AUTOINCREMENT, ts DATETIME`},
+		{DriverPostgres, `id INTEGER PRIMARY KEY AUTOINCREMENT`, `id BIGSERIAL PRIMARY KEY`},
+		{DriverPostgres, `telegram_id INTEGER UNIQUE NOT NULL`, `telegram_id BIGINT UNIQUE NOT NULL`},
+		{DriverPostgres, `registration_start_time DATETIME`, `registration_start_time TIMESTAMP`},
+		{DriverPostgres, `created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP`, `created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP`},
+		{DriverPostgres, `ALTER TABLE users ADD COLUMN lang TEXT`, `ALTER TABLE users ADD COLUMN IF NOT EXISTS lang TEXT`},
+		// Слова, в которые INTEGER входит частью, не трогаются
+		{DriverPostgres, `integer_note TEXT, MYINTEGER TEXT`, `integer_note TEXT, MYINTEGER TEXT`},
+	}
+	for _, tt := range tests {
+		if got := ddl(tt.driver, tt.in); got != tt.want {
+			t.Errorf("ddl(%s, %q) = %q, want %q", tt.driver, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	if got := sqliteDSN("bot.db"); got != "bot.db?_pragma=busy_timeout(10000)&_pragma=synchronous(FULL)" {
+		t.Errorf("sqliteDSN = %q", got)
+	}
+	if got := sqliteDSN("file:bot.db?mode=rwc"); !strings.HasPrefix(got, "file:bot.db?mode=rwc&_pragma=") {
+		t.Errorf("sqliteDSN = %q", got)
+	}
+}
+
+// openTestDB открывает пустую базу с актуальной схемой: SQLite — во временном
+// каталоге, PostgreSQL — в отдельной схеме базы TEST_PG_DSN, которая удаляется
+// после теста. Без TEST_PG_DSN тест PostgreSQL пропускается.
+func openTestDB(t *testing.T, driver string) *sql.DB {
+	t.Helper()
+	var db *sql.DB
+	var err error
+	if driver == DriverPostgres {
+		dsn := os.Getenv("TEST_PG_DSN")
+		if dsn == "" {
+			t.Skip("TEST_PG_DSN не задан")
+		}
+		if db, err = Open(driver, dsn); err != nil {
+			t.Fatal(err)
+		}
+		// Одно соединение — чтобы search_path действовал на все запросы теста
+		db.SetMaxOpenConns(1)
+		schema := fmt.Sprintf("tbvit_test_%d", time.Now().UnixNano())
+		if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		})
+		if _, err := db.Exec(`SET search_path TO ` + schema); err != nil {
+			t.Fatal(err)
+		}
+	} else if db, err = Open(driver, filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := Migrate(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrateAndCRUD(t *testing.T) {
+	for _, driver := range []string{DriverSQLite, DriverPostgres} {
+		t.Run(driver, func(t *testing.T) {
+			db := openTestDB(t, driver)
+
+			v, err := SchemaVersion(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := migrations[len(migrations)-1].Version; v != want {
+				t.Fatalf("версия схемы %d, want %d", v, want)
+			}
+			// Повторный запуск ничего не применяет
+			if err := Migrate(db, driver); err != nil {
+				t.Fatal(err)
+			}
+
+			// INSERT … RETURNING id
+			id1, err := AddProduct(db, 5, "Чай", 10, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id2, err := AddProduct(db, 5, "Кофе", 20, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id1 <= 0 || id2 <= id1 {
+				t.Fatalf("id товаров: %d, %d", id1, id2)
+			}
+
+			p, err := GetProduct(db, id1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Price, p.Remains = 15, 2
+			if err := UpdateProduct(db, p); err != nil {
+				t.Fatal(err)
+			}
+			if p, err = GetProduct(db, id1); err != nil || p.Price != 15 || p.Remains != 2 {
+				t.Fatalf("после правки: %+v, %v", p, err)
+			}
+
+			// bool в параметре (inStockOnly) — 0/1 для PostgreSQL
+			all, err := ListProducts(db, 5, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inStock, err := ListProducts(db, 5, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 2 || len(inStock) != 1 {
+				t.Fatalf("товаров %d, в наличии %d; want 2 и 1", len(all), len(inStock))
+			}
+
+			if err := DeleteProduct(db, id2); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := GetProduct(db, id2); err != sql.ErrNoRows {
+				t.Fatalf("удалённый товар: %v", err)
+			}
+
+			// Telegram ID больше 32 бит
+			const userID = int64(7_000_000_001)
+			if err := UpdateRest(db, userID, "5"); err != nil {
+				t.Fatal(err)
+			}
+			users, err := ListRestUsers(db, 5, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 1 || users[0].TelegramID != userID {
+				t.Fatalf("сотрудники: %+v", users)
+			}
+		})
+	}
+}
+
+// Каждое соединение пула SQLite должно ждать снятия блокировки, а не только первое.
+func TestSQLiteBusyTimeoutPerConn(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	ctx := t.Context()
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var timeout int
+		if err := conn.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&timeout); err != nil {
+			t.Fatal(err)
+		}
+		if timeout != 10000 {
+			t.Errorf("соединение %d: busy_timeout = %d, want 10000", i+1, timeout)
+		}
+	}
+}
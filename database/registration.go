@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"tbViT/i18n"
+)
+
+// ErrRegistrationDecided — заявку уже рассмотрел другой админ, или кнопка устарела.
+var ErrRegistrationDecided = i18n.NewError("reg.already_decided")
+
+// pendingRegistration — условие «заявка подана, не подтверждена и относится к предприятию админа».
+const pendingRegistration = `telegram_id=? AND COALESCE(verified, 0)=0 AND submitted_at IS NOT NULL AND rest_number=?`
+
+// ApproveRegistration подтверждает заявку userID с ролью worker или manager.
+// Заявки приходят всем админам предприятия, поэтому решение — условный UPDATE:
+// повторное нажатие, кнопка другого админа или устаревшее напоминание получают
+// ErrRegistrationDecided и не трогают ни баланс, ни роль уже работающего сотрудника.
+func ApproveRegistration(db *sql.DB, adminID, userID int64, role string) error {
+	if role != "worker" && role != "manager" {
+		return i18n.NewError("err.invalid_access_level", role)
+	}
+	restNumber, err := GetUserRestID(db, adminID)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`UPDATE users SET access_level=?, verified=1, current_balance=0, last_ts=0, submitted_at=NULL
+WHERE `+pendingRegistration, role, userID, restNumber)
+	return decided(res, err)
+}
+
+// RejectRegistration отклоняет заявку userID на тех же условиях, что и ApproveRegistration.
+func RejectRegistration(db *sql.DB, adminID, userID int64) error {
+	restNumber, err := GetUserRestID(db, adminID)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`UPDATE users SET verified=0, access_level='', submitted_at=NULL WHERE `+pendingRegistration,
+		userID, restNumber)
+	return decided(res, err)
+}
+
+func decided(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRegistrationDecided
+	}
+	return nil
+}
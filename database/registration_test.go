@@ -0,0 +1,55 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApproveRegistration(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	_, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, access_level, verified, current_balance) VALUES
+(1, 5, 'admin', 1, 0), (2, 5, 'admin', 1, 0), (9, 6, 'admin', 1, 0),
+(10, 5, '', 0, 0), (11, 5, 'worker', 1, 40), (12, 5, '', 0, 0)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE users SET submitted_at=CURRENT_TIMESTAMP WHERE telegram_id IN (10, 12)`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Админ другого предприятия не решает чужие заявки
+	if err := ApproveRegistration(db, 9, 10, "worker"); !errors.Is(err, ErrRegistrationDecided) {
+		t.Errorf("чужое предприятие: %v", err)
+	}
+	if err := ApproveRegistration(db, 1, 10, "admin"); err == nil {
+		t.Error("кнопкой заявки нельзя назначить админа")
+	}
+	if err := ApproveRegistration(db, 1, 10, "manager"); err != nil {
+		t.Fatal(err)
+	}
+	// Второй админ нажимает ту же кнопку — роль и баланс не меняются
+	if err := ApproveRegistration(db, 2, 10, "worker"); !errors.Is(err, ErrRegistrationDecided) {
+		t.Errorf("повторное решение: %v", err)
+	}
+	if u, _ := GetUser(db, 10); u.AccessLevel != "manager" || !u.Verified {
+		t.Errorf("после одобрения: %+v", u)
+	}
+
+	// Устаревшая кнопка по работающему сотруднику не обнуляет баланс и не меняет роль
+	if err := ApproveRegistration(db, 1, 11, "manager"); !errors.Is(err, ErrRegistrationDecided) {
+		t.Errorf("подтверждённый сотрудник: %v", err)
+	}
+	if err := RejectRegistration(db, 1, 11); !errors.Is(err, ErrRegistrationDecided) {
+		t.Errorf("отклонение подтверждённого: %v", err)
+	}
+	if u, _ := GetUser(db, 11); u.AccessLevel != "worker" || u.Balance != 40 || !u.Verified {
+		t.Errorf("сотрудник изменён: %+v", u)
+	}
+
+	if err := RejectRegistration(db, 2, 12); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApproveRegistration(db, 1, 12, "worker"); !errors.Is(err, ErrRegistrationDecided) {
+		t.Errorf("одобрение отклонённой заявки: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package database
+
+import "database/sql"
+
+// UserRepository — чтение пользователей без привязки к Telegram.
+type UserRepository interface {
+	GetUser(telegramID int64) (User, error)
+	ListRestUsers(restNumber int, verifiedOnly bool) ([]User, error)
+	ListWorkersPage(restNumber, limit, offset int) ([]User, int, error)
+}
+
+// ProductRepository — чтение товаров магазина.
+type ProductRepository interface {
+	GetProduct(id int) (Product, error)
+	ListProducts(restNumber int, inStockOnly bool) ([]Product, error)
+}
+
+// OrderRepository — чтение заказов.
+type OrderRepository interface {
+	GetOrder(id int) (Order, error)
+	ListOpenOrders(restNumber int) ([]Order, error)
+	ListUserOrders(telegramID int64, limit, offset int) ([]Order, int, error)
+}
+
+// TransactionRepository — чтение истории баланса.
+type TransactionRepository interface {
+	ListTransactions(telegramID int64, f TxFilter, limit, offset int) ([]Transaction, int, error)
+}
+
+// Store реализует репозитории поверх *sql.DB через функции пакета.
+type Store struct {
+	db *sql.DB
+}
+
+var (
+	_ UserRepository        = (*Store)(nil)
+	_ ProductRepository     = (*Store)(nil)
+	_ OrderRepository       = (*Store)(nil)
+	_ TransactionRepository = (*Store)(nil)
+)
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetUser(telegramID int64) (User, error) {
+	return GetUser(s.db, telegramID)
+}
+
+func (s *Store) ListRestUsers(restNumber int, verifiedOnly bool) ([]User, error) {
+	return ListRestUsers(s.db, restNumber, verifiedOnly)
+}
+
+func (s *Store) ListWorkersPage(restNumber, limit, offset int) ([]User, int, error) {
+	return ListWorkersPage(s.db, restNumber, limit, offset)
+}
+
+func (s *Store) GetProduct(id int) (Product, error) {
+	return GetProduct(s.db, id)
+}
+
+func (s *Store) ListProducts(restNumber int, inStockOnly bool) ([]Product, error) {
+	return ListProducts(s.db, restNumber, inStockOnly)
+}
+
+func (s *Store) GetOrder(id int) (Order, error) {
+	return GetOrder(s.db, id)
+}
+
+func (s *Store) ListOpenOrders(restNumber int) ([]Order, error) {
+	return ListOpenOrders(s.db, restNumber)
+}
+
+func (s *Store) ListUserOrders(telegramID int64, limit, offset int) ([]Order, int, error) {
+	return ListUserOrders(s.db, telegramID, limit, offset)
+}
+
+func (s *Store) ListTransactions(telegramID int64, f TxFilter, limit, offset int) ([]Transaction, int, error) {
+	return ListTransactions(s.db, telegramID, f, limit, offset)
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/i18n"
+)
+
+type RestaurantSummary struct {
+	RestNumber int
+	Staff      int
+	Admins     int
+	Balance    int
+	Frozen     bool
+}
+
+// ValidAccessLevels — допустимые значения users.access_level
+var ValidAccessLevels = []string{"worker", "manager", "admin"}
+
+func IsValidAccessLevel(level string) bool {
+	for _, l := range ValidAccessLevels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// ListRestaurants возвращает все известные предприятия со сводкой по персоналу и балансам.
+func ListRestaurants(db *sql.DB) ([]RestaurantSummary, error) {
+	rows, err := db.Query(`
+SELECT r.rest_number,
+       COALESCE(s.staff, 0), COALESCE(s.admins, 0), COALESCE(s.balance, 0),
+       COALESCE((SELECT frozen FROM restaurants WHERE rest_number = r.rest_number), 0)
+FROM (SELECT rest_number FROM users WHERE rest_number IS NOT NULL AND CAST(rest_number AS TEXT) != ''
+      UNION SELECT rest_number FROM restaurants) r
+LEFT JOIN (SELECT rest_number,
+                  COUNT(*) AS staff,
+                  SUM(CASE WHEN access_level = 'admin' THEN 1 ELSE 0 END) AS admins,
+                  SUM(COALESCE(current_balance, 0)) AS balance
+           FROM users WHERE verified = 1 GROUP BY rest_number) s ON s.rest_number = r.rest_number
+ORDER BY r.rest_number`)
+	if err != nil {
+		slog.Error("Ошибка загрузки списка предприятий", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []RestaurantSummary
+	for rows.Next() {
+		var r RestaurantSummary
+		if err := rows.Scan(&r.RestNumber, &r.Staff, &r.Admins, &r.Balance, &r.Frozen); err != nil {
+			slog.Error("Ошибка скана в ListRestaurants", "err", err)
+			continue
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func GetRestaurantSummary(db *sql.DB, restNumber int) (RestaurantSummary, error) {
+	r := RestaurantSummary{RestNumber: restNumber}
+	err := db.QueryRow(`SELECT COUNT(*),
+       COALESCE(SUM(CASE WHEN access_level = 'admin' THEN 1 ELSE 0 END), 0),
+       COALESCE(SUM(COALESCE(current_balance, 0)), 0)
+FROM users WHERE rest_number=? AND verified=1`, restNumber).Scan(&r.Staff, &r.Admins, &r.Balance)
+	if err != nil {
+		return r, err
+	}
+	r.Frozen, err = IsRestFrozen(db, restNumber)
+	return r, err
+}
+
+func IsRestFrozen(db *sql.DB, restNumber int) (bool, error) {
+	var frozen bool
+	err := db.QueryRow(`SELECT frozen FROM restaurants WHERE rest_number=?`, restNumber).Scan(&frozen)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return frozen, err
+}
+
+// IsUserRestFrozen проверяет, заморожено ли предприятие пользователя.
+func IsUserRestFrozen(db *sql.DB, userID int64) bool {
+	restNumber, err := GetUserRestID(db, userID)
+	if err != nil {
+		return false
+	}
+	frozen, err := IsRestFrozen(db, restNumber)
+	if err != nil {
+		slog.Error("Ошибка проверки заморозки предприятия", "rest", restNumber, "err", err)
+	}
+	return frozen
+}
+
+func SetRestFrozen(db *sql.DB, restNumber int, frozen bool) error {
+	if restNumber <= 0 {
+		return i18n.NewError("err.invalid_rest")
+	}
+	_, err := db.Exec(`INSERT INTO restaurants (rest_number, frozen) VALUES (?, ?)
+ON CONFLICT(rest_number) DO UPDATE SET frozen=excluded.frozen`, restNumber, frozen)
+	if err == nil {
+		slog.Info("Заморозка предприятия изменена", "rest", restNumber, "frozen", frozen)
+	}
+	return err
+}
+
+func usersFromRows(rows *sql.Rows) ([]User, error) {
+	defer rows.Close()
+	var list []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.TelegramID, &u.Username, &u.Name, &u.TableNumber, &u.RestNumber,
+			&u.AccessLevel, &u.Verified, &u.Balance, &u.Blocked); err != nil {
+			slog.Error("Ошибка скана пользователя", "err", err)
+			continue
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+const userColumns = `telegram_id, COALESCE(username, ''), COALESCE(name, ''), COALESCE(table_number, ''),
+CAST(COALESCE(rest_number, 0) AS INTEGER), COALESCE(access_level, ''), COALESCE(verified, 0), COALESCE(current_balance, 0),
+COALESCE(blocked, 0)`
+
+// ListRestUsers возвращает сотрудников предприятия по номеру расписания;
+// verifiedOnly — без заявок, ещё не подтверждённых админом.
+func ListRestUsers(db *sql.DB, restNumber int, verifiedOnly bool) ([]User, error) {
+	rows, err := db.Query(`SELECT `+userColumns+` FROM users
+WHERE rest_number=? AND (verified=1 OR ?=0) ORDER BY LENGTH(table_number), table_number ASC`, restNumber, verifiedOnly)
+	if err != nil {
+		slog.Error("Ошибка загрузки списка сотрудников", "err", err)
+		return nil, err
+	}
+	return usersFromRows(rows)
+}
+
+// ListWorkersPage возвращает страницу подтверждённых работников предприятия и их общее число.
+func ListWorkersPage(db *sql.DB, restNumber, limit, offset int) ([]User, int, error) {
+	var total int
+	err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE rest_number=? AND access_level='worker' AND verified=1`,
+		restNumber).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.Query(`SELECT `+userColumns+` FROM users
+WHERE rest_number=? AND access_level='worker' AND verified=1
+ORDER BY LENGTH(table_number), table_number ASC LIMIT ? OFFSET ?`, restNumber, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	list, err := usersFromRows(rows)
+	return list, total, err
+}
+
+// SearchUsers ищет пользователей по всей сети: по Telegram ID, имени или username.
+func SearchUsers(db *sql.DB, query string, limit int) ([]User, error) {
+	query = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(query), "@"))
+	if query == "" {
+		return nil, i18n.NewError("err.empty_query")
+	}
+	if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+		rows, err := db.Query(`SELECT `+userColumns+` FROM users WHERE telegram_id=?`, id)
+		if err != nil {
+			return nil, err
+		}
+		return usersFromRows(rows)
+	}
+	// lower() в SQLite не работает с кириллицей, поэтому фильтруем на стороне Go
+	rows, err := db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY rest_number, name`)
+	if err != nil {
+		return nil, err
+	}
+	all, err := usersFromRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	needle := strings.ToLower(query)
+	var found []User
+	for _, u := range all {
+		if strings.Contains(strings.ToLower(u.Name), needle) || strings.Contains(strings.ToLower(u.Username), needle) {
+			found = append(found, u)
+			if len(found) == limit {
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+// GetUser возвращает пользователя по Telegram ID или sql.ErrNoRows.
+func GetUser(db *sql.DB, telegramID int64) (User, error) {
+	rows, err := db.Query(`SELECT `+userColumns+` FROM users WHERE telegram_id=?`, telegramID)
+	if err != nil {
+		return User{}, err
+	}
+	list, err := usersFromRows(rows)
+	if err != nil {
+		return User{}, err
+	}
+	if len(list) == 0 {
+		return User{}, sql.ErrNoRows
+	}
+	return list[0], nil
+}
+
+// AssignAdmin назначает подтверждённого пользователя администратором его предприятия.
+func AssignAdmin(db *sql.DB, telegramID int64) error {
+	u, err := GetUser(db, telegramID)
+	if err == sql.ErrNoRows {
+		return i18n.NewError("err.user_not_found")
+	}
+	if err != nil {
+		return err
+	}
+	if !u.Verified || u.RestNumber == 0 {
+		return i18n.NewError("err.user_not_registered")
+	}
+	_, err = db.Exec(`UPDATE users SET access_level='admin' WHERE telegram_id=?`, telegramID)
+	if err == nil {
+		slog.Info("Назначен админ предприятия", "user_id", telegramID, "rest", u.RestNumber)
+	}
+	return err
+}
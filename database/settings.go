@@ -0,0 +1,151 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"tbViT/i18n"
+)
+
+// ManagerWeeklyBudget — бюджет менеджера по умолчанию: сколько 🌟 он может начислить
+// за 7 дней. 0 — без ограничения. Переопределяется конфигурацией и настройками предприятия.
+var ManagerWeeklyBudget = 0
+
+// TopUpCooldownHours — сколько часов после начисления работнику нельзя начислить снова.
+var TopUpCooldownHours = 12
+
+// RestOverrides — значения настроек по предприятиям из конфигурации:
+// номер предприятия → ключ настройки → значение. Заданное админом в меню важнее.
+var RestOverrides map[int]map[string]int
+
+// RestSetting — настройка предприятия, хранится в колонке таблицы restaurants.
+// NULL в колонке означает значение по умолчанию.
+// Название и единица измерения берутся из каталога по ключам setting.<Key>.title и setting.<Key>.unit.
+type RestSetting struct {
+	Key     string
+	Default func() int
+}
+
+// Title — название настройки на языке lang.
+func (s RestSetting) Title(lang string) string {
+	return i18n.T(lang, "setting."+s.Key+".title")
+}
+
+// Unit — единица измерения и пояснение к значению на языке lang.
+func (s RestSetting) Unit(lang string) string {
+	return i18n.T(lang, "setting."+s.Key+".unit")
+}
+
+// RestSettings — настройки, доступные админу в меню «⚙️ Настройки».
+var RestSettings = []RestSetting{
+	{Key: "topup_cooldown_hours", Default: func() int { return TopUpCooldownHours }},
+	{Key: "manager_budget", Default: func() int { return ManagerWeeklyBudget }},
+	{Key: "order_remind_hours", Default: func() int { return 24 }},
+	{Key: "order_escalate_hours", Default: func() int { return 48 }},
+	{Key: "order_autocancel_hours", Default: func() int { return 0 }},
+	{Key: "approval_remind_hours", Default: func() int { return 24 }},
+	{Key: "approval_escalate_hours", Default: func() int { return 72 }},
+	{Key: "kudos_weekly_allowance", Default: func() int { return KudosWeeklyAllowance }},
+	{Key: "kudos_star_rate", Default: func() int { return 0 }},
+	{Key: "stars_expire_months", Default: func() int { return 0 }},
+	{Key: "max_balance", Default: func() int { return 0 }},
+}
+
+func findRestSetting(key string) (RestSetting, bool) {
+	for _, s := range RestSettings {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return RestSetting{}, false
+}
+
+// IsRestSetting — есть ли настройка предприятия с таким ключом.
+func IsRestSetting(key string) bool {
+	_, ok := findRestSetting(key)
+	return ok
+}
+
+// GetRestSetting возвращает значение настройки предприятия с учётом значения по умолчанию.
+func GetRestSetting(q queryer, restNumber int, key string) int {
+	value, _ := LookupRestSetting(q, restNumber, key)
+	return value
+}
+
+// LookupRestSetting возвращает значение настройки; custom == true, если его задал админ.
+// Иначе действует значение из конфигурации предприятия или общее по умолчанию.
+func LookupRestSetting(q queryer, restNumber int, key string) (value int, custom bool) {
+	s, ok := findRestSetting(key)
+	if !ok {
+		return 0, false
+	}
+	var stored sql.NullInt64
+	// key берётся только из RestSettings, поэтому подстановка имени колонки безопасна
+	err := q.QueryRow(`SELECT `+s.Key+` FROM restaurants WHERE rest_number=?`, restNumber).Scan(&stored)
+	if err != nil && err != sql.ErrNoRows {
+		slog.Error("Ошибка чтения настройки", "setting", key, "rest", restNumber, "err", err)
+	}
+	if stored.Valid {
+		return int(stored.Int64), true
+	}
+	if v, ok := RestOverrides[restNumber][s.Key]; ok {
+		return v, false
+	}
+	return s.Default(), false
+}
+
+// SetRestSetting сохраняет настройку предприятия.
+func SetRestSetting(db *sql.DB, restNumber int, key string, value int) error {
+	s, ok := findRestSetting(key)
+	if !ok {
+		return i18n.NewError("err.setting_unknown", key)
+	}
+	if restNumber <= 0 {
+		return i18n.NewError("err.invalid_rest")
+	}
+	if value < 0 {
+		return i18n.NewError("err.value_negative")
+	}
+	_, err := db.Exec(`INSERT INTO restaurants (rest_number, `+s.Key+`) VALUES (?, ?)
+ON CONFLICT(rest_number) DO UPDATE SET `+s.Key+`=excluded.`+s.Key, restNumber, value)
+	return err
+}
+
+// ResetRestSetting возвращает настройке предприятия значение по умолчанию.
+func ResetRestSetting(db *sql.DB, restNumber int, key string) error {
+	s, ok := findRestSetting(key)
+	if !ok {
+		return i18n.NewError("err.setting_unknown", key)
+	}
+	_, err := db.Exec(`UPDATE restaurants SET `+s.Key+`=NULL WHERE rest_number=?`, restNumber)
+	return err
+}
+
+// IssuedByActor — сколько 🌟 actorID начислил за последние days дней.
+func IssuedByActor(q queryer, actorID int64, days int) (int, error) {
+	var issued int
+	err := q.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM transactions
+WHERE actor_id=? AND type=? AND created_at >= ?`,
+		actorID, TxTopUp, since(days)).Scan(&issued)
+	return issued, err
+}
+
+// ManagerBudgetLeft — остаток недельного бюджета менеджера. limited == false — лимит не задан.
+func ManagerBudgetLeft(q queryer, managerID int64) (left, budget int, limited bool, err error) {
+	restNumber, err := GetUserRestID(q, managerID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	budget = GetRestSetting(q, restNumber, "manager_budget")
+	if budget == 0 {
+		return 0, 0, false, nil
+	}
+	issued, err := IssuedByActor(q, managerID, 7)
+	if err != nil {
+		return 0, budget, true, err
+	}
+	left = budget - issued
+	if left < 0 {
+		left = 0
+	}
+	return left, budget, true, nil
+}
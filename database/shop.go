@@ -1,97 +1,268 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
-	"time"
+	"tbViT/i18n"
 )
 
-func SendHistoryOrders(db *sql.DB, fromID int64) (string, error) {
-	rows, err := db.Query(`SELECT product_name, status, price, created_at
-FROM orders WHERE telegram_id=? ORDER BY created_at DESC`, fromID)
-	if err != nil {
-		log.Printf("Ошибка загрузки списка заказов: %v", err)
-		return "", err
-	}
-	defer rows.Close()
+const orderColumns = `o.id, o.telegram_id, COALESCE(u.name, ''), COALESCE(u.table_number, ''), o.product_name,
+COALESCE(o.price, 0), o.status, COALESCE(o.rest_number, 0), o.created_at`
 
-	var list strings.Builder
+func ordersFromRows(rows *sql.Rows) ([]Order, error) {
+	defer rows.Close()
+	var list []Order
 	for rows.Next() {
-		var product, status, price string
-		var createdAt time.Time
-		if err := rows.Scan(&product, &status, &price, &createdAt); err != nil {
-			log.Printf("Ошибка скана в SendHistoryOrders: %v", err)
+		var o Order
+		if err := rows.Scan(&o.ID, &o.TelegramID, &o.BuyerName, &o.BuyerTable, &o.Product,
+			&o.Price, &o.Status, &o.RestNumber, &o.CreatedAt); err != nil {
+			slog.Error("Ошибка скана заказа", "err", err)
 			continue
 		}
-		var msg string
-		dateOnly := createdAt.Format("2006-01-02")
-		switch status {
-		case "deny":
-			msg = "Отменен ❌"
-		case "в сборке":
-			msg = "В сборке 🚚"
-		case "accept":
-			msg = "Выполнен ✅"
-		}
-		list.WriteString(fmt.Sprintf("%s | %s | %s | %s🌟\n", dateOnly, product, msg, price))
+		list = append(list, o)
 	}
+	return list, rows.Err()
+}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Ошибка при итерации по строкам: %v", err)
-		return "", err
+// ListUserOrders возвращает страницу заказов пользователя (новые первыми) и общее число заказов.
+func ListUserOrders(db *sql.DB, telegramID int64, limit, offset int) ([]Order, int, error) {
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM orders WHERE telegram_id=?`, telegramID).Scan(&total); err != nil {
+		slog.Error("Ошибка подсчёта заказов", "err", err)
+		return nil, 0, err
 	}
-	return list.String(), nil
+	rows, err := db.Query(`SELECT `+orderColumns+`
+FROM orders o LEFT JOIN users u ON u.telegram_id = o.telegram_id
+WHERE o.telegram_id=? ORDER BY o.created_at DESC, o.id DESC LIMIT ? OFFSET ?`, telegramID, limit, offset)
+	if err != nil {
+		slog.Error("Ошибка загрузки списка заказов", "err", err)
+		return nil, 0, err
+	}
+	list, err := ordersFromRows(rows)
+	return list, total, err
 }
 
-func CompleteOrder(db *sql.DB, id int, decision string) (int64, string) {
-	var buyerID int64
-	var price int
-	var product, status string
-	err := db.QueryRow(`SELECT telegram_id, price, product_name, status FROM orders WHERE id = ?`,
-		id).Scan(&buyerID, &price, &product, &status)
-	if status == "deny" || status == "accept" {
-		return buyerID, "complite"
+// ListOpenOrders возвращает заказы предприятия, ожидающие сборки, в порядке поступления.
+func ListOpenOrders(db *sql.DB, restNumber int) ([]Order, error) {
+	rows, err := db.Query(`SELECT `+orderColumns+`
+FROM orders o LEFT JOIN users u ON u.telegram_id = o.telegram_id
+WHERE o.rest_number=? AND o.status=? ORDER BY o.created_at, o.id`, restNumber, OrderOpen)
+	if err != nil {
+		slog.Error("Ошибка загрузки открытых заказов", "err", err)
+		return nil, err
+	}
+	return ordersFromRows(rows)
+}
+
+// OpenOrdersByRest — число заказов в сборке по предприятиям.
+func OpenOrdersByRest(db *sql.DB) (map[int]int, error) {
+	rows, err := db.Query(`SELECT COALESCE(rest_number, 0), COUNT(*) FROM orders WHERE status=? GROUP BY rest_number`, OrderOpen)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[int]int)
+	for rows.Next() {
+		var rest, n int
+		if err := rows.Scan(&rest, &n); err != nil {
+			return nil, err
+		}
+		counts[rest] += n
 	}
+	return counts, rows.Err()
+}
 
+// GetOrder возвращает заказ по номеру или sql.ErrNoRows.
+func GetOrder(db *sql.DB, id int) (Order, error) {
+	rows, err := db.Query(`SELECT `+orderColumns+`
+FROM orders o LEFT JOIN users u ON u.telegram_id = o.telegram_id WHERE o.id=?`, id)
+	if err != nil {
+		return Order{}, err
+	}
+	list, err := ordersFromRows(rows)
 	if err != nil {
-		log.Printf("Ошибка получения данных при возврате средств: %v", err)
+		return Order{}, err
+	}
+	if len(list) == 0 {
+		return Order{}, sql.ErrNoRows
 	}
+	return list[0], nil
+}
 
-	if decision == "deny" {
-		_, err = db.Exec("UPDATE users SET current_balance = current_balance + ? WHERE telegram_id = ?", price, buyerID)
-		if err != nil {
-			log.Printf("Ошибка возврата баланса пользователю %d: %v", buyerID, err)
+// ListProducts возвращает товары предприятия; inStockOnly — только те, что есть в наличии.
+func ListProducts(db *sql.DB, restNumber int, inStockOnly bool) ([]Product, error) {
+	rows, err := db.Query(`SELECT id, rest_number, product, price, remains FROM shop
+WHERE rest_number=? AND (remains > 0 OR ?=0) ORDER BY product`, restNumber, inStockOnly)
+	if err != nil {
+		slog.Error("Ошибка загрузки товаров", "err", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Product
+	for rows.Next() {
+		var p Product
+		if err := rows.Scan(&p.ID, &p.RestNumber, &p.Name, &p.Price, &p.Remains); err != nil {
+			slog.Error("Ошибка скана товара", "err", err)
+			continue
 		}
+		list = append(list, p)
 	}
+	return list, rows.Err()
+}
+
+// GetProduct возвращает товар по id или sql.ErrNoRows.
+func GetProduct(q queryer, id int) (Product, error) {
+	var p Product
+	err := q.QueryRow(`SELECT id, rest_number, product, price, remains FROM shop WHERE id=?`, id).
+		Scan(&p.ID, &p.RestNumber, &p.Name, &p.Price, &p.Remains)
+	return p, err
+}
 
-	db.Exec("UPDATE orders SET status=? WHERE id=?", decision, id)
+// ErrOrderDecided — заказ уже выдан или отменён.
+var ErrOrderDecided = i18n.NewError("orders.already_done")
 
-	return buyerID, product
+// CompleteOrder выдаёт (accept) или отменяет (deny) заказ в сборке; при отмене
+// звёзды возвращаются покупателю. Статус меняется условным UPDATE в той же
+// транзакции, что и возврат, поэтому одновременные решения по одному заказу
+// (админ в боте, API, веб-панель, автоотмена) не вернут звёзды дважды: второе
+// получит ErrOrderDecided. Любая ошибка откатывает и статус, и возврат.
+func CompleteOrder(db *sql.DB, id int, decision string) (int64, string, error) {
+	return CompleteOrderContext(context.Background(), db, id, decision)
 }
 
-func GetOrderInfo(db *sql.DB, id int) (string, string, string, int, error) {
+// CompleteOrderContext — CompleteOrder с контекстом обновления для запросов к базе.
+func CompleteOrderContext(ctx context.Context, db *sql.DB, id int, decision string) (int64, string, error) {
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer sqlTx.Rollback()
+	tx := withContext{ctx, sqlTx}
+
+	res, err := tx.Exec(`UPDATE orders SET status=?, decided_at=CURRENT_TIMESTAMP WHERE id=? AND status=?`,
+		decision, id, OrderOpen)
+	if err != nil {
+		return 0, "", err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, "", err
+	} else if n != 1 {
+		return 0, "", ErrOrderDecided
+	}
+
 	var buyerID int64
-	var product, num, name string
 	var price int
+	var product string
+	err = tx.QueryRow(`SELECT telegram_id, COALESCE(price, 0), COALESCE(product_name, '') FROM orders WHERE id=?`, id).
+		Scan(&buyerID, &price, &product)
+	if err != nil {
+		return 0, "", err
+	}
+	if decision == OrderDenied {
+		if _, err = tx.Exec(`UPDATE users SET current_balance = COALESCE(current_balance, 0) + ? WHERE telegram_id=?`, price, buyerID); err != nil {
+			return 0, "", err
+		}
+		if err = AddTransaction(tx, buyerID, price, TxRefund, 0, product); err != nil {
+			return 0, "", err
+		}
+	}
+	if err = sqlTx.Commit(); err != nil {
+		return 0, "", err
+	}
+	if decision == OrderDenied {
+		countStars(price, TxRefund)
+	}
+	return buyerID, product, nil
+}
+
+// BuyProduct списывает цену товара с баланса покупателя, уменьшает остаток и создаёт
+// заказ; возвращает номер заказа. Товар другого предприятия, отсутствие в наличии
+// и нехватка звёзд — *i18n.Error.
+func BuyProduct(db *sql.DB, buyerID int64, productID int) (int, error) {
+	return BuyProductContext(context.Background(), db, buyerID, productID)
+}
 
-	err := db.QueryRow(`SELECT telegram_id, product_name, price FROM orders WHERE id = ?`,
-		id).Scan(&buyerID, &product, &price)
+// BuyProductContext — BuyProduct с контекстом обновления для запросов к базе.
+func BuyProductContext(ctx context.Context, db *sql.DB, buyerID int64, productID int) (int, error) {
+	q := withContext{ctx, db}
+	p, err := GetProduct(q, productID)
+	if err != nil {
+		return 0, err
+	}
+	restNum, err := GetUserRestID(q, buyerID)
 	if err != nil {
-		log.Printf("Ошибка получения данных заказа: %v", err)
-		return "", "", "", 0, err
+		return 0, err
+	}
+	if restNum != p.RestNumber {
+		return 0, i18n.NewError("buy.other_rest")
+	}
+	if p.Remains < 1 {
+		return 0, i18n.NewError("buy.out_of_stock")
 	}
 
-	err = db.QueryRow(`SELECT table_number, name FROM users WHERE telegram_id = ?`, buyerID).Scan(&num, &name)
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer sqlTx.Rollback()
+	tx := withContext{ctx, sqlTx}
+	// Баланс и остаток проверяются внутри транзакции: параллельная покупка могла их изменить
+	var balance int
+	if err = tx.QueryRow(`SELECT COALESCE(current_balance, 0) FROM users WHERE telegram_id=?`, buyerID).Scan(&balance); err != nil {
+		return 0, err
+	}
+	if balance < p.Price {
+		return 0, i18n.NewError("buy.not_enough")
+	}
+	res, err := tx.Exec(`UPDATE shop SET remains = remains - 1 WHERE id=? AND remains > 0`, productID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, i18n.NewError("buy.out_of_stock")
+	}
+	if _, err = tx.Exec(`UPDATE users SET current_balance = current_balance - ? WHERE telegram_id=?`, p.Price, buyerID); err != nil {
+		return 0, err
+	}
+	if err = AddTransaction(tx, buyerID, -p.Price, TxPurchase, buyerID, p.Name); err != nil {
+		return 0, err
+	}
+	var orderID int
+	err = tx.QueryRow(`INSERT INTO orders (telegram_id, product_name, product_id, status, rest_number, price)
+VALUES (?, ?, ?, ?, ?, ?) RETURNING id`, buyerID, p.Name, productID, OrderOpen, p.RestNumber, p.Price).Scan(&orderID)
 	if err != nil {
-		log.Printf("Ошибка получения данных покупателя: %v", err)
-		return "", "", "", 0, err
+		return 0, err
+	}
+	if err = sqlTx.Commit(); err != nil {
+		return 0, err
 	}
+	countStars(-p.Price, TxPurchase)
+	return orderID, nil
+}
+
+// AddProduct добавляет товар в магазин предприятия и возвращает его id.
+func AddProduct(db *sql.DB, restNumber int, name string, price, remains int) (int, error) {
+	var id int
+	err := db.QueryRow(`INSERT INTO shop (product, price, remains, rest_number) VALUES (?, ?, ?, ?) RETURNING id`,
+		name, price, remains, restNumber).Scan(&id)
+	return id, err
+}
 
-	return num, name, product, price, nil
+// UpdateProduct сохраняет название, цену и остаток товара.
+func UpdateProduct(db *sql.DB, p Product) error {
+	res, err := db.Exec(`UPDATE shop SET product=?, price=?, remains=? WHERE id=?`, p.Name, p.Price, p.Remains, p.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
 }
 
 func DeleteProduct(db *sql.DB, id int) error {
@@ -102,9 +273,9 @@ func DeleteProduct(db *sql.DB, id int) error {
 	return nil
 }
 
-func GetUserRestID(db *sql.DB, userID int64) (int, error) {
+func GetUserRestID(q queryer, userID int64) (int, error) {
 	var restID int
-	err := db.QueryRow(`SELECT rest_number FROM users WHERE telegram_id=?`, userID).Scan(&restID)
+	err := q.QueryRow(`SELECT rest_number FROM users WHERE telegram_id=?`, userID).Scan(&restID)
 	return restID, err
 }
 
@@ -144,35 +315,3 @@ func ParseProductID(data string) (int, error) {
 	}
 	return id, nil
 }
-
-func KeyboardOrders(db *sql.DB, fromID int64) (tgbotapi.InlineKeyboardMarkup, string) {
-	rows, err := db.Query(`SELECT id, product_name, telegram_id FROM orders WHERE rest_number=(
-			SELECT rest_number FROM users WHERE telegram_id=?) AND status = ?`, fromID, "в сборке")
-	if err != nil {
-		log.Printf("Ошибка запроса KeyboardOrders: %v", err)
-		return tgbotapi.NewInlineKeyboardMarkup([][]tgbotapi.InlineKeyboardButton{}...), "Ошибка загрузки заказов"
-	}
-	defer rows.Close()
-
-	var keyboardRows [][]tgbotapi.InlineKeyboardButton
-	for rows.Next() {
-		var userID int64
-		var id int
-		var product string
-		if err := rows.Scan(&id, &product, &userID); err != nil {
-			log.Printf("Ошибка скана KeyboardOrders: %v", err)
-			continue
-		}
-		num, name, _, _, _ := GetWorkerInfoValues(db, userID)
-		btn := tgbotapi.NewInlineKeyboardButtonData(
-			fmt.Sprintf("%s %s (%s)", num, name, product),
-			fmt.Sprintf("orders_order:%d", id),
-		)
-		keyboardRows = append(keyboardRows, tgbotapi.NewInlineKeyboardRow(btn))
-	}
-	if len(keyboardRows) == 0 {
-		return tgbotapi.InlineKeyboardMarkup{}, "В предприятии отсутствуют заказы"
-	}
-
-	return tgbotapi.NewInlineKeyboardMarkup(keyboardRows...), ""
-}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// Стадии контроля сроков для заказов и заявок на регистрацию
+const (
+	SLAStageNone      = 0
+	SLAStageReminded  = 1 // админам отправлено напоминание
+	SLAStageEscalated = 2 // передано суперпользователям
+)
+
+// StaleItem — заказ в сборке или заявка на регистрацию, ожидающая действия.
+type StaleItem struct {
+	ID         int64 // id заказа или telegram_id заявителя
+	RestNumber int
+	Name       string
+	Table      string
+	Product    string // только для заказов
+	Price      int    // только для заказов
+	AgeHours   float64
+	Stage      int
+}
+
+func scanStaleItems(rows *sql.Rows, withProduct bool) ([]StaleItem, error) {
+	defer rows.Close()
+	var list []StaleItem
+	for rows.Next() {
+		var it StaleItem
+		var from time.Time
+		var err error
+		if withProduct {
+			err = rows.Scan(&it.ID, &it.RestNumber, &it.Name, &it.Table, &it.Product, &it.Price, &from, &it.Stage)
+		} else {
+			err = rows.Scan(&it.ID, &it.RestNumber, &it.Name, &it.Table, &from, &it.Stage)
+		}
+		if err != nil {
+			slog.Error("Ошибка скана в scanStaleItems", "err", err)
+			continue
+		}
+		it.AgeHours = time.Since(from).Hours()
+		list = append(list, it)
+	}
+	return list, rows.Err()
+}
+
+// OpenOrdersAging — все заказы в сборке с возрастом в часах.
+func OpenOrdersAging(db *sql.DB) ([]StaleItem, error) {
+	rows, err := db.Query(`SELECT o.id, CAST(COALESCE(o.rest_number, 0) AS INTEGER), COALESCE(u.name, ''),
+       COALESCE(u.table_number, ''), COALESCE(o.product_name, ''), COALESCE(o.price, 0),
+       o.created_at, COALESCE(o.sla_stage, 0)
+FROM orders o LEFT JOIN users u ON u.telegram_id = o.telegram_id
+WHERE o.status='в сборке' ORDER BY o.created_at`)
+	if err != nil {
+		return nil, err
+	}
+	return scanStaleItems(rows, true)
+}
+
+// PendingApprovalsAging — заявки на регистрацию, ожидающие решения админа.
+func PendingApprovalsAging(db *sql.DB) ([]StaleItem, error) {
+	rows, err := db.Query(`SELECT telegram_id, CAST(COALESCE(rest_number, 0) AS INTEGER), COALESCE(name, ''),
+       COALESCE(table_number, ''), submitted_at, COALESCE(sla_stage, 0)
+FROM users WHERE submitted_at IS NOT NULL AND COALESCE(verified, 0) != 1 ORDER BY submitted_at`)
+	if err != nil {
+		return nil, err
+	}
+	return scanStaleItems(rows, false)
+}
+
+func SetOrderSLAStage(db *sql.DB, orderID int64, stage int) error {
+	_, err := db.Exec(`UPDATE orders SET sla_stage=? WHERE id=?`, stage, orderID)
+	return err
+}
+
+func SetApprovalSLAStage(db *sql.DB, telegramID int64, stage int) error {
+	_, err := db.Exec(`UPDATE users SET sla_stage=? WHERE telegram_id=?`, stage, telegramID)
+	return err
+}
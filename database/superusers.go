@@ -0,0 +1,140 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/i18n"
+)
+
+type SuperUser struct {
+	TelegramID int64
+	Name       string
+	Source     string // env — задан через TELEGRAM_SUPER_USER, console — добавлен из консоли
+	AddedBy    int64
+}
+
+// ParseSuperUsers разбирает список ID из TELEGRAM_SUPER_USER (через запятую или пробел).
+func ParseSuperUsers(value string) ([]int64, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == ' ' })
+	var ids []int64
+	for _, f := range fields {
+		id, err := strconv.ParseInt(f, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("некорректный ID суперпользователя %q", f)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("не задан ни один суперпользователь")
+	}
+	return ids, nil
+}
+
+// SeedSuperUsers приводит суперпользователей из настроек (source='env') к списку
+// ids: новые добавляет, а тех, кого из настроек убрали, лишает прав — иначе строку
+// env не удалить и из консоли (err.super_env). Всё — одной транзакцией.
+func SeedSuperUsers(db *sql.DB, ids []int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		_, err := tx.Exec(`INSERT INTO super_users (telegram_id, source, added_by) VALUES (?, 'env', 0)
+ON CONFLICT(telegram_id) DO UPDATE SET source='env'`, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := `DELETE FROM super_users WHERE source='env'`
+	if len(ids) > 0 {
+		query += ` AND telegram_id NOT IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+	}
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		slog.Warn("Сняты суперпользователи, которых больше нет в настройках", "count", n)
+	}
+	return nil
+}
+
+func IsSuperUser(db *sql.DB, telegramID int64) bool {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM super_users WHERE telegram_id=?)`, telegramID).Scan(&exists)
+	if err != nil {
+		slog.Error("Ошибка проверки суперпользователя", "user_id", telegramID, "err", err)
+		return false
+	}
+	return exists
+}
+
+func ListSuperUsers(db *sql.DB) ([]SuperUser, error) {
+	rows, err := db.Query(`SELECT s.telegram_id, COALESCE(u.name, ''), s.source, COALESCE(s.added_by, 0)
+FROM super_users s LEFT JOIN users u ON u.telegram_id = s.telegram_id ORDER BY s.created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []SuperUser
+	for rows.Next() {
+		var s SuperUser
+		if err := rows.Scan(&s.TelegramID, &s.Name, &s.Source, &s.AddedBy); err != nil {
+			slog.Error("Ошибка скана в ListSuperUsers", "err", err)
+			continue
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func AddSuperUser(db *sql.DB, telegramID, addedBy int64) error {
+	if telegramID <= 0 {
+		return i18n.NewError("err.invalid_telegram_id")
+	}
+	res, err := db.Exec(`INSERT INTO super_users (telegram_id, source, added_by) VALUES (?, 'console', ?)
+ON CONFLICT(telegram_id) DO NOTHING`,
+		telegramID, addedBy)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return i18n.NewError("err.already_super")
+	}
+	return nil
+}
+
+// RemoveSuperUser удаляет суперпользователя, добавленного из консоли.
+// Заданных через окружение убрать нельзя — они вернутся при перезапуске.
+func RemoveSuperUser(db *sql.DB, telegramID, removedBy int64) error {
+	if telegramID == removedBy {
+		return i18n.NewError("err.super_self")
+	}
+	var source string
+	err := db.QueryRow(`SELECT source FROM super_users WHERE telegram_id=?`, telegramID).Scan(&source)
+	if err == sql.ErrNoRows {
+		return i18n.NewError("err.super_not_found")
+	}
+	if err != nil {
+		return err
+	}
+	if source == "env" {
+		return i18n.NewError("err.super_env")
+	}
+	_, err = db.Exec(`DELETE FROM super_users WHERE telegram_id=?`, telegramID)
+	return err
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"tbViT/i18n"
+	"tbViT/logging"
+)
+
+// Из параллельных начислений одному работнику проходит одно: остальные упираются в паузу.
+func TestTopUpBalanceCooldown(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	const adminID, workerID = int64(1), int64(100)
+	_, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, access_level, current_balance)
+VALUES (?, 1, 1, 'admin', 0), (?, 1, 1, 'worker', 0)`, adminID, workerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ok, cooldown := 0, 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := TopUpBalance(db, adminID, workerID, 1)
+			var ruleErr *i18n.Error
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				ok++
+			case errors.As(err, &ruleErr):
+				cooldown++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != 1 || cooldown != 4 {
+		t.Fatalf("начислено %d раз, пауза %d; want 1 и 4", ok, cooldown)
+	}
+	if balance, _ := GetBalance(db, workerID); balance != 1 {
+		t.Errorf("баланс %d, want 1", balance)
+	}
+}
+
+func TestTopUpBalanceLimits(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	const managerID = int64(2)
+	_, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, access_level, current_balance)
+VALUES (1, 1, 1, 'admin', 0), (?, 1, 1, 'manager', 0),
+(101, 1, 1, 'worker', 0), (102, 1, 1, 'worker', 0), (103, 1, 1, 'worker', 4), (104, 1, 1, 'worker', 5)`, managerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RestOverrides = map[int]map[string]int{1: {"manager_budget": 3, "max_balance": 5}}
+	t.Cleanup(func() { RestOverrides = nil })
+
+	if _, err := TopUpBalance(db, managerID, 101, 2); err != nil {
+		t.Fatal(err)
+	}
+	// Остаток бюджета — 1 звезда
+	_, err = TopUpBalance(db, managerID, 102, 2)
+	if e := (*i18n.Error)(nil); !errors.As(err, &e) || e.Key != "topup.budget_exceeded" {
+		t.Fatalf("err = %v, want topup.budget_exceeded", err)
+	}
+	// До максимума работнику 103 остаётся 1 звезда
+	_, err = TopUpBalance(db, managerID, 103, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = TopUpBalance(db, 1, 103, 1); err == nil {
+		t.Fatal("повторное начисление в паузу должно отклоняться")
+	}
+	// Админа бюджет не ограничивает, но максимум баланса действует
+	_, err = TopUpBalance(db, 1, 104, 1)
+	if e := (*i18n.Error)(nil); !errors.As(err, &e) || e.Key != "topup.max_balance" {
+		t.Fatalf("err = %v, want topup.max_balance", err)
+	}
+
+	// Отклонённые начисления ничего не меняют
+	for id, want := range map[int64]int{101: 2, 102: 0, 103: 5, 104: 5} {
+		var balance int
+		var lastTs int64
+		if err := db.QueryRow(`SELECT current_balance, COALESCE(last_ts, 0) FROM users WHERE telegram_id=?`, id).Scan(&balance, &lastTs); err != nil {
+			t.Fatal(err)
+		}
+		if balance != want {
+			t.Errorf("работник %d: баланс %d, want %d", id, balance, want)
+		}
+		if (id == 102 || id == 104) && lastTs != 0 {
+			t.Errorf("работник %d: пауза выставлена после отказа", id)
+		}
+	}
+}
+
+// Запросы TopUpBalanceContext попадают в журнал с cid обновления.
+func TestTopUpBalanceContextCID(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, access_level, current_balance)
+VALUES (1, 1, 1, 'admin', 0), (100, 1, 1, 'worker', 0)`); err != nil {
+		t.Fatal(err)
+	}
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	var buf bytes.Buffer
+	if err := logging.Setup(&buf, "debug"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := logging.WithID(context.Background(), "test-cid")
+	if _, err := TopUpBalanceContext(ctx, db, 1, 100, 1); err != nil {
+		t.Fatal(err)
+	}
+	var updates, inserts int
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec struct{ Msg, Query, CID string }
+		if err := json.Unmarshal([]byte(line), &rec); err != nil || rec.Msg != "Запрос к БД" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(rec.Query, "UPDATE users"):
+			updates++
+		case strings.HasPrefix(rec.Query, "INSERT INTO transactions"):
+			inserts++
+		default:
+			continue
+		}
+		if rec.CID != "test-cid" {
+			t.Errorf("запрос %q без cid обновления", rec.Query)
+		}
+	}
+	if updates == 0 || inserts == 0 {
+		t.Fatalf("в журнале нет запросов начисления:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strings"
+	"tbViT/metrics"
+	"time"
+)
+
+// SlowQuery — запросы дольше этого пишутся в журнал с уровнем warn.
+var SlowQuery = 500 * time.Millisecond
+
+// traceConnector пишет запросы в журнал с уровнем debug и учитывает их время
+// в метриках. Запросы, выполненные с контекстом обновления (ExecContext,
+// QueryContext…), получают его cid. Параметры запросов не пишутся: в них
+// персональные данные.
+type traceConnector struct {
+	driver.Connector
+}
+
+func (c traceConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &traceConn{conn}, nil
+}
+
+type traceConn struct {
+	driver.Conn
+}
+
+func (c *traceConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *traceConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return traceStmt{stmt, query}, nil
+}
+
+func (c *traceConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	traceQuery(ctx, query, start, err)
+	return rows, err
+}
+
+func (c *traceConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	traceQuery(ctx, query, start, err)
+	return res, err
+}
+
+func (c *traceConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *traceConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *traceConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *traceConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+type traceStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s traceStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	traceQuery(ctx, s.query, start, err)
+	return res, err
+}
+
+func (s traceStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	traceQuery(ctx, s.query, start, err)
+	return rows, err
+}
+
+func traceQuery(ctx context.Context, query string, start time.Time, err error) {
+	// ErrSkip — драйвер просит database/sql выполнить запрос через Prepare
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	elapsed := time.Since(start)
+	metrics.DBQueryDuration.Observe(elapsed.Seconds(), queryOp(query))
+	level := slog.LevelDebug
+	if elapsed >= SlowQuery {
+		level = slog.LevelWarn
+	}
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	attrs := []any{"query", strings.Join(strings.Fields(query), " "), "ms", elapsed.Milliseconds()}
+	if err != nil {
+		attrs = append(attrs, "err", err)
+	}
+	if level == slog.LevelWarn {
+		slog.Log(ctx, level, "Медленный запрос к БД", attrs...)
+		return
+	}
+	slog.Log(ctx, level, "Запрос к БД", attrs...)
+}
+
+// queryOp — вид запроса для метки метрики: первое слово запроса.
+func queryOp(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	switch op := strings.ToLower(fields[0]); op {
+	case "select", "insert", "update", "delete", "with":
+		return op
+	}
+	return "other"
+}
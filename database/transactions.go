@@ -0,0 +1,116 @@
+package database
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"tbViT/metrics"
+	"time"
+)
+
+// Типы операций с балансом
+const (
+	TxTopUp      = "topup"      // начисление менеджером/админом
+	TxPurchase   = "purchase"   // покупка в магазине
+	TxRefund     = "refund"     // возврат за отменённый заказ
+	TxCorrection = "correction" // ручная корректировка баланса админом
+	TxTransfer   = "transfer"   // пересчёт баланса при переводе в другое предприятие
+	TxBonus      = "bonus"      // бонус за достижение
+	TxKudos      = "kudos"      // звёзды за одобренную благодарность коллеги
+	TxExpiry     = "expiry"     // сгорание старых звёзд
+)
+
+// TxTypes — типы операций в порядке отображения в фильтре истории.
+var TxTypes = []string{TxTopUp, TxPurchase, TxRefund, TxCorrection, TxTransfer, TxBonus, TxKudos, TxExpiry}
+
+type Transaction struct {
+	ID           int
+	TelegramID   int64
+	RestNumber   int
+	Amount       int
+	BalanceAfter int
+	Type         string
+	ActorID      int64
+	ActorName    string
+	Comment      string
+	CreatedAt    time.Time
+}
+
+// execer — общее для *sql.DB и *sql.Tx, чтобы операция писалась в той же транзакции, что и баланс.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// AddTransaction записывает операцию с балансом. Вызывать после изменения current_balance:
+// остаток после операции берётся из users. Метрики звёзд учитываются отдельно —
+// countStars после успешного Commit, чтобы откаченная операция в них не попала.
+func AddTransaction(q execer, telegramID int64, amount int, txType string, actorID int64, comment string) error {
+	_, err := q.Exec(`INSERT INTO transactions (telegram_id, rest_number, amount, balance_after, type, actor_id, comment)
+SELECT telegram_id, rest_number, ?, COALESCE(current_balance, 0), ?, ?, ? FROM users WHERE telegram_id=?`,
+		amount, txType, actorID, comment, telegramID)
+	if err != nil {
+		slog.Error("Ошибка записи операции", "type", txType, "user_id", telegramID, "err", err)
+		return err
+	}
+	return nil
+}
+
+// countStars учитывает в метриках операцию, записанную AddTransaction в
+// зафиксированной транзакции.
+func countStars(amount int, txType string) {
+	if amount > 0 {
+		metrics.StarsIssued.Add(float64(amount), txType)
+	} else if amount < 0 {
+		metrics.StarsSpent.Add(float64(-amount), txType)
+	}
+}
+
+// TxFilter — фильтр истории баланса. Days == 0 — за всё время, Type == "" — все типы.
+type TxFilter struct {
+	Days int
+	Type string
+}
+
+func (f TxFilter) where(telegramID int64) (string, []interface{}) {
+	where := []string{"t.telegram_id=?"}
+	args := []interface{}{telegramID}
+	if f.Days > 0 {
+		where = append(where, "t.created_at >= ?")
+		args = append(args, time.Now().UTC().AddDate(0, 0, -f.Days).Format("2006-01-02 15:04:05"))
+	}
+	if f.Type != "" {
+		where = append(where, "t.type=?")
+		args = append(args, f.Type)
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// ListTransactions возвращает страницу истории баланса (новые первыми) и общее число операций.
+func ListTransactions(db *sql.DB, telegramID int64, f TxFilter, limit, offset int) ([]Transaction, int, error) {
+	where, args := f.where(telegramID)
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions t WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	args = append(args, limit, offset)
+	rows, err := db.Query(`SELECT t.id, t.telegram_id, COALESCE(t.rest_number, 0), t.amount, t.balance_after, t.type,
+       COALESCE(t.actor_id, 0), COALESCE(u.name, ''), COALESCE(t.comment, ''), t.created_at
+FROM transactions t LEFT JOIN users u ON u.telegram_id = t.actor_id
+WHERE `+where+` ORDER BY t.id DESC LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var list []Transaction
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.ID, &t.TelegramID, &t.RestNumber, &t.Amount, &t.BalanceAfter, &t.Type,
+			&t.ActorID, &t.ActorName, &t.Comment, &t.CreatedAt); err != nil {
+			slog.Error("Ошибка скана в ListTransactions", "err", err)
+			continue
+		}
+		list = append(list, t)
+	}
+	return list, total, rows.Err()
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"tbViT/i18n"
+)
+
+// Режимы переноса баланса при переводе сотрудника
+const (
+	TransferBalanceCarry   = "carry"   // баланс переносится как есть
+	TransferBalanceConvert = "convert" // баланс пересчитывается по курсу TransferConvertPercent
+	TransferBalanceReset   = "reset"   // баланс обнуляется
+)
+
+// Что делать с незакрытыми заказами сотрудника при переводе
+const (
+	TransferOrdersCancel   = "cancel"   // отменить с возвратом звёзд
+	TransferOrdersComplete = "complete" // считать выполненными
+)
+
+// TransferConvertPercent — курс пересчёта баланса (в процентах) для режима convert.
+// Переопределяется переменной окружения TRANSFER_CONVERT_PERCENT.
+var TransferConvertPercent = 50
+
+type Transfer struct {
+	ID          int
+	TelegramID  int64
+	FromRest    int
+	ToRest      int
+	BalanceMode string
+	OrdersMode  string
+	Status      string
+	InitiatorID int64
+}
+
+func IsValidTransferBalanceMode(mode string) bool {
+	switch mode {
+	case TransferBalanceCarry, TransferBalanceConvert, TransferBalanceReset:
+		return true
+	}
+	return false
+}
+
+func IsValidTransferOrdersMode(mode string) bool {
+	return mode == TransferOrdersCancel || mode == TransferOrdersComplete
+}
+
+// ConvertTransferBalance считает баланс сотрудника после перевода в зависимости от режима.
+func ConvertTransferBalance(balance int, mode string) int {
+	switch mode {
+	case TransferBalanceConvert:
+		return balance * TransferConvertPercent / 100
+	case TransferBalanceReset:
+		return 0
+	}
+	return balance
+}
+
+func CountOpenOrders(db *sql.DB, telegramID int64) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM orders WHERE telegram_id=? AND status=?`,
+		telegramID, "в сборке").Scan(&n)
+	return n, err
+}
+
+// CreateTransfer создаёт заявку на перевод сотрудника в другое предприятие.
+// Возвращает ID заявки. Одновременно у сотрудника может быть только одна открытая заявка.
+func CreateTransfer(db *sql.DB, workerID int64, toRest int, balanceMode, ordersMode string, initiatorID int64) (int, error) {
+	if !IsValidTransferBalanceMode(balanceMode) || !IsValidTransferOrdersMode(ordersMode) {
+		return 0, i18n.NewError("err.transfer_mode")
+	}
+	fromRest, err := GetUserRestID(db, workerID)
+	if err != nil {
+		return 0, err
+	}
+	if fromRest == toRest {
+		return 0, i18n.NewError("err.transfer_same_rest")
+	}
+
+	var pending int
+	err = db.QueryRow(`SELECT COUNT(*) FROM transfers WHERE telegram_id=? AND status='pending'`, workerID).Scan(&pending)
+	if err != nil {
+		return 0, err
+	}
+	if pending > 0 {
+		return 0, i18n.NewError("err.transfer_pending")
+	}
+
+	var id int
+	err = db.QueryRow(`INSERT INTO transfers (telegram_id, from_rest, to_rest, balance_mode, orders_mode, status, initiator_id)
+VALUES (?, ?, ?, ?, ?, 'pending', ?) RETURNING id`, workerID, fromRest, toRest, balanceMode, ordersMode, initiatorID).Scan(&id)
+	if err != nil {
+		slog.Error("Ошибка создания заявки на перевод", "user_id", workerID, "err", err)
+		return 0, err
+	}
+	slog.Info("Заявка на перевод", "transfer_id", id, "user_id", workerID, "from_rest", fromRest, "to_rest", toRest, "balance_mode", balanceMode, "orders_mode", ordersMode)
+	return id, nil
+}
+
+func GetTransfer(db *sql.DB, id int) (Transfer, error) {
+	var t Transfer
+	err := db.QueryRow(`SELECT id, telegram_id, from_rest, to_rest, balance_mode, orders_mode, status, initiator_id
+FROM transfers WHERE id=?`, id).Scan(&t.ID, &t.TelegramID, &t.FromRest, &t.ToRest, &t.BalanceMode,
+		&t.OrdersMode, &t.Status, &t.InitiatorID)
+	return t, err
+}
+
+// AcceptTransfer переводит сотрудника в новое предприятие: закрывает его заказы,
+// пересчитывает баланс и фиксирует результат в заявке. Возвращает баланс до и после перевода.
+// Заявка закрывается условным UPDATE в той же транзакции, поэтому два одновременных
+// решения не переведут сотрудника дважды: второе получит err.transfer_decided.
+func AcceptTransfer(db *sql.DB, id int, deciderID int64) (Transfer, int, int, error) {
+	t, err := GetTransfer(db, id)
+	if err != nil {
+		return t, 0, 0, err
+	}
+
+	lang := UserLang(db, t.TelegramID)
+	tx, err := db.Begin()
+	if err != nil {
+		return t, 0, 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE transfers SET status='accepted', decided_by=?, decided_at=CURRENT_TIMESTAMP
+WHERE id=? AND status='pending'`, deciderID, id)
+	if err != nil {
+		return t, 0, 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return t, 0, 0, err
+	} else if n != 1 {
+		return t, 0, 0, i18n.NewError("err.transfer_decided")
+	}
+
+	var restNumber int
+	err = tx.QueryRow(`SELECT rest_number FROM users WHERE telegram_id=?`, t.TelegramID).Scan(&restNumber)
+	if err != nil {
+		return t, 0, 0, i18n.NewError("err.transfer_worker_not_found", err)
+	}
+	if restNumber != t.FromRest {
+		return t, 0, 0, i18n.NewError("err.transfer_worker_moved")
+	}
+	// Единственного админа не переводим: предприятие осталось бы без администратора
+	if err = ensureNotLastAdmin(tx, t.TelegramID); err != nil {
+		return t, 0, 0, err
+	}
+
+	// Закрываем незакрытые заказы до пересчёта баланса, чтобы возврат попал в перенос
+	var refund int
+	if t.OrdersMode == TransferOrdersCancel {
+		err = tx.QueryRow(`SELECT COALESCE(SUM(price), 0) FROM orders WHERE telegram_id=? AND status='в сборке'`,
+			t.TelegramID).Scan(&refund)
+		if err != nil {
+			return t, 0, 0, err
+		}
+		if refund > 0 {
+			_, err = tx.Exec(`UPDATE users SET current_balance = current_balance + ? WHERE telegram_id=?`, refund, t.TelegramID)
+			if err != nil {
+				return t, 0, 0, err
+			}
+			if err = AddTransaction(tx, t.TelegramID, refund, TxRefund, deciderID, i18n.T(lang, "transfer.tx_refund_comment")); err != nil {
+				return t, 0, 0, err
+			}
+		}
+		_, err = tx.Exec(`UPDATE orders SET status='deny', decided_at=CURRENT_TIMESTAMP WHERE telegram_id=? AND status='в сборке'`, t.TelegramID)
+	} else {
+		_, err = tx.Exec(`UPDATE orders SET status='accept', decided_at=CURRENT_TIMESTAMP WHERE telegram_id=? AND status='в сборке'`, t.TelegramID)
+	}
+	if err != nil {
+		return t, 0, 0, err
+	}
+
+	var before int
+	err = tx.QueryRow(`SELECT COALESCE(current_balance, 0) FROM users WHERE telegram_id=?`, t.TelegramID).Scan(&before)
+	if err != nil {
+		return t, 0, 0, err
+	}
+	after := ConvertTransferBalance(before, t.BalanceMode)
+
+	_, err = tx.Exec(`UPDATE users SET rest_number=?, current_balance=? WHERE telegram_id=?`,
+		t.ToRest, after, t.TelegramID)
+	if err != nil {
+		return t, 0, 0, err
+	}
+	if after != before {
+		comment := fmt.Sprintf("%d → %d", t.FromRest, t.ToRest)
+		if err = AddTransaction(tx, t.TelegramID, after-before, TxTransfer, deciderID, comment); err != nil {
+			return t, 0, 0, err
+		}
+	}
+	_, err = tx.Exec(`UPDATE transfers SET balance_before=?, balance_after=? WHERE id=?`, before, after, id)
+	if err != nil {
+		return t, 0, 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return t, 0, 0, err
+	}
+	countStars(refund, TxRefund)
+	countStars(after-before, TxTransfer)
+	slog.Info("Перевод выполнен", "transfer_id", id, "user_id", t.TelegramID, "to_rest", t.ToRest, "balance_before", before, "balance_after", after)
+	return t, before, after, nil
+}
+
+func DeclineTransfer(db *sql.DB, id int, deciderID int64) (Transfer, error) {
+	t, err := GetTransfer(db, id)
+	if err != nil {
+		return t, err
+	}
+	res, err := db.Exec(`UPDATE transfers SET status='declined', decided_by=?, decided_at=CURRENT_TIMESTAMP
+WHERE id=? AND status='pending'`, deciderID, id)
+	if err != nil {
+		return t, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return t, i18n.NewError("err.transfer_decided")
+	}
+	return t, nil
+}
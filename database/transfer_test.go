@@ -0,0 +1,100 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"tbViT/i18n"
+)
+
+// Одновременные решения по одной заявке переводят сотрудника и возвращают звёзды один раз.
+func TestAcceptTransferOnce(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	const userID = int64(100)
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, current_balance) VALUES (?, 1, 1, 10)`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO orders (telegram_id, product_name, status, rest_number, price) VALUES (?, 'Чай', ?, 1, 4)`, userID, OrderOpen); err != nil {
+		t.Fatal(err)
+	}
+	id, err := CreateTransfer(db, userID, 2, TransferBalanceCarry, TransferOrdersCancel, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted, decided := 0, 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, err := AcceptTransfer(db, id, 1)
+			var ruleErr *i18n.Error
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				accepted++
+			case errors.As(err, &ruleErr):
+				decided++
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 {
+		t.Fatalf("принято %d раз (отклонено как решённые: %d), want 1", accepted, decided)
+	}
+
+	var balance, rest, refunds int
+	if err := db.QueryRow(`SELECT current_balance, rest_number FROM users WHERE telegram_id=?`, userID).Scan(&balance, &rest); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE telegram_id=? AND type=?`, userID, TxRefund).Scan(&refunds); err != nil {
+		t.Fatal(err)
+	}
+	if balance != 14 || rest != 2 || refunds != 1 {
+		t.Errorf("баланс %d, предприятие %d, возвратов %d; want 14, 2, 1", balance, rest, refunds)
+	}
+
+	if _, err := DeclineTransfer(db, id, 1); err == nil {
+		t.Error("решённую заявку нельзя отклонить")
+	}
+}
+
+// Единственного админа предприятия перевести нельзя; заявка остаётся открытой.
+func TestAcceptTransferLastAdmin(t *testing.T) {
+	db := openTestDB(t, DriverSQLite)
+	const adminID = int64(100)
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, access_level, current_balance)
+VALUES (?, 1, 1, 'admin', 10)`, adminID); err != nil {
+		t.Fatal(err)
+	}
+	id, err := CreateTransfer(db, adminID, 2, TransferBalanceCarry, TransferOrdersCancel, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := AcceptTransfer(db, id, 1); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("err = %v, want ErrLastAdmin", err)
+	}
+	tr, err := GetTransfer(db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rest int
+	if err := db.QueryRow(`SELECT rest_number FROM users WHERE telegram_id=?`, adminID).Scan(&rest); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Status != "pending" || rest != 1 {
+		t.Errorf("заявка %q, предприятие %d; want pending и 1", tr.Status, rest)
+	}
+
+	// Со вторым админом перевод проходит
+	if _, err := db.Exec(`INSERT INTO users (telegram_id, rest_number, verified, access_level) VALUES (101, 1, 1, 'admin')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := AcceptTransfer(db, id, 1); err != nil {
+		t.Fatal(err)
+	}
+}
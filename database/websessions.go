@@ -0,0 +1,112 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"time"
+)
+
+// Сроки действия токенов веб-панели: ссылка для входа одноразовая и короткая,
+// сессия живёт рабочую смену.
+var (
+	WebLoginTTL   = 10 * time.Minute
+	WebSessionTTL = 12 * time.Hour
+)
+
+// Виды записей web_sessions
+const (
+	webKindLogin   = "login"
+	webKindSession = "session"
+)
+
+// WebSession — вход в веб-панель. RestNumber — предприятие, выбранное
+// суперпользователем; у админа не используется: он всегда работает со своим.
+type WebSession struct {
+	TelegramID int64
+	RestNumber int
+	ExpiresAt  time.Time
+}
+
+func newWebToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateWebLogin выпускает одноразовый токен ссылки для входа в веб-панель.
+// Заодно удаляет просроченные токены и сессии.
+func CreateWebLogin(db *sql.DB, userID int64) (string, error) {
+	now := time.Now()
+	if _, err := db.Exec(`DELETE FROM web_sessions WHERE expires_at<=?`, now.Unix()); err != nil {
+		slog.Error("Ошибка очистки сессий веб-панели", "err", err)
+	}
+	token, err := newWebToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(`INSERT INTO web_sessions (token_hash, kind, telegram_id, expires_at) VALUES (?, ?, ?, ?)`,
+		hashToken(token), webKindLogin, userID, now.Add(WebLoginTTL).Unix())
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// StartWebSession гасит токен ссылки и открывает по нему сессию. Неизвестный,
+// просроченный или уже использованный токен — sql.ErrNoRows.
+func StartWebSession(db *sql.DB, loginToken string) (string, WebSession, error) {
+	now := time.Now()
+	token, err := newWebToken()
+	if err != nil {
+		return "", WebSession{}, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return "", WebSession{}, err
+	}
+	defer tx.Rollback()
+
+	s := WebSession{ExpiresAt: now.Add(WebSessionTTL)}
+	err = tx.QueryRow(`DELETE FROM web_sessions WHERE token_hash=? AND kind=? AND expires_at>? RETURNING telegram_id`,
+		hashToken(loginToken), webKindLogin, now.Unix()).Scan(&s.TelegramID)
+	if err != nil {
+		return "", WebSession{}, err
+	}
+	_, err = tx.Exec(`INSERT INTO web_sessions (token_hash, kind, telegram_id, expires_at) VALUES (?, ?, ?, ?)`,
+		hashToken(token), webKindSession, s.TelegramID, s.ExpiresAt.Unix())
+	if err != nil {
+		return "", WebSession{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", WebSession{}, err
+	}
+	slog.Info("Вход в веб-панель", "user_id", s.TelegramID)
+	return token, s, nil
+}
+
+// FindWebSession ищет действующую сессию; просроченная — sql.ErrNoRows.
+func FindWebSession(db *sql.DB, token string) (WebSession, error) {
+	var s WebSession
+	var expires int64
+	err := db.QueryRow(`SELECT telegram_id, rest_number, expires_at FROM web_sessions WHERE token_hash=? AND kind=? AND expires_at>?`,
+		hashToken(token), webKindSession, time.Now().Unix()).Scan(&s.TelegramID, &s.RestNumber, &expires)
+	s.ExpiresAt = time.Unix(expires, 0)
+	return s, err
+}
+
+// SetWebSessionRest запоминает предприятие, выбранное суперпользователем.
+func SetWebSessionRest(db *sql.DB, token string, restNumber int) error {
+	_, err := db.Exec(`UPDATE web_sessions SET rest_number=? WHERE token_hash=? AND kind=?`,
+		restNumber, hashToken(token), webKindSession)
+	return err
+}
+
+// EndWebSession закрывает сессию (выход из веб-панели).
+func EndWebSession(db *sql.DB, token string) error {
+	_, err := db.Exec(`DELETE FROM web_sessions WHERE token_hash=? AND kind=?`, hashToken(token), webKindSession)
+	return err
+}
@@ -0,0 +1,132 @@
+// Package export превращает табличные данные из database в файлы CSV и XLSX.
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+	"tbViT/database"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+func IsValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatXLSX
+}
+
+// Render собирает файл нужного формата.
+func Render(t database.Table, format string) ([]byte, error) {
+	if format == FormatXLSX {
+		return XLSX(t)
+	}
+	return CSV(t)
+}
+
+// numericColumns отмечает колонки таблицы, числовые по схеме.
+func numericColumns(t database.Table) []bool {
+	numeric := make([]bool, len(t.Header))
+	for i, h := range t.Header {
+		numeric[i] = slices.Contains(t.Numeric, h)
+	}
+	return numeric
+}
+
+// number — значение числовой колонки; ok == false, если это не целое число.
+func number(numeric []bool, col int, v string) (n int64, ok bool) {
+	if col >= len(numeric) || !numeric[col] {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil
+}
+
+// safeText защищает от подстановки формул: значение, начинающееся с =, +, -, @
+// или управляющего символа, табличный редактор выполнил бы как формулу, поэтому
+// перед ним ставится апостроф. Имена и названия товаров вводят сами пользователи.
+func safeText(v string) string {
+	if v == "" {
+		return v
+	}
+	switch v[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + v
+	}
+	return v
+}
+
+func CSV(t database.Table) ([]byte, error) {
+	var buf bytes.Buffer
+	// BOM, чтобы Excel открыл кириллицу в UTF-8
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+	w.Write(t.Header)
+	numeric := numericColumns(t)
+	for _, row := range t.Rows {
+		cells := make([]string, len(row))
+		for j, v := range row {
+			// Отрицательное число в числовой колонке — не формула
+			if _, ok := number(numeric, j, v); ok {
+				cells[j] = v
+			} else {
+				cells[j] = safeText(v)
+			}
+		}
+		w.Write(cells)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func XLSX(t database.Table) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := t.Name
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(t.Header))
+	for i, h := range t.Header {
+		header[i] = h
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return nil, err
+	}
+	numeric := numericColumns(t)
+	for i, row := range t.Rows {
+		cells := make([]interface{}, len(row))
+		for j, v := range row {
+			// Числа пишем числами, чтобы по ним можно было считать в Excel; номер
+			// в расписании и прочие текстовые колонки остаются текстом, с нулями впереди
+			if n, ok := number(numeric, j, v); ok {
+				cells[j] = n
+			} else {
+				cells[j] = safeText(v)
+			}
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := sw.SetRow(cell, cells); err != nil {
+			return nil, err
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		return nil, err
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
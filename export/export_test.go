@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+	"tbViT/database"
+)
+
+var table = database.Table{
+	Name:    "staff",
+	Header:  []string{"table_number", "name", "current_balance"},
+	Numeric: []string{"current_balance"},
+	Rows: [][]string{
+		{"007", "=HYPERLINK(\"http://x\")", "-5"},
+		{"12", "@SUM(A1)", "10"},
+		{"-3", "+7", ""},
+	},
+}
+
+func TestCSV(t *testing.T) {
+	data, err := CSV(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff")))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"table_number", "name", "current_balance"},
+		{"007", "'=HYPERLINK(\"http://x\")", "-5"},
+		{"12", "'@SUM(A1)", "10"},
+		{"'-3", "'+7", ""},
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("[%d][%d] = %q, want %q", i, j, records[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	data, err := XLSX(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tests := []struct {
+		cell string
+		typ  excelize.CellType
+		want string
+	}{
+		{"A2", excelize.CellTypeInlineString, "007"},
+		{"B2", excelize.CellTypeInlineString, "'=HYPERLINK(\"http://x\")"},
+		{"C2", excelize.CellTypeUnset, "-5"},
+		{"A4", excelize.CellTypeInlineString, "'-3"},
+	}
+	for _, tt := range tests {
+		got, err := f.GetCellValue("staff", tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.cell, got, tt.want)
+		}
+		typ, err := f.GetCellType("staff", tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != tt.typ {
+			t.Errorf("%s: тип %v, want %v", tt.cell, typ, tt.typ)
+		}
+	}
+}
+
+// Журнал выгружается через CSV: значения, введённые пользователями, экранируются.
+func TestAuditCSV(t *testing.T) {
+	db, err := database.Open(database.DriverSQLite, filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := database.Migrate(db, database.DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	database.Audit(db, database.AuditEntry{ActorID: 1, RestNumber: 5, Action: "correction:name",
+		Target: "-2", Before: "Иван", After: "=HYPERLINK(\"http://x\")"})
+
+	table, err := database.AuditTable(db, database.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := CSV(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff")))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("строк %d, want 2", len(records))
+	}
+	want := map[int]string{2: "1", 3: "5", 5: "'-2", 6: "Иван", 7: "'=HYPERLINK(\"http://x\")"}
+	for j, v := range want {
+		if records[1][j] != v {
+			t.Errorf("%s = %q, want %q", records[0][j], records[1][j], v)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"sync"
+	"tbViT/database"
+	"tbViT/outbox"
+	"time"
+)
+
+// Идущие в фоне рассылки: StopBroadcasts прерывает их и дожидается, чтобы база
+// не закрылась посреди записи статуса доставки.
+var (
+	deliveries                sync.WaitGroup
+	deliveryCtx, stopDelivery = context.WithCancel(context.Background())
+)
+
+// BroadcastMessage собирает сообщение рассылки для chatID в зависимости от вида содержимого.
+func BroadcastMessage(b database.Broadcast, chatID int64, markup interface{}) tgbotapi.Chattable {
+	switch b.Kind {
+	case database.BroadcastPhoto:
+		msg := tgbotapi.NewPhoto(chatID, tgbotapi.FileID(b.FileID))
+		msg.Caption = b.Text
+		msg.ReplyMarkup = markup
+		return msg
+	case database.BroadcastDocument:
+		msg := tgbotapi.NewDocument(chatID, tgbotapi.FileID(b.FileID))
+		msg.Caption = b.Text
+		msg.ReplyMarkup = markup
+		return msg
+	}
+	msg := tgbotapi.NewMessage(chatID, b.Text)
+	msg.ReplyMarkup = markup
+	return msg
+}
+
+// IsBlockedError — пользователь заблокировал бота или удалил аккаунт.
+func IsBlockedError(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == 403
+}
+
+// retryAfter — сколько ждать, если Telegram ответил 429 Too Many Requests.
+func retryAfter(err error) time.Duration {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == 429 && apiErr.RetryAfter > 0 {
+		return time.Duration(apiErr.RetryAfter) * time.Second
+	}
+	return 0
+}
+
+// StartDelivery запускает рассылку объявления id в фоне.
+func StartDelivery(bot *tgbotapi.BotAPI, db *sql.DB, id int) {
+	deliveries.Add(1)
+	go func() {
+		defer deliveries.Done()
+		DeliverBroadcast(deliveryCtx, bot, db, id)
+	}()
+}
+
+// StopBroadcasts прерывает фоновые рассылки и ждёт их завершения не дольше ctx.
+// Прерванная рассылка остаётся незавершённой и продолжится после запуска.
+func StopBroadcasts(ctx context.Context) error {
+	stopDelivery()
+	done := make(chan struct{})
+	go func() {
+		deliveries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitBroadcasts дожидается фоновых рассылок после StopBroadcasts без ограничения по времени.
+func WaitBroadcasts() {
+	deliveries.Wait()
+}
+
+// DeliverBroadcast рассылает объявление всем ещё не получившим его адресатам
+// и сообщает автору итог. Можно вызывать повторно — отправленным не дублирует.
+// После отмены ctx рассылка прерывается, не отмечая её завершённой.
+func DeliverBroadcast(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, id int) {
+	b, err := database.GetBroadcast(db, id)
+	if err != nil {
+		slog.Error("Ошибка загрузки рассылки", "broadcast_id", id, "err", err)
+		return
+	}
+	recipients, err := database.PendingBroadcastRecipients(db, id)
+	if err != nil {
+		slog.Error("Ошибка загрузки получателей рассылки", "broadcast_id", id, "err", err)
+		return
+	}
+
+	for _, chatID := range recipients {
+		if ctx.Err() != nil {
+			slog.Info("Рассылка прервана остановкой бота", "broadcast_id", id)
+			return
+		}
+		// Общий с очередью уведомлений лимит скорости
+		outbox.WaitGlobal()
+		_, err := bot.Send(BroadcastMessage(b, chatID, nil))
+		if wait := retryAfter(err); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				slog.Info("Рассылка прервана остановкой бота", "broadcast_id", id)
+				return
+			}
+			_, err = bot.Send(BroadcastMessage(b, chatID, nil))
+		}
+		switch {
+		case err == nil:
+			database.SetDeliveryStatus(db, id, chatID, database.DeliverySent, "")
+		case IsBlockedError(err):
+			database.SetDeliveryStatus(db, id, chatID, database.DeliveryBlocked, err.Error())
+			database.SetUserBlocked(db, chatID, true)
+		default:
+			slog.Warn("Ошибка доставки рассылки", "broadcast_id", id, "chat_id", chatID, "err", err)
+			database.SetDeliveryStatus(db, id, chatID, database.DeliveryFailed, err.Error())
+		}
+	}
+
+	if err := database.FinishBroadcast(db, id); err != nil {
+		slog.Error("Ошибка завершения рассылки", "broadcast_id", id, "err", err)
+	}
+	stats, _ := database.GetBroadcastStats(db, id)
+	bot.Send(tgbotapi.NewMessage(b.AuthorID, database.Tr(db, b.AuthorID, "broadcast.done",
+		id, stats.Sent, stats.Blocked, stats.Failed)))
+}
+
+// ResumeBroadcasts дорассылает объявления, прерванные перезапуском бота.
+func ResumeBroadcasts(bot *tgbotapi.BotAPI, db *sql.DB) {
+	ids, err := database.SendingBroadcasts(db)
+	if err != nil {
+		slog.Error("Ошибка поиска незавершённых рассылок", "err", err)
+		return
+	}
+	for _, id := range ids {
+		slog.Info("Продолжаем рассылку", "broadcast_id", id)
+		StartDelivery(bot, db, id)
+	}
+}
@@ -0,0 +1,104 @@
+package features
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/outbox"
+)
+
+// DigestDays — за сколько дней собирается еженедельный дайджест.
+const DigestDays = 7
+
+// WeeklyDigests рассылает еженедельные сводки: админам — по предприятию,
+// менеджерам — остаток бюджета, работникам — движение баланса.
+func WeeklyDigests(db *sql.DB) error {
+	recipients, err := database.ListDigestRecipients(db)
+	if err != nil {
+		return err
+	}
+
+	// Сводка предприятия одинакова для всех его админов с одним языком
+	type restKey struct {
+		rest int
+		lang string
+	}
+	restDigests := make(map[restKey]string)
+	sent, failed := 0, 0
+	for _, r := range recipients {
+		lang := database.UserLang(db, r.TelegramID)
+		var text string
+		switch r.AccessLevel {
+		case "admin":
+			key := restKey{r.RestNumber, lang}
+			if _, ok := restDigests[key]; !ok {
+				restDigests[key] = restDigestText(db, lang, r.RestNumber)
+			}
+			text = restDigests[key]
+		case "manager":
+			text = managerDigestText(db, lang, r.TelegramID)
+		default:
+			text = workerDigestText(db, lang, r.TelegramID)
+		}
+		if text == "" {
+			continue
+		}
+		if err := outbox.Send(db, r.TelegramID, text); err != nil {
+			failed++
+			continue
+		}
+		sent++
+	}
+	slog.Info("Еженедельный дайджест", "queued", sent, "failed", failed)
+	return nil
+}
+
+func restDigestText(db *sql.DB, lang string, restNumber int) string {
+	d, err := database.GetRestDigest(db, restNumber, DigestDays)
+	if err != nil {
+		slog.Error("Ошибка сбора дайджеста предприятия", "rest", restNumber, "err", err)
+		return ""
+	}
+	var text strings.Builder
+	text.WriteString(i18n.T(lang, "digest.rest_header", restNumber))
+	text.WriteString(i18n.N(lang, "digest.rest_issued", d.TopUps, d.StarsIssued))
+	text.WriteString(i18n.T(lang, "digest.rest_orders", d.OrdersAccepted, d.OrdersDenied, d.OrdersOpen))
+	if len(d.Top) > 0 {
+		text.WriteString(i18n.T(lang, "digest.rest_top"))
+		for i, p := range d.Top {
+			text.WriteString(fmt.Sprintf("%d. %s (%s) — %d🌟\n", i+1, p.Name, p.TableNumber, p.Stars))
+		}
+	}
+	return text.String()
+}
+
+func managerDigestText(db *sql.DB, lang string, managerID int64) string {
+	issued, err := database.IssuedByActor(db, managerID, DigestDays)
+	if err != nil {
+		slog.Error("Ошибка сбора дайджеста менеджера", "user_id", managerID, "err", err)
+		return ""
+	}
+	text := i18n.T(lang, "digest.manager_issued", issued)
+	left, budget, limited, err := database.ManagerBudgetLeft(db, managerID)
+	switch {
+	case err != nil:
+		slog.Error("Ошибка расчёта бюджета менеджера", "user_id", managerID, "err", err)
+	case limited:
+		text += i18n.T(lang, "digest.manager_budget", left, budget)
+	default:
+		text += i18n.T(lang, "digest.manager_unlimited")
+	}
+	return text
+}
+
+func workerDigestText(db *sql.DB, lang string, workerID int64) string {
+	d, err := database.GetWorkerDigest(db, workerID, DigestDays)
+	if err != nil {
+		slog.Error("Ошибка сбора дайджеста работника", "user_id", workerID, "err", err)
+		return ""
+	}
+	return i18n.T(lang, "digest.worker", d.Earned, d.Spent, d.Balance)
+}
@@ -0,0 +1,55 @@
+package features
+
+import (
+	"database/sql"
+	"log/slog"
+	"tbViT/database"
+	"tbViT/outbox"
+	"time"
+)
+
+// ProcessStarExpiry списывает звёзды старше срока, заданного в настройках предприятия,
+// и заранее предупреждает работников о ближайшем сгорании.
+func ProcessStarExpiry(db *sql.DB) error {
+	holders, err := database.ListBalanceHolders(db)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	months := make(map[int]int)
+	expired, warned := 0, 0
+	for _, h := range holders {
+		m, ok := months[h.RestNumber]
+		if !ok {
+			m = database.GetRestSetting(db, h.RestNumber, "stars_expire_months")
+			months[h.RestNumber] = m
+		}
+		if m == 0 {
+			continue
+		}
+
+		amount, err := database.ExpireStars(db, h.TelegramID, m, now)
+		if err != nil {
+			slog.Error("Ошибка списания сгоревших звёзд", "user_id", h.TelegramID, "err", err)
+			continue
+		}
+		if amount > 0 {
+			expired += amount
+			outbox.Send(db, h.TelegramID, database.TrN(db, h.TelegramID, "expiry.expired_notice", m, amount))
+		}
+
+		soon, until, err := database.ExpiringSoon(db, h.TelegramID, m, database.ExpiryWarnDays, now)
+		if err != nil {
+			slog.Error("Ошибка расчёта предупреждения о сгорании", "user_id", h.TelegramID, "err", err)
+			continue
+		}
+		if soon > 0 {
+			outbox.Send(db, h.TelegramID, database.TrN(db, h.TelegramID, "expiry.warn_notice", database.ExpiryWarnDays, soon))
+			warned++
+		}
+		database.SetExpiryWarned(db, h.TelegramID, until)
+	}
+	slog.Info("Сгорание звёзд", "expired", expired, "warned", warned)
+	return nil
+}
@@ -1,40 +1,71 @@
 package features
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"tbViT/i18n"
+	"tbViT/web"
+)
 
-// GenMainMenu генерирует основной инлайн-клавиатурный блок по роли пользователя
-func GenMainMenu(accessLevel string, userID, superUser int64) tgbotapi.InlineKeyboardMarkup {
+// GenMainMenu генерирует основной инлайн-клавиатурный блок по роли пользователя на языке lang
+func GenMainMenu(lang, accessLevel string, isSuper bool) tgbotapi.InlineKeyboardMarkup {
 	var kbRows [][]tgbotapi.InlineKeyboardButton
 	if accessLevel == "worker" {
 		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🌟 Баланс", "show_balance"),
-			tgbotapi.NewInlineKeyboardButtonData("🏪 Магазин", "menu_market"),
-			tgbotapi.NewInlineKeyboardButtonData("🛍 Заказы", "history_orders"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.balance"), "show_balance"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.shop"), "menu_market"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.orders"), "history_orders"),
+		))
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.balance_history"), "balance_history"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.leaderboard"), "leaderboard"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.profile"), "profile"),
+		))
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.kudos"), "kudos"),
 		))
 	}
 	if accessLevel == "manager" || accessLevel == "admin" {
 		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("💰 Начислить", "topup_"),
-			tgbotapi.NewInlineKeyboardButtonData("📋 Список", "menu_list"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.topup"), "topup_"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.list"), "menu_list"),
 		))
 	}
 	if accessLevel == "admin" {
 		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✏️Данные", "menu_admin_setbal"),
-			tgbotapi.NewInlineKeyboardButtonData("🏦️ Магазин", "shop_edit"),
-			tgbotapi.NewInlineKeyboardButtonData("❗️Доступ", "accesslevel"),
-			tgbotapi.NewInlineKeyboardButtonData("❇️Заказы", "orders"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.correction"), "menu_admin_setbal"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.shop_edit"), "shop_edit"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.access"), "accesslevel"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.admin_orders"), "orders"),
+		))
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.transfer"), "transfer"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.audit"), "audit"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.export"), "export"),
+		))
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.announcement"), "broadcast"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.kudos_queue"), "kudos_queue"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.settings"), "settings"),
+		))
+	}
+	if web.Enabled() && (accessLevel == "admin" || isSuper) {
+		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.web"), "web_login"),
 		))
 	}
-	if userID == superUser {
+	if isSuper {
 		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Переход", "super_user:transition"),
-			tgbotapi.NewInlineKeyboardButtonData("Доступ", "super_user:access"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.console"), "super_user:console"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.super_transition"), "super_user:transition"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.super_access"), "super_user:access"),
 		))
 	}
 
 	if len(kbRows) == 0 {
 		kbRows = [][]tgbotapi.InlineKeyboardButton{}
 	}
+	kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "lang.btn"), "lang"),
+	))
 	return tgbotapi.NewInlineKeyboardMarkup(kbRows...)
 }
@@ -1,107 +1,99 @@
 package features
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"log"
+	"log/slog"
 	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/service"
+	"tbViT/view"
 )
 
 var SentMessages = make(map[int64][]int)
 
 func ShowShop(bot *tgbotapi.BotAPI, db *sql.DB, chatID int64, userID int64) {
+	lang := database.UserLang(db, userID)
 	restID, _ := database.GetUserRestID(db, userID)
-	rows, err := db.Query(`SELECT id, product, price, remains FROM shop WHERE remains > 0 AND rest_number=?`, restID)
+	msg, err := shopPage(database.NewStore(db), lang, chatID, restID)
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка чтения магазина"))
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "shop.err_load")))
 		return
 	}
-	defer rows.Close()
-	var kbRows [][]tgbotapi.InlineKeyboardButton
-	text := "🛒 Доступные товары:\n"
-	for rows.Next() {
-		var id int
-		var product string
-		var price, remains int
-		rows.Scan(&id, &product, &price, &remains)
-		text += fmt.Sprintf("• %s — %d🌟 (%d шт.)\n", product, price, remains)
-		btn := tgbotapi.NewInlineKeyboardButtonData(
-			fmt.Sprintf("Купить %s (%d🌟)", product, price),
-			fmt.Sprintf("buy_product:%d", id),
-		)
-		kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(btn))
-	}
-	if len(kbRows) == 0 {
-		text = "😔 Товары закончились!"
-	}
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
 	bot.Send(msg)
 }
 
-func ShowShopEdit(bot *tgbotapi.BotAPI, adminID int64) {
+// shopPage — витрина предприятия restID: товары в наличии, прочитанные через
+// database.ProductRepository.
+func shopPage(products database.ProductRepository, lang string, chatID int64, restID int) (tgbotapi.MessageConfig, error) {
+	list, err := products.ListProducts(restID, true)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, err
+	}
+	return view.Shop(lang, chatID, list), nil
+}
+
+func ShowShopEdit(bot *tgbotapi.BotAPI, db *sql.DB, adminID int64) {
+	lang := database.UserLang(db, adminID)
 	buttons := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("📝 Отредактировать товар", "shop_edit:choose"),
-			tgbotapi.NewInlineKeyboardButtonData("➕ Добавить товар", "shop_edit:shop_add"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "shop.btn_edit"), "shop_edit:choose"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "shop.btn_add"), "shop_edit:shop_add"),
 		),
 	)
-	msg := tgbotapi.NewMessage(adminID, "Меню магазина:")
+	msg := tgbotapi.NewMessage(adminID, i18n.T(lang, "shop.menu"))
 	msg.ReplyMarkup = buttons
 	bot.Send(msg)
 }
 
 func ShowOrders(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64) {
-	buttons, _ := database.KeyboardOrders(db, fromID)
-	msg := tgbotapi.NewMessage(fromID, "Активные заказы:")
-	msg.ReplyMarkup = buttons
-	if len(buttons.InlineKeyboard) == 0 {
-		msg = tgbotapi.NewMessage(fromID, "В предприятии отсутствуют заказы")
+	lang := database.UserLang(db, fromID)
+	restID, err := database.GetUserRestID(db, fromID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "err.rest_lookup")))
+		return
+	}
+	orders, err := database.ListOpenOrders(db, restID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "orders.err_load")))
+		return
 	}
+	if len(orders) == 0 {
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "orders.none")))
+		return
+	}
+	msg := tgbotapi.NewMessage(fromID, i18n.T(lang, "orders.active"))
+	msg.ReplyMarkup = view.OrdersKeyboard(orders)
 	bot.Send(msg)
 }
 
 func AcceptOrders(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, orderID int) {
-	accept := fmt.Sprintf("orders_order:%d:accept", orderID)
-	deny := fmt.Sprintf("orders_order:%d:deny", orderID)
-	buttons := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("✅ Выполнить", accept),
-			tgbotapi.NewInlineKeyboardButtonData("❌ Отменить", deny),
-		),
-	)
-	num, name, product, price, err := database.GetOrderInfo(db, orderID)
+	lang := database.UserLang(db, fromID)
+	order, err := database.GetOrder(db, orderID)
 	if err != nil {
-		log.Printf("Ошибка получения информации о заказе и покупателе: %v", err)
+		slog.Error("Ошибка получения информации о заказе и покупателе", "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "orders.err_load")))
+		return
 	}
-	text := fmt.Sprintf("Заказ:\n%s %s\n%s %d🌟", num, name, product, price)
-	msg := tgbotapi.NewMessage(fromID, text)
-	msg.ReplyMarkup = buttons
-	bot.Send(msg)
+	bot.Send(view.OrderCard(lang, fromID, order))
 }
 
-func CompliteOrders(bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, orderID int, decision string) {
-	if decision == "accept" {
-		buyerID, product := database.CompleteOrder(db, orderID, decision)
-		if product == "complite" {
-			msgAdmin := tgbotapi.NewMessage(fromID, "Заказ уже был обработан! ⛔️")
-			bot.Send(msgAdmin)
-		} else {
-			msgBuyer := fmt.Sprintf("Заказ (%s) доставлен в офис.\nМожно забирать.", product)
-			msg := tgbotapi.NewMessage(buyerID, msgBuyer)
-			msgAdmin := tgbotapi.NewMessage(fromID, "Покупатель уведомлен о готовности заказа.✅")
-			bot.Send(msg)
-			bot.Send(msgAdmin)
-		}
-	}
-	if decision == "deny" {
-		buyerID, product := database.CompleteOrder(db, orderID, decision)
-		msgBuyer := fmt.Sprintf("Заказа (%s) отменен.\nПодробности у администратора магазина.", product)
-		msg := tgbotapi.NewMessage(buyerID, msgBuyer)
-		bot.Send(msg)
-		msgAdmin := tgbotapi.NewMessage(fromID, "Покупатель уведомлен об отмене заказа.❌")
-		bot.Send(msgAdmin)
+func CompliteOrders(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, fromID int64, orderID int, decision string) {
+	lang := database.UserLang(db, fromID)
+	_, err := service.DecideOrder(ctx, db, fromID, orderID, decision)
+	var ruleErr *i18n.Error
+	switch {
+	case errors.As(err, &ruleErr):
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.Err(lang, err)))
+	case err != nil:
+		slog.ErrorContext(ctx, "Ошибка обработки заказа", "order_id", orderID, "err", err)
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "orders.err_load")))
+	case decision == database.OrderAccepted:
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "orders.ready_ack")))
+	default:
+		bot.Send(tgbotapi.NewMessage(fromID, i18n.T(lang, "orders.denied_ack")))
 	}
 }
 
@@ -112,7 +104,7 @@ func DeleteAllBotMessages(bot *tgbotapi.BotAPI, chatID int64) {
 			MessageID: mID,
 		}
 		_, err := bot.Request(del)
-		log.Printf("deleteMessage chat=%d msg=%d err=%v", chatID, mID, err)
+		slog.Debug("Ошибка удаления сообщения", "chat_id", chatID, "message_id", mID, "err", err)
 	}
 	SentMessages[chatID] = nil
 }
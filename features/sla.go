@@ -0,0 +1,173 @@
+package features
+
+import (
+	"database/sql"
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/outbox"
+	"tbViT/view"
+)
+
+// noticeLine — строка напоминания, переводится отдельно для каждого получателя.
+type noticeLine struct {
+	key  string
+	args []interface{}
+}
+
+func renderLines(lang string, lines []noticeLine) string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = i18n.T(lang, l.key, l.args...)
+	}
+	return strings.Join(out, "\n")
+}
+
+// restNotice — напоминание админам одного предприятия.
+type restNotice struct {
+	lines     []noticeLine
+	orders    bool
+	approvals []database.StaleItem
+}
+
+// slaNotices — накопленные за проверку сообщения, чтобы не слать по одному на каждый заказ.
+type slaNotices struct {
+	admins map[int]*restNotice // по предприятиям
+	supers []noticeLine
+}
+
+func (n *slaNotices) admin(restNumber int) *restNotice {
+	if n.admins[restNumber] == nil {
+		n.admins[restNumber] = &restNotice{}
+	}
+	return n.admins[restNumber]
+}
+
+// CheckSLA проверяет сроки заказов в сборке и заявок на регистрацию по настройкам
+// предприятия: напоминает админам, передаёт просроченное суперпользователям и при
+// включённой автоотмене отменяет заказ с возвратом звёзд.
+func CheckSLA(db *sql.DB) error {
+	notices := &slaNotices{admins: make(map[int]*restNotice)}
+	if err := checkOrdersSLA(db, notices); err != nil {
+		return err
+	}
+	if err := checkApprovalsSLA(db, notices); err != nil {
+		return err
+	}
+
+	for restNumber, n := range notices.admins {
+		adminIDs, err := database.GetRestAdminIDs(db, restNumber)
+		if err != nil {
+			slog.Error("Ошибка поиска админов предприятия для напоминания", "rest", restNumber, "err", err)
+			continue
+		}
+		for _, adminID := range adminIDs {
+			lang := database.UserLang(db, adminID)
+			var kbRows [][]tgbotapi.InlineKeyboardButton
+			if n.orders {
+				kbRows = append(kbRows, tgbotapi.NewInlineKeyboardRow(
+					tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "menu.admin_orders"), "orders"),
+				))
+			}
+			for _, p := range n.approvals {
+				kbRows = append(kbRows, view.ApprovalRow(lang, p.ID, p.Name))
+			}
+			msg := tgbotapi.NewMessage(adminID, i18n.T(lang, "sla.admin_header")+renderLines(lang, n.lines))
+			if len(kbRows) > 0 {
+				msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(kbRows...)
+			}
+			outbox.Enqueue(db, msg)
+		}
+	}
+
+	if len(notices.supers) > 0 {
+		supers, err := database.ListSuperUsers(db)
+		if err != nil {
+			return err
+		}
+		for _, su := range supers {
+			lang := database.UserLang(db, su.TelegramID)
+			outbox.Send(db, su.TelegramID, i18n.T(lang, "sla.super_header")+renderLines(lang, notices.supers))
+		}
+	}
+	return nil
+}
+
+func checkOrdersSLA(db *sql.DB, notices *slaNotices) error {
+	orders, err := database.OpenOrdersAging(db)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		remind := database.GetRestSetting(db, o.RestNumber, "order_remind_hours")
+		escalate := database.GetRestSetting(db, o.RestNumber, "order_escalate_hours")
+		autocancel := database.GetRestSetting(db, o.RestNumber, "order_autocancel_hours")
+		args := []interface{}{o.ID, o.Product, o.Name, o.Table, o.AgeHours}
+
+		switch {
+		case autocancel > 0 && o.AgeHours >= float64(autocancel):
+			if autoCancelOrder(db, o) {
+				n := notices.admin(o.RestNumber)
+				n.lines = append(n.lines, noticeLine{"sla.order_autocancelled", args})
+			}
+		case escalate > 0 && o.AgeHours >= float64(escalate) && o.Stage < database.SLAStageEscalated:
+			notices.supers = append(notices.supers, noticeLine{"sla.super_order", append([]interface{}{o.RestNumber}, args...)})
+			database.SetOrderSLAStage(db, o.ID, database.SLAStageEscalated)
+		case remind > 0 && o.AgeHours >= float64(remind) && o.Stage < database.SLAStageReminded:
+			n := notices.admin(o.RestNumber)
+			n.lines = append(n.lines, noticeLine{"sla.order", args})
+			n.orders = true
+			database.SetOrderSLAStage(db, o.ID, database.SLAStageReminded)
+		}
+	}
+	return nil
+}
+
+func autoCancelOrder(db *sql.DB, o database.StaleItem) bool {
+	buyerID, product, err := database.CompleteOrder(db, int(o.ID), database.OrderDenied)
+	if errors.Is(err, database.ErrOrderDecided) {
+		// Админ успел выдать или отменить заказ, пока шла проверка
+		return false
+	}
+	if err != nil {
+		slog.Error("Ошибка автоотмены заказа", "order_id", o.ID, "err", err)
+		return false
+	}
+	database.Audit(db, database.AuditEntry{
+		RestNumber: o.RestNumber,
+		Action:     "order_autocancel",
+		Target:     strconv.FormatInt(o.ID, 10),
+		Before:     "в сборке",
+		After:      "deny",
+	})
+	outbox.Send(db, buyerID, database.Tr(db, buyerID, "sla.autocancel_notice", product, o.Price))
+	return true
+}
+
+func checkApprovalsSLA(db *sql.DB, notices *slaNotices) error {
+	pending, err := database.PendingApprovalsAging(db)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		remind := database.GetRestSetting(db, p.RestNumber, "approval_remind_hours")
+		escalate := database.GetRestSetting(db, p.RestNumber, "approval_escalate_hours")
+		args := []interface{}{p.Name, p.Table, p.ID, p.AgeHours}
+
+		switch {
+		case escalate > 0 && p.AgeHours >= float64(escalate) && p.Stage < database.SLAStageEscalated:
+			notices.supers = append(notices.supers, noticeLine{"sla.super_approval", append([]interface{}{p.RestNumber}, args...)})
+			database.SetApprovalSLAStage(db, p.ID, database.SLAStageEscalated)
+		case remind > 0 && p.AgeHours >= float64(remind) && p.Stage < database.SLAStageReminded:
+			n := notices.admin(p.RestNumber)
+			n.lines = append(n.lines, noticeLine{"sla.approval", args})
+			n.approvals = append(n.approvals, p)
+			database.SetApprovalSLAStage(db, p.ID, database.SLAStageReminded)
+		}
+	}
+	return nil
+}
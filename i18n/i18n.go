@@ -0,0 +1,220 @@
+// Package i18n — каталог текстов бота. Тексты лежат в locales/<язык>.json по ключам;
+// значение — строка формата fmt или объект с формами множественного числа
+// (ru: one/few/many, en: one/other).
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Default — язык по умолчанию и запасной, если в другом языке нет ключа.
+const Default = "ru"
+
+// Langs — поддерживаемые языки в порядке показа в меню выбора.
+var Langs = []string{"ru", "en"}
+
+// LangNames — подписи языков в меню выбора.
+var LangNames = map[string]string{
+	"ru": "🇷🇺 Русский",
+	"en": "🇬🇧 English",
+}
+
+// pluralForms — обязательные формы множественного числа по языкам.
+var pluralForms = map[string][]string{
+	"ru": {"one", "few", "many"},
+	"en": {"one", "other"},
+}
+
+//go:embed locales/*.json
+var files embed.FS
+
+// message — текст или набор форм множественного числа.
+type message struct {
+	Text  string
+	Forms map[string]string
+}
+
+func (m *message) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &m.Text); err == nil {
+		return nil
+	}
+	return json.Unmarshal(b, &m.Forms)
+}
+
+var catalogs = make(map[string]map[string]message)
+
+func init() {
+	for _, lang := range Langs {
+		raw, err := files.ReadFile(path.Join("locales", lang+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: нет каталога %s: %v", lang, err))
+		}
+		c := make(map[string]message)
+		if err := json.Unmarshal(raw, &c); err != nil {
+			panic(fmt.Sprintf("i18n: ошибка разбора каталога %s: %v", lang, err))
+		}
+		catalogs[lang] = c
+	}
+}
+
+// Normalize приводит language_code Telegram ("en-US", "ru") к поддерживаемому языку.
+func Normalize(code string) string {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	if _, ok := catalogs[code]; ok {
+		return code
+	}
+	return Default
+}
+
+func lookup(lang, key string) (message, bool) {
+	if m, ok := catalogs[lang][key]; ok {
+		return m, true
+	}
+	m, ok := catalogs[Default][key]
+	if !ok {
+		slog.Warn("i18n: нет ключа", "key", key)
+	}
+	return m, ok
+}
+
+func format(s string, args []interface{}) string {
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+// T возвращает текст по ключу на языке lang, подставляя args как в fmt.Sprintf.
+// Если ключа нет — текст языка по умолчанию, если нет и там — сам ключ.
+func T(lang, key string, args ...interface{}) string {
+	m, ok := lookup(lang, key)
+	if !ok {
+		return key
+	}
+	if m.Text == "" && m.Forms != nil {
+		return N(lang, key, 0, args...)
+	}
+	return format(m.Text, args)
+}
+
+// N возвращает текст с формой множественного числа для n. Первым аргументом
+// форматирования идёт n, за ним args.
+func N(lang, key string, n int, args ...interface{}) string {
+	m, ok := lookup(lang, key)
+	if !ok {
+		return key
+	}
+	args = append([]interface{}{n}, args...)
+	if m.Forms == nil {
+		return format(m.Text, args)
+	}
+	if _, ok := catalogs[lang][key]; !ok {
+		lang = Default
+	}
+	s, ok := m.Forms[pluralForm(lang, n)]
+	if !ok {
+		s = m.Forms["other"]
+	}
+	return format(s, args)
+}
+
+// pluralForm — форма множественного числа по правилам CLDR для целых чисел.
+func pluralForm(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	switch lang {
+	case "ru":
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		}
+		return "many"
+	}
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// Has — есть ли ключ в каталоге языка по умолчанию.
+func Has(key string) bool {
+	_, ok := catalogs[Default][key]
+	return ok
+}
+
+// HasPrefix сообщает, есть ли в каталоге ключи, начинающиеся с prefix
+// (для ключей, составляемых в коде, вроде "role."+role).
+func HasPrefix(prefix string) bool {
+	for key := range catalogs[Default] {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing возвращает проблемы каталогов: ключи, которых нет в каком-то языке,
+// и ключи с неполным набором форм множественного числа.
+func Missing() []string {
+	all := make(map[string]bool)
+	for _, c := range catalogs {
+		for key := range c {
+			all[key] = true
+		}
+	}
+	var problems []string
+	for _, lang := range Langs {
+		for key := range all {
+			m, ok := catalogs[lang][key]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: нет ключа %q", lang, key))
+				continue
+			}
+			if m.Forms == nil {
+				continue
+			}
+			for _, form := range pluralForms[lang] {
+				if _, ok := m.Forms[form]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: у ключа %q нет формы %q", lang, key, form))
+				}
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// Error — ошибка с текстом из каталога, чтобы показать её пользователю на его языке.
+type Error struct {
+	Key  string
+	Args []interface{}
+}
+
+func (e *Error) Error() string {
+	return T(Default, e.Key, e.Args...)
+}
+
+// NewError создаёт ошибку с текстом по ключу каталога.
+func NewError(key string, args ...interface{}) error {
+	return &Error{Key: key, Args: args}
+}
+
+// Err — текст ошибки на языке lang: для ошибок каталога перевод, для прочих err.Error().
+func Err(lang string, err error) string {
+	if e, ok := err.(*Error); ok {
+		return T(lang, e.Key, e.Args...)
+	}
+	return err.Error()
+}
@@ -1,246 +1,567 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
-	"log"
-	_ "modernc.org/sqlite"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
 	"strconv"
+	"strings"
+	"syscall"
+	"tbViT/api"
+	"tbViT/backup"
 	"tbViT/callback"
+	"tbViT/config"
 	"tbViT/database"
 	"tbViT/features"
+	"tbViT/i18n"
+	"tbViT/logging"
+	"tbViT/metrics"
+	"tbViT/outbox"
+	"tbViT/scheduler"
+	"tbViT/service"
 	"tbViT/stepreg"
+	"tbViT/web"
+	"time"
 )
 
-var (
-	userCommands = []tgbotapi.BotCommand{
-		{Command: "menu", Description: "Меню"},
+// userCommands — команды бота на языке lang.
+func userCommands(lang string) []tgbotapi.BotCommand {
+	return []tgbotapi.BotCommand{
+		{Command: "menu", Description: i18n.T(lang, "cmd.menu")},
 	}
-)
+}
+
+// updatesChannel подключается к Telegram в режиме из настроек: long polling или
+// вебхук, обработчик которого регистрируется в mux. После отмены stop вебхук
+// отвечает 503, и Telegram повторит обновление уже новому процессу.
+func updatesChannel(stop context.Context, bot *tgbotapi.BotAPI, tg config.Telegram, mux *http.ServeMux) (tgbotapi.UpdatesChannel, error) {
+	if tg.Mode != config.ModeWebhook {
+		// Пока вебхук установлен, getUpdates не работает
+		if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			return nil, err
+		}
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = tg.PollTimeout
+		return bot.GetUpdatesChan(u), nil
+	}
+
+	wh, err := tgbotapi.NewWebhook(tg.WebhookURL)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := bot.Request(wh); err != nil {
+		return nil, err
+	}
+	path := wh.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	updates := make(chan tgbotapi.Update, bot.Buffer)
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		update, err := bot.HandleUpdate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		select {
+		case updates <- *update:
+		case <-stop.Done():
+			http.Error(w, "бот останавливается", http.StatusServiceUnavailable)
+		}
+	})
+	return updates, nil
+}
+
+// updateType — тип обновления для метрики tbvit_updates_total.
+func updateType(update tgbotapi.Update) string {
+	switch {
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.Message != nil && update.Message.IsCommand():
+		return "command"
+	case update.Message != nil:
+		return "message"
+	}
+	return "other"
+}
 
 var userState = make(map[int64]*callback.CorrectionState)
 var shopState = make(map[int64]*callback.CorrectionState)
 
 func main() {
 
-	err := godotenv.Load()
-	botToken := os.Getenv("TOCKEN")
-	if botToken == "" {
-		panic("Missing token")
-	}
-	superUser, err := strconv.ParseInt(os.Getenv("TELEGRAM_SUPER_USER"), 10, 64)
+	godotenv.Load()
+	logging.Setup(os.Stderr, "info")
+	// Настройки: config.yaml (или CONFIG_FILE), поверх — переменные окружения
+	cfg, err := config.Load("")
 	if err != nil {
-		panic("not valid superUser")
+		logging.Fatal("Ошибка чтения конфигурации", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		logging.Fatal("Ошибка конфигурации", err)
+	}
+	cfg.Apply()
+	logging.Setup(os.Stderr, cfg.LogLevel)
+	tgbotapi.SetLogger(logging.BotLogger{})
 
-	db, err := sql.Open("sqlite", "botdata.db")
+	db, err := database.Open(cfg.DB.Driver, cfg.DB.DSN)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("Ошибка подключения к БД", err)
 	}
-	defer db.Close()
 
-	// --- Настройки SQLite для надежности и производительности ---
-	// Увеличивает время ожидания при блокировке базы данных (в миллисекундах)
-	// Это позволяет SQLite подождать, пока блокировка не снимется, вместо немедленного возврата ошибки.
-	_, err = db.Exec("PRAGMA busy_timeout = 10000;") // 10 секунд ожидания
-	if err != nil {
-		log.Printf("Warning: Failed to set busy_timeout: %v", err)
+	if err := database.Migrate(db, cfg.DB.Driver); err != nil {
+		logging.Fatal("Ошибка миграции схемы", err)
 	}
 
-	// Включает режим WAL (Write-Ahead Logging)
-	// WAL улучшает производительность одновременного доступа (чтение/запись) и надежность.
-	_, err = db.Exec("PRAGMA journal_mode=WAL;")
-	if err != nil {
-		log.Printf("Warning: Failed to set journal_mode=WAL: %v", err)
+	if err = database.SeedSuperUsers(db, cfg.SuperUsers); err != nil {
+		logging.Fatal("Ошибка записи суперпользователей", err)
 	}
 
-	// Устанавливает режим синхронизации на FULL
-	// Это гарантирует, что данные будут записаны на диск перед тем, как операция COMMIT будет считаться завершенной.
-	// Повышает надежность ценой некоторого снижения скорости записи.
-	_, err = db.Exec("PRAGMA synchronous = FULL;")
-	if err != nil {
-		log.Printf("Warning: Failed to set synchronous=FULL: %v", err)
-	}
-
-	// Создаём таблицу
-	db.Exec(`CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		telegram_id INTEGER UNIQUE NOT NULL,
-		username TEXT,
-		name TEXT,
-		table_number TEXT,
-		rest_number INTEGER,
-		access_level TEXT, 
-		verified INTEGER,
-		reg_state TEXT,
-        current_balance INTEGER,
-        all_time_balance INTEGER,
-        last_ts INTEGER,
-        tmp_field INTEGER,
-        special_roll TEXT, 
-         registration_start_time DATETIME                        
-	)`)
-
-	db.Exec(`CREATE TABLE IF NOT EXISTS shop (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		product TEXT NOT NULL,
-		price INTEGER NOT NULL,
-		remains INTEGER,
-		rest_number INTEGER                        
-	)`)
-
-	db.Exec(`CREATE TABLE IF NOT EXISTS orders (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		telegram_id INTEGER NOT NULL,
-		product_name TEXT,  
-		status TEXT, 
-		product_id  INTEGER, 
-		rest_number INTEGER ,
-		price INTEGER,  
-		created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP           
-	)`)
-
-	bot, err := tgbotapi.NewBotAPI(botToken)
+	// Заработок за всё время раньше не вёлся — досчитываем по истории операций
+	if err := database.BackfillAllTimeBalance(db); err != nil {
+		slog.Error("Ошибка пересчёта all_time_balance", "err", err)
+	}
+
+	bot, err := tgbotapi.NewBotAPIWithClient(cfg.Token, tgbotapi.APIEndpoint, metrics.TelegramClient{Client: &http.Client{}})
 	if err != nil {
-		log.Panic(err)
+		logging.Fatal("Ошибка подключения к Telegram", err)
+	}
+	bot.Debug = logging.Debug()
+
+	// Исходящая очередь уведомлений
+	sender := outbox.Start(bot, db)
+
+	// Фоновые задачи
+	sched := scheduler.New(db)
+	if err := sched.Add("weekly_digest", "0 9 * * 1", func() error {
+		return features.WeeklyDigests(db)
+	}); err != nil {
+		logging.Fatal("Ошибка регистрации задачи", err)
+	}
+	if err := sched.Add("sla_check", "*/15 * * * *", func() error {
+		return features.CheckSLA(db)
+	}); err != nil {
+		logging.Fatal("Ошибка регистрации задачи", err)
+	}
+	if err := sched.Add("outbox_purge", "30 4 * * *", func() error {
+		return database.PurgeSentOutbox(db, 30)
+	}); err != nil {
+		logging.Fatal("Ошибка регистрации задачи", err)
+	}
+	if err := sched.Add("stars_expiry", "0 3 * * *", func() error {
+		return features.ProcessStarExpiry(db)
+	}); err != nil {
+		logging.Fatal("Ошибка регистрации задачи", err)
+	}
+	// Резервные копии снимаются только с SQLite; PostgreSQL копируется своими средствами (pg_dump)
+	if driver, _ := database.NormalizeDriver(cfg.DB.Driver); driver == database.DriverSQLite {
+		if err := sched.Add("backup", "0 2 * * *", func() error {
+			_, err := backup.Create(db)
+			return err
+		}); err != nil {
+			logging.Fatal("Ошибка регистрации задачи", err)
+		}
+	}
+	sched.Start()
+	features.ResumeBroadcasts(bot, db)
+
+	// HTTP-сервер: вебхук и проверки состояния
+	mux := http.NewServeMux()
+	health := metrics.Health{DB: db}
+	if cfg.Telegram.Mode == config.ModePolling {
+		health.PollStale = 2*time.Duration(cfg.Telegram.PollTimeout)*time.Second + 30*time.Second
+	}
+	health.Register(mux)
+	if cfg.HTTP.API {
+		api.Server{DB: db}.Register(mux)
 	}
-	bot.Debug = true
+	if cfg.HTTP.Dashboard {
+		web.Server{DB: db}.Register(mux)
+	}
+	metrics.NewGaugeFunc("tbvit_orders_open", "Заказы в сборке по предприятиям.", []string{"rest"},
+		func(set func(value float64, labelValues ...string)) error {
+			counts, err := database.OpenOrdersByRest(db)
+			for rest, n := range counts {
+				set(float64(n), strconv.Itoa(rest))
+			}
+			return err
+		})
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+	// SIGTERM (docker stop) и Ctrl+C останавливают бот штатно
+	stop, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
 
-	_, err = bot.Request(tgbotapi.NewSetMyCommands(userCommands...))
+	updates, err := updatesChannel(stop, bot, cfg.Telegram, mux)
 	if err != nil {
-		log.Println("Ошибка установки меню user:", err)
+		logging.Fatal("Ошибка подключения к Telegram", err)
+	}
+	srv := &http.Server{Addr: cfg.HTTP.Listen, Handler: mux}
+	if cfg.HTTP.Listen != "" {
+		go func() {
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logging.Fatal("Ошибка HTTP-сервера", err)
+			}
+		}()
+	}
+	// Метрики — на отдельном адресе, недоступном снаружи
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+	metricsSrv := &http.Server{Addr: cfg.HTTP.MetricsListen, Handler: metricsMux}
+	if cfg.HTTP.MetricsListen != "" {
+		go func() {
+			if err := metricsSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logging.Fatal("Ошибка HTTP-сервера метрик", err)
+			}
+		}()
+	}
+
+	_, err = bot.Request(tgbotapi.NewSetMyCommands(userCommands(i18n.Default)...))
+	if err != nil {
+		slog.Error("Ошибка установки меню user", "err", err)
+	}
+	for _, lang := range i18n.Langs {
+		_, err = bot.Request(tgbotapi.NewSetMyCommandsWithScopeAndLanguage(
+			tgbotapi.NewBotCommandScopeDefault(), lang, userCommands(lang)...))
+		if err != nil {
+			slog.Error("Ошибка установки меню user", "lang", lang, "err", err)
+		}
 	}
 
+	metrics.SetReady(true)
+	// Контекст обработчиков отменяется, только если они не уложились в shutdown_timeout:
+	// тогда текущее обновление дорабатывается, а остальные из буфера пропускаются
+	work, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+	loopDone := make(chan struct{})
 	// Главный цикл получения и обработки событий
-	for update := range updates {
-		// (1) Регистрация пользователей
-		if stepreg.RegistrationHandler(bot, db, update) {
-			continue
+	go func() {
+		defer close(loopDone)
+		for {
+			select {
+			case <-stop.Done():
+				// Обновления из буфера Telegram уже считает доставленными — обрабатываем их
+				for {
+					select {
+					case update, ok := <-updates:
+						if !ok || work.Err() != nil {
+							return
+						}
+						processUpdate(work, bot, db, update)
+					default:
+						return
+					}
+				}
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				processUpdate(work, bot, db, update)
+			}
+		}
+	}()
+
+	<-stop.Done()
+	stopSignals()
+	slog.Info("Остановка бота", "timeout_s", cfg.ShutdownTimeout)
+	metrics.SetReady(false)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if cfg.Telegram.Mode == config.ModePolling {
+		bot.StopReceivingUpdates()
+	}
+	select {
+	case <-loopDone:
+	case <-ctx.Done():
+		// Обработчики не прерываются на середине: база закрывается только после них
+		slog.Warn("Обработка обновлений не завершилась за отведённое время, дожидаемся текущего обновления")
+		cancelWork()
+		<-loopDone
+	}
+	if err := sched.Stop(ctx); err != nil {
+		slog.Warn("Фоновая задача не завершилась за отведённое время, дожидаемся её", "err", err)
+		sched.Wait()
+	}
+	if err := features.StopBroadcasts(ctx); err != nil {
+		slog.Warn("Рассылка не прервалась за отведённое время, дожидаемся её", "err", err)
+		features.WaitBroadcasts()
+	}
+	if err := sender.Stop(ctx); err != nil {
+		slog.Warn("Очередь уведомлений отправлена не полностью, остаток уйдёт после запуска", "err", err)
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("Ошибка остановки HTTP-сервера", "err", err)
+	}
+	if err := metricsSrv.Shutdown(ctx); err != nil {
+		slog.Warn("Ошибка остановки HTTP-сервера метрик", "err", err)
+	}
+	if err := db.Close(); err != nil {
+		slog.Error("Ошибка закрытия БД", "err", err)
+	}
+	slog.Info("Бот остановлен")
+}
+
+// processUpdate обрабатывает обновление в главном цикле с отдельным cid.
+func processUpdate(work context.Context, bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Update) {
+	metrics.Updates.Inc(updateType(update))
+	// Все записи журнала об этом обновлении помечаются одним cid
+	ctx := logging.WithID(work, logging.NewID())
+	slog.DebugContext(ctx, "Обновление", "update_id", update.UpdateID)
+	handleUpdate(ctx, bot, db, update)
+}
+
+// recoverUpdate перехватывает панику обработчика: пишет стек в журнал, отвечает
+// на нажатие кнопки сообщением об ошибке, и бот продолжает работу.
+func recoverUpdate(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Update) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	route := updateType(update)
+	if cq := update.CallbackQuery; cq != nil {
+		route = callback.Route(cq.Data)
+	}
+	metrics.Panics.Inc(route)
+	slog.ErrorContext(ctx, "Паника в обработчике обновления", "route", route,
+		"panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+
+	from := update.SentFrom()
+	if from == nil {
+		return
+	}
+	text := i18n.T(database.UserLang(db, from.ID), "err.internal")
+	if cq := update.CallbackQuery; cq != nil {
+		if _, err := bot.Request(tgbotapi.NewCallbackWithAlert(cq.ID, text)); err == nil {
+			return
 		}
+	}
+	bot.Send(tgbotapi.NewMessage(from.ID, text))
+}
 
-		//обработка команды меню
-		if update.Message != nil && (update.Message.Text == "/menu" || update.Message.Text == "меню") {
-			userID := update.Message.From.ID
+// handleUpdate обрабатывает одно обновление: регистрацию, меню, нажатия кнопок
+// и ввод в ожидаемых состояниях.
+func handleUpdate(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Update) {
+	metrics.UpdateStarted()
+	defer metrics.UpdateDone()
+	defer recoverUpdate(ctx, bot, db, update)
 
-			features.DeleteAllBotMessages(bot, userID)
+	// Язык по умолчанию — из настроек Telegram, пока пользователь не выбрал свой
+	if from := update.SentFrom(); from != nil {
+		database.InitUserLang(db, from.ID, from.LanguageCode)
+	}
 
-			accessLevel, err := database.GetAccessLevel(db, userID)
-			if err != nil {
-				log.Println("Access Error:", err)
-			}
+	// (1) Регистрация пользователей
+	if stepreg.RegistrationHandler(ctx, bot, db, update) {
+		return
+	}
 
-			menuMarkup := features.GenMainMenu(accessLevel, userID, superUser)
-			response := tgbotapi.NewMessage(userID, "Ваше меню:")
-			response.ReplyMarkup = menuMarkup
+	//обработка команды меню
+	if update.Message != nil && (update.Message.Text == "/menu" || update.Message.Text == "меню") {
+		userID := update.Message.From.ID
 
-			sent, err := bot.Send(response)
-			if err == nil {
-				features.SentMessages[userID] = []int{sent.MessageID}
-			}
-			continue
+		features.DeleteAllBotMessages(bot, userID)
+
+		accessLevel, err := database.GetAccessLevel(db, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка чтения уровня доступа", "user_id", userID, "err", err)
 		}
 
-		if update.CallbackQuery != nil {
-			callback.HandleCallback(bot, db, update.CallbackQuery, userState, shopState, superUser)
-			continue
+		lang := database.UserLang(db, userID)
+		isSuper := database.IsSuperUser(db, userID)
+		menuMarkup := features.GenMainMenu(lang, accessLevel, isSuper)
+		menuText := i18n.T(lang, "menu.title")
+		if !isSuper && database.IsUserRestFrozen(db, userID) {
+			menuMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "lang.btn"), "lang"),
+			))
+			menuText = i18n.T(lang, "rest.frozen")
 		}
+		response := tgbotapi.NewMessage(userID, menuText)
+		response.ReplyMarkup = menuMarkup
 
-		if update.Message != nil {
-			userID := update.Message.From.ID
-			text := update.Message.Text
+		sent, err := bot.Send(response)
+		if err == nil {
+			features.SentMessages[userID] = []int{sent.MessageID}
+		}
+		return
+	}
 
-			if st, ok := shopState[userID]; ok {
-				switch st.Field {
-				case "wait_new_price", "wait_new_remains", "wait_new_product_name",
-					"wait_new_product_price", "wait_new_product_remains":
-					log.Println("HandleShopMessage called for user:", userID, "field:", st.Field)
-					callback.HandleShopMessage(bot, db, update.Message, shopState)
-					continue
-				}
+	if update.CallbackQuery != nil {
+		start := time.Now()
+		callback.HandleCallback(ctx, bot, db, update.CallbackQuery, userState, shopState)
+		metrics.CallbackDuration.Observe(time.Since(start).Seconds(), callback.Route(update.CallbackQuery.Data))
+		return
+	}
+
+	if update.Message != nil {
+		userID := update.Message.From.ID
+		text := update.Message.Text
+		lang := database.UserLang(db, userID)
+		// Пишет боту — значит, не заблокировал его
+		database.SetUserBlocked(db, userID, false)
+
+		if st, ok := shopState[userID]; ok {
+			switch st.Field {
+			case "wait_new_price", "wait_new_remains", "wait_new_product_name",
+				"wait_new_product_price", "wait_new_product_remains":
+				slog.DebugContext(ctx, "Ввод для редактирования магазина", "user_id", userID, "field", st.Field)
+				callback.HandleShopMessage(ctx, bot, db, update.Message, shopState)
+				return
 			}
+		}
 
-			state, ok := userState[userID]
+		state, ok := userState[userID]
 
-			if ok && (state.Field == "super_user:wait_rest_number" || state.Field == "super_user:wait_access_level") && userID == superUser {
-				switch state.Field {
-				case "super_user:wait_rest_number":
-					restNumber, err := strconv.Atoi(text)
-					if err != nil {
-						bot.Send(tgbotapi.NewMessage(userID, "❌ Введи корректный номер предприятия (целое число)!"))
-					} else {
-						err = database.UpdateRest(db, userID, text)
-						if err != nil {
-							bot.Send(tgbotapi.NewMessage(userID, "Ошибка super_user:transition!"))
-						} else {
-							bot.Send(tgbotapi.NewMessage(userID, "Номер нового предприятия: "+strconv.Itoa(restNumber)))
-						}
-						delete(userState, userID)
-						continue
-					}
-				case "super_user:wait_access_level":
-					err = database.ChangeAccess(db, userID, text)
+		if ok && strings.HasPrefix(state.Field, "super_user:") && database.IsSuperUser(db, userID) {
+			switch state.Field {
+			case "super_user:wait_rest_number":
+				restNumber, err := strconv.Atoi(text)
+				if err != nil || restNumber <= 0 {
+					bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "err.rest_number")))
+				} else {
+					oldRest, _ := database.GetUserDep(db, userID)
+					err = database.UpdateRest(db, userID, strconv.Itoa(restNumber))
 					if err != nil {
-						bot.Send(tgbotapi.NewMessage(userID, "Ошибка super_user:access!"))
+						bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "super.err_transition")))
 					} else {
-						bot.Send(tgbotapi.NewMessage(userID, "Текущий уровень: "+text))
+						database.Audit(db, database.AuditEntry{ActorID: userID, RestNumber: restNumber,
+							Action: "super_user:transition", Target: strconv.FormatInt(userID, 10),
+							Before: oldRest, After: strconv.Itoa(restNumber)})
+						bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "super.transition_done", restNumber)))
 					}
 					delete(userState, userID)
-					continue
+					return
 				}
+			case "super_user:wait_search":
+				callback.HandleSuperSearch(bot, db, userID, text)
+				delete(userState, userID)
+				return
+			case "super_user:wait_super_id":
+				callback.HandleSuperAdd(bot, db, userID, text)
+				delete(userState, userID)
+				return
+			case "super_user:wait_job_spec":
+				callback.HandleJobSpec(bot, db, userID, state.Value, text)
+				delete(userState, userID)
+				return
 			}
+		}
 
-			if ok && state.Field == "wait_table_number" {
-				desiredRole := state.Value // worker/manager/admin
-				tableNumber := text
-
-				// если роль admin, спрашиваем подтверждение
-				if desiredRole == "admin" {
-
-					confirmMarkup := tgbotapi.NewInlineKeyboardMarkup(
-						tgbotapi.NewInlineKeyboardRow(
-							tgbotapi.NewInlineKeyboardButtonData("✅ Да, я уверен", "confirmAdmin:"+tableNumber),
-							tgbotapi.NewInlineKeyboardButtonData("❌ Нет", "cancelAdmin"),
-						),
-					)
-					info := fmt.Sprintf("\nВы выбрали сотрудника с номером %s.\nУверенны, что хотите сделать этого человека администратором?",
-						tableNumber)
-					msg := tgbotapi.NewMessage(userID, info)
-					msg.ReplyMarkup = confirmMarkup
-					bot.Send(msg)
-					// Можно сохранить tableNumber и роль во временном state
-					state.Field = "wait_confirm_admin"
-					state.Value = tableNumber
-					continue
-				}
+		if ok && state.Field == "broadcast:wait_content" {
+			callback.HandleBroadcastContent(ctx, bot, db, update.Message, state.Value)
+			delete(userState, userID)
+			return
+		}
 
-				//Для worker/manager — сразу применяем
-				err := database.ChangeRole(db, userID, tableNumber, desiredRole)
-				if err != nil {
-					bot.Send(tgbotapi.NewMessage(userID, "❌ Ошибка изменения роли: "+err.Error()))
-				} else {
-					bot.Send(tgbotapi.NewMessage(userID, "✅ Роль успешно изменена!"))
-				}
+		if ok && state.Field == "kudos:wait_message" {
+			callback.HandleKudosMessage(ctx, bot, db, userID, state.ID, update.Message.Text)
+			delete(userState, userID)
+			return
+		}
+
+		if ok && state.Field == "settings:wait_value" {
+			callback.HandleSettingValue(ctx, bot, db, userID, state.Value, text)
+			delete(userState, userID)
+			return
+		}
+
+		if ok && state.Field == "export:wait_range" {
+			callback.HandleExportRange(ctx, bot, db, userID, state.Value, text)
+			delete(userState, userID)
+			return
+		}
+
+		if ok && state.Field == "audit:wait_filter" {
+			callback.HandleAuditFilter(ctx, bot, db, userID, text)
+			delete(userState, userID)
+			return
+		}
+
+		if ok && state.Field == "transfer:wait_rest" {
+			toRest, err := strconv.Atoi(text)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "err.rest_number")))
+				return
+			}
+			ownRest, _ := database.GetUserRestID(db, userID)
+			if toRest == ownRest {
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "transfer.same_rest")))
+				return
+			}
+			if frozen, _ := database.IsRestFrozen(db, toRest); frozen {
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "transfer.dest_frozen")))
+				delete(userState, userID)
+				return
+			}
+			if admins, err := database.GetRestAdminIDs(db, toRest); err != nil || len(admins) == 0 {
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "transfer.dest_no_admin")))
 				delete(userState, userID)
-				continue
+				return
 			}
+			state.Field = "transfer:wait_mode"
+			state.Value = text
+			msg := tgbotapi.NewMessage(userID, i18n.T(lang, "transfer.ask_mode", toRest))
+			msg.ReplyMarkup = callback.TransferBalanceModeMarkup(lang)
+			bot.Send(msg)
+			return
+		}
 
-			if ok && (state.Field == "balance" || state.Field == "name" || state.Field == "tablenumber" || state.Field == "delete") {
-				err := database.ApplyCorrection(db, state.ID, state.Field, text)
-				if err != nil {
-					bot.Send(tgbotapi.NewMessage(userID, "❌ Ошибка корректировки!"))
-				} else {
-					bot.Send(tgbotapi.NewMessage(userID, "✅ Поле успешно обновлено!"))
+		if ok && state.Field == "wait_table_number" {
+			desiredRole := state.Value // worker/manager/admin
+			tableNumber := text
+
+			// если роль admin или передача прав, спрашиваем подтверждение
+			if desiredRole == "admin" || desiredRole == "owner" {
+				confirmData := "confirmAdmin:" + tableNumber
+				info := i18n.T(lang, "role.confirm_admin", tableNumber)
+				if desiredRole == "owner" {
+					confirmData = "confirmOwner:" + tableNumber
+					info = i18n.T(lang, "role.confirm_owner", tableNumber)
 				}
-				delete(userState, userID) // очищаем состояние
-				continue
+
+				confirmMarkup := tgbotapi.NewInlineKeyboardMarkup(
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "role.btn_sure"), confirmData),
+						tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "role.btn_no"), "cancelAdmin"),
+					),
+				)
+				msg := tgbotapi.NewMessage(userID, info)
+				msg.ReplyMarkup = confirmMarkup
+				bot.Send(msg)
+				// Можно сохранить tableNumber и роль во временном state
+				state.Field = "wait_confirm_admin"
+				state.Value = tableNumber
+				return
+			}
+
+			//Для worker/manager — сразу применяем
+			targetID, before, err := database.ChangeRole(db, userID, tableNumber, desiredRole)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "role.err_change", i18n.Err(lang, err))))
+			} else {
+				database.AuditAction(db, userID, "change_role", strconv.FormatInt(targetID, 10), before, desiredRole)
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "role.changed")))
+			}
+			delete(userState, userID)
+			return
+		}
+
+		if ok && (state.Field == "balance" || state.Field == "name" || state.Field == "tablenumber" || state.Field == "delete") {
+			err := service.CorrectStaff(db, userID, state.ID, state.Field, text)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "correction.err")))
+			} else {
+				bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "correction.done")))
 			}
+			delete(userState, userID) // очищаем состояние
+			return
 		}
 	}
 }
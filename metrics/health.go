@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HandlerStuck — дольше этого одно обновление обрабатываться не должно:
+// главный цикл обрабатывает обновления по одному, и зависший обработчик
+// останавливает весь бот.
+var HandlerStuck = 2 * time.Minute
+
+var (
+	ready     atomic.Bool
+	lastPoll  atomic.Int64 // время последнего успешного getUpdates, UnixNano
+	busySince atomic.Int64 // начало обработки текущего обновления, UnixNano; 0 — цикл ждёт
+)
+
+// SetReady отмечает, что бот запущен и принимает обновления.
+func SetReady(v bool) {
+	// Отсчёт для проверки опроса начинается с запуска, даже если getUpdates ещё ни разу не ответил
+	lastPoll.CompareAndSwap(0, time.Now().UnixNano())
+	ready.Store(v)
+}
+
+func polled() {
+	lastPoll.Store(time.Now().UnixNano())
+}
+
+// UpdateStarted и UpdateDone окружают обработку обновления в главном цикле.
+func UpdateStarted() {
+	busySince.Store(time.Now().UnixNano())
+}
+
+func UpdateDone() {
+	busySince.Store(0)
+}
+
+// Health — проверки для /healthz и /readyz.
+type Health struct {
+	DB *sql.DB
+	// PollStale — сколько может пройти без успешного getUpdates; 0 — режим вебхука,
+	// опрос не проверяется.
+	PollStale time.Duration
+}
+
+// problems — список неполадок; пустой — всё в порядке.
+func (h Health) problems(ctx context.Context) []string {
+	var list []string
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := h.DB.PingContext(ctx); err != nil {
+		list = append(list, "db: "+err.Error())
+	} else if _, err := h.DB.ExecContext(ctx, `SELECT 1`); err != nil {
+		list = append(list, "db: "+err.Error())
+	}
+
+	now := time.Now()
+	if h.PollStale > 0 && ready.Load() {
+		if last := lastPoll.Load(); last > 0 && now.Sub(time.Unix(0, last)) > h.PollStale {
+			list = append(list, fmt.Sprintf("telegram: нет ответа getUpdates %s", now.Sub(time.Unix(0, last)).Round(time.Second)))
+		}
+	}
+	if since := busySince.Load(); since > 0 && now.Sub(time.Unix(0, since)) > HandlerStuck {
+		list = append(list, fmt.Sprintf("updates: обновление обрабатывается %s", now.Sub(time.Unix(0, since)).Round(time.Second)))
+	}
+	return list
+}
+
+// Register подключает /healthz и /readyz. /healthz проверяет базу и главный
+// цикл, /readyz — ещё и то, что запуск завершён. Метрики (Handler) отдаются
+// на отдельном адресе, чтобы не светить их на публичном.
+func (h Health) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, h.problems(r.Context()))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		list := h.problems(r.Context())
+		if !ready.Load() {
+			list = append(list, "бот запускается")
+		}
+		writeHealth(w, list)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, problems []string) {
+	w.Header().Set("Content-Type", "application/json")
+	if len(problems) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "fail", "errors": problems})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
@@ -0,0 +1,260 @@
+// Package metrics — метрики бота в текстовом формате Prometheus и проверки
+// /healthz и /readyz. Метрики — счётчики и гистограммы в памяти процесса;
+// значения, которые проще посчитать по базе, снимаются в момент запроса (GaugeFunc).
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets — границы гистограмм длительности, в секундах.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Метрики бота
+var (
+	Updates          = NewCounterVec("tbvit_updates_total", "Обновления Telegram по типам.", "type")
+	CallbackDuration = NewHistogramVec("tbvit_callback_duration_seconds", "Время обработки нажатия кнопки по маршрутам.", DefBuckets, "route")
+	TelegramRequests = NewCounterVec("tbvit_telegram_requests_total", "Запросы к Telegram Bot API по методам.", "method")
+	TelegramErrors   = NewCounterVec("tbvit_telegram_errors_total", "Ошибки запросов к Telegram Bot API по методам.", "method")
+	DBQueryDuration  = NewHistogramVec("tbvit_db_query_duration_seconds", "Время запросов к БД по видам.", DefBuckets, "op")
+	StarsIssued      = NewCounterVec("tbvit_stars_issued_total", "Начисленные звёзды по типам операций.", "type")
+	StarsSpent       = NewCounterVec("tbvit_stars_spent_total", "Списанные звёзды по типам операций.", "type")
+	APIRequests      = NewCounterVec("tbvit_api_requests_total", "Запросы к HTTP API по маршрутам и кодам ответа.", "route", "code")
+	Panics           = NewCounterVec("tbvit_panics_total", "Паники в обработчиках обновлений по маршрутам.", "route")
+)
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+var (
+	mu       sync.Mutex
+	registry []collector
+)
+
+func register(c collector) {
+	mu.Lock()
+	registry = append(registry, c)
+	mu.Unlock()
+}
+
+// Handler отдаёт все метрики в текстовом формате Prometheus.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		mu.Lock()
+		list := append([]collector(nil), registry...)
+		mu.Unlock()
+		for _, c := range list {
+			if err := c.write(w); err != nil {
+				fmt.Fprintf(w, "# ошибка сбора: %s\n", strings.ReplaceAll(err.Error(), "\n", " "))
+			}
+		}
+	})
+}
+
+// series — значения одной комбинации меток.
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64 // только для гистограмм: число наблюдений в каждой корзине
+	sum    float64
+}
+
+// vec — метрика с метками; ключ серии — значения меток через \xff.
+type vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newVec(name, help, kind string, labels []string) *vec {
+	return &vec{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*series)}
+}
+
+func (v *vec) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s ожидает %d меток, передано %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]*series, len(keys))
+	for i, k := range keys {
+		list[i] = v.series[k]
+	}
+	return list
+}
+
+func (v *vec) header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	return err
+}
+
+func (v *vec) write(w io.Writer) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.header(w); err != nil {
+		return err
+	}
+	for _, s := range v.sorted() {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.name, labelString(v.labels, s.labels), formatFloat(s.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CounterVec — монотонно растущий счётчик.
+type CounterVec struct {
+	*vec
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels)}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.get(labelValues).value += value
+	c.mu.Unlock()
+}
+
+// HistogramVec — распределение значений по корзинам.
+type HistogramVec struct {
+	*vec
+	buckets []float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{newVec(name, help, "histogram", labels), buckets}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+	for i, b := range h.buckets {
+		if value <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.value++
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.header(w); err != nil {
+		return err
+	}
+	for _, s := range h.sorted() {
+		names := append(append([]string(nil), h.labels...), "le")
+		for i, b := range h.buckets {
+			values := append(append([]string(nil), s.labels...), formatFloat(b))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labelString(names, values), s.counts[i])
+		}
+		values := append(append([]string(nil), s.labels...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %s\n", h.name, labelString(names, values), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, labelString(h.labels, s.labels), formatFloat(s.sum))
+		if _, err := fmt.Fprintf(w, "%s_count%s %s\n", h.name, labelString(h.labels, s.labels), formatFloat(s.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GaugeFunc — значения, которые вычисляются при каждом запросе метрик;
+// collect вызывает set для каждой серии.
+type GaugeFunc struct {
+	name    string
+	help    string
+	labels  []string
+	collect func(set func(value float64, labelValues ...string)) error
+}
+
+func NewGaugeFunc(name, help string, labels []string, collect func(set func(value float64, labelValues ...string)) error) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, labels: labels, collect: collect}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) error {
+	v := newVec(g.name, g.help, "gauge", g.labels)
+	err := g.collect(func(value float64, labelValues ...string) {
+		v.get(labelValues).value = value
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", g.name, err)
+	}
+	return v.write(w)
+}
+
+func labelString(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
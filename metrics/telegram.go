@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http"
+	"path"
+)
+
+// TelegramClient — HTTP-клиент для telegram-bot-api (tgbotapi.NewBotAPIWithClient):
+// считает запросы и ошибки по методам Bot API и отмечает успешные getUpdates
+// для проверки цикла опроса.
+type TelegramClient struct {
+	Client *http.Client
+}
+
+func (c TelegramClient) Do(req *http.Request) (*http.Response, error) {
+	// Путь запроса — /bot<токен>/<метод>; в метки попадает только метод
+	method := path.Base(req.URL.Path)
+	TelegramRequests.Inc(method)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		TelegramErrors.Inc(method)
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		TelegramErrors.Inc(method)
+	} else if method == "getUpdates" {
+		polled()
+	}
+	return resp, nil
+}
@@ -0,0 +1,279 @@
+// Package outbox — исходящая очередь сообщений. Уведомления сохраняются в таблицу
+// outbox и отправляются фоновым обработчиком с ограничением скорости, повторами
+// с нарастающей паузой и учётом retry_after от Telegram.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"sync"
+	"tbViT/database"
+	"time"
+)
+
+const (
+	// GlobalInterval — не чаще одного сообщения за интервал на весь бот (~25 в секунду).
+	GlobalInterval = 40 * time.Millisecond
+	// ChatInterval — не чаще одного сообщения за интервал в один чат.
+	ChatInterval = time.Second
+	// MaxAttempts — после стольких неудачных попыток сообщение уходит в dead.
+	MaxAttempts = 5
+	// MaxBackoff — предельная пауза между повторами.
+	MaxBackoff = time.Hour
+)
+
+var (
+	global = &limiter{interval: GlobalInterval}
+	wake   = make(chan struct{}, 1)
+)
+
+// limiter выдаёт слоты отправки не чаще interval.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *limiter) Wait() {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	time.Sleep(wait)
+}
+
+// WaitGlobal занимает слот общего лимита. Нужен тем, кто шлёт в обход очереди (рассылки),
+// чтобы вместе с очередью не превышать лимит Telegram.
+func WaitGlobal() {
+	global.Wait()
+}
+
+// Enqueue ставит сообщение в очередь. Поддерживаются текст, фото и документ по file_id
+// и инлайн-клавиатура.
+func Enqueue(db *sql.DB, c tgbotapi.Chattable) error {
+	var m database.OutboxMessage
+	var markup interface{}
+	switch msg := c.(type) {
+	case tgbotapi.MessageConfig:
+		m = database.OutboxMessage{ChatID: msg.ChatID, Kind: database.BroadcastText, Text: msg.Text, ParseMode: msg.ParseMode}
+		markup = msg.ReplyMarkup
+	case tgbotapi.PhotoConfig:
+		file, ok := msg.File.(tgbotapi.FileID)
+		if !ok {
+			return errors.New("в очередь можно поставить только фото по file_id")
+		}
+		m = database.OutboxMessage{ChatID: msg.ChatID, Kind: database.BroadcastPhoto, Text: msg.Caption,
+			FileID: string(file), ParseMode: msg.ParseMode}
+		markup = msg.ReplyMarkup
+	case tgbotapi.DocumentConfig:
+		file, ok := msg.File.(tgbotapi.FileID)
+		if !ok {
+			return errors.New("в очередь можно поставить только документ по file_id")
+		}
+		m = database.OutboxMessage{ChatID: msg.ChatID, Kind: database.BroadcastDocument, Text: msg.Caption,
+			FileID: string(file), ParseMode: msg.ParseMode}
+		markup = msg.ReplyMarkup
+	default:
+		return fmt.Errorf("неподдерживаемый тип сообщения %T", c)
+	}
+
+	if markup != nil {
+		kb, ok := markup.(tgbotapi.InlineKeyboardMarkup)
+		if !ok {
+			return fmt.Errorf("неподдерживаемая клавиатура %T", markup)
+		}
+		raw, err := json.Marshal(kb)
+		if err != nil {
+			return err
+		}
+		m.ReplyMarkup = string(raw)
+	}
+
+	if _, err := database.EnqueueOutbox(db, m); err != nil {
+		slog.Error("Ошибка постановки сообщения в очередь", "chat_id", m.ChatID, "err", err)
+		return err
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Send ставит в очередь текстовое сообщение.
+func Send(db *sql.DB, chatID int64, text string) error {
+	return Enqueue(db, tgbotapi.NewMessage(chatID, text))
+}
+
+func chattable(m database.OutboxMessage) (tgbotapi.Chattable, error) {
+	var markup interface{}
+	if m.ReplyMarkup != "" {
+		var kb tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(m.ReplyMarkup), &kb); err != nil {
+			return nil, err
+		}
+		markup = kb
+	}
+	switch m.Kind {
+	case database.BroadcastPhoto:
+		msg := tgbotapi.NewPhoto(m.ChatID, tgbotapi.FileID(m.FileID))
+		msg.Caption, msg.ParseMode, msg.ReplyMarkup = m.Text, m.ParseMode, markup
+		return msg, nil
+	case database.BroadcastDocument:
+		msg := tgbotapi.NewDocument(m.ChatID, tgbotapi.FileID(m.FileID))
+		msg.Caption, msg.ParseMode, msg.ReplyMarkup = m.Text, m.ParseMode, markup
+		return msg, nil
+	}
+	msg := tgbotapi.NewMessage(m.ChatID, m.Text)
+	msg.ParseMode, msg.ReplyMarkup = m.ParseMode, markup
+	return msg, nil
+}
+
+// Worker отправляет сообщения из очереди.
+type Worker struct {
+	bot      *tgbotapi.BotAPI
+	db       *sql.DB
+	lastSent map[int64]time.Time
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// Start запускает обработчик очереди в отдельной горутине.
+func Start(bot *tgbotapi.BotAPI, db *sql.DB) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Worker{bot: bot, db: db, lastSent: make(map[int64]time.Time),
+		ctx: ctx, cancel: cancel, done: make(chan struct{})}
+	go w.loop()
+	return w
+}
+
+func (w *Worker) loop() {
+	defer close(w.done)
+	for w.ctx.Err() == nil {
+		sent := w.processDue(w.ctx)
+		if sent > 0 {
+			continue
+		}
+		select {
+		case <-w.ctx.Done():
+		case <-wake:
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Stop останавливает фоновый цикл и до отмены ctx отправляет всё, что уже пора
+// отправить, в том числе поставленное обработчиками во время остановки.
+// Неотправленное остаётся в базе и уйдёт после перезапуска.
+func (w *Worker) Stop(ctx context.Context) error {
+	w.cancel()
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	for {
+		if w.processDue(ctx) > 0 {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Ничего не отправлено: очередь пуста или чаты ещё под лимитом
+		due, err := database.DueOutbox(w.db, time.Now(), 1)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ChatInterval / 4):
+		}
+	}
+}
+
+// processDue отправляет созревшие сообщения и возвращает количество обработанных.
+// После отмены ctx новые сообщения не отправляются.
+func (w *Worker) processDue(ctx context.Context) int {
+	due, err := database.DueOutbox(w.db, time.Now(), 100)
+	if err != nil {
+		slog.Error("Очередь: ошибка выборки сообщений", "err", err)
+		return 0
+	}
+	processed := 0
+	for _, m := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		// Лимит на чат: сообщение подождёт следующего прохода, порядок в чате сохраняется
+		if time.Since(w.lastSent[m.ChatID]) < ChatInterval {
+			continue
+		}
+		global.Wait()
+		w.deliver(m)
+		w.lastSent[m.ChatID] = time.Now()
+		processed++
+	}
+	for chatID, t := range w.lastSent {
+		if time.Since(t) > ChatInterval {
+			delete(w.lastSent, chatID)
+		}
+	}
+	return processed
+}
+
+func (w *Worker) deliver(m database.OutboxMessage) {
+	c, err := chattable(m)
+	if err != nil {
+		database.MarkOutboxDead(w.db, m.ID, m.Attempts, err.Error())
+		return
+	}
+	_, err = w.bot.Send(c)
+	if err == nil {
+		database.MarkOutboxSent(w.db, m.ID)
+		return
+	}
+
+	var apiErr *tgbotapi.Error
+	isAPI := errors.As(err, &apiErr)
+	switch {
+	case isAPI && apiErr.Code == 403:
+		// Бот заблокирован — повторять бессмысленно
+		database.SetUserBlocked(w.db, m.ChatID, true)
+		database.MarkOutboxDead(w.db, m.ID, m.Attempts+1, err.Error())
+	case isAPI && apiErr.Code == 429:
+		// Превышен лимит — ждём, сколько попросил Telegram, попытку не засчитываем
+		wait := time.Duration(apiErr.RetryAfter) * time.Second
+		if wait <= 0 {
+			wait = time.Second
+		}
+		database.RetryOutbox(w.db, m.ID, m.Attempts, time.Now().Add(wait), err.Error())
+	default:
+		attempts := m.Attempts + 1
+		if attempts >= MaxAttempts {
+			slog.Warn("Очередь: сообщение не доставлено", "outbox_id", m.ID, "chat_id", m.ChatID, "attempts", attempts, "err", err)
+			database.MarkOutboxDead(w.db, m.ID, attempts, err.Error())
+			return
+		}
+		database.RetryOutbox(w.db, m.ID, attempts, time.Now().Add(Backoff(attempts)), err.Error())
+	}
+}
+
+// Backoff — пауза перед повтором: 2, 4, 8... секунд, но не больше MaxBackoff.
+func Backoff(attempts int) time.Duration {
+	d := time.Duration(1<<uint(attempts)) * time.Second
+	if d > MaxBackoff || d <= 0 {
+		return MaxBackoff
+	}
+	return d
+}
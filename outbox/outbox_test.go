@@ -0,0 +1,191 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"tbViT/database"
+)
+
+// fakeTelegram отвечает на sendMessage ошибкой code (0 — успех) и запоминает получателей.
+type fakeTelegram struct {
+	mu    sync.Mutex
+	code  int
+	chats []string
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasSuffix(r.URL.Path, "/getMe") {
+		fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`)
+		return
+	}
+	r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chats = append(f.chats, r.Form.Get("chat_id"))
+	if f.code != 0 {
+		w.WriteHeader(f.code)
+		fmt.Fprintf(w, `{"ok":false,"error_code":%d,"description":"ошибка %d"}`, f.code, f.code)
+		return
+	}
+	fmt.Fprint(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"private"}}}`)
+}
+
+func newWorker(t *testing.T, code int) (*Worker, *fakeTelegram) {
+	t.Helper()
+	db, err := database.Open(database.DriverSQLite, filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.Migrate(db, database.DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	tg := &fakeTelegram{code: code}
+	srv := httptest.NewServer(tg)
+	t.Cleanup(srv.Close)
+	bot, err := tgbotapi.NewBotAPIWithClient("token", srv.URL+"/bot%s/%s", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Worker{bot: bot, db: db, lastSent: make(map[int64]time.Time)}, tg
+}
+
+type outboxRow struct {
+	status      string
+	attempts    int
+	nextAttempt int64
+	lastError   sql.NullString
+}
+
+func getRow(t *testing.T, db *sql.DB, id int64) outboxRow {
+	t.Helper()
+	var r outboxRow
+	err := db.QueryRow(`SELECT status, attempts, next_attempt_at, last_error FROM outbox WHERE id=?`, id).
+		Scan(&r.status, &r.attempts, &r.nextAttempt, &r.lastError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func enqueue(t *testing.T, db *sql.DB, chatID int64) int64 {
+	t.Helper()
+	id, err := database.EnqueueOutbox(db, database.OutboxMessage{ChatID: chatID, Kind: database.BroadcastText, Text: "привет"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{4, 16 * time.Second},
+		{12, MaxBackoff},
+		{100, MaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// Сбой отправки откладывает сообщение с нарастающей паузой, после MaxAttempts — в dead.
+func TestDeliverRetryAndDeadLetter(t *testing.T) {
+	w, _ := newWorker(t, http.StatusInternalServerError)
+	id := enqueue(t, w.db, 42)
+
+	for attempt := 1; attempt < MaxAttempts; attempt++ {
+		due, err := database.DueOutbox(w.db, time.Now().Add(MaxBackoff), 10)
+		if err != nil || len(due) != 1 {
+			t.Fatalf("попытка %d: к отправке %v, %v", attempt, due, err)
+		}
+		start := time.Now()
+		w.deliver(due[0])
+		r := getRow(t, w.db, id)
+		if r.status != database.OutboxPending || r.attempts != attempt || !r.lastError.Valid {
+			t.Fatalf("попытка %d: %+v", attempt, r)
+		}
+		if wait := time.Unix(r.nextAttempt, 0).Sub(start); wait < Backoff(attempt)-time.Second || wait > Backoff(attempt)+time.Second {
+			t.Errorf("попытка %d: пауза %v, want %v", attempt, wait, Backoff(attempt))
+		}
+	}
+
+	due, err := database.DueOutbox(w.db, time.Now().Add(MaxBackoff), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("последняя попытка: %v, %v", due, err)
+	}
+	w.deliver(due[0])
+	if r := getRow(t, w.db, id); r.status != database.OutboxDead || r.attempts != MaxAttempts {
+		t.Fatalf("после %d попыток: %+v", MaxAttempts, r)
+	}
+}
+
+// 403 — бот заблокирован: сообщение сразу в dead, пользователь помечен.
+func TestDeliverBlocked(t *testing.T) {
+	w, _ := newWorker(t, http.StatusForbidden)
+	if _, err := w.db.Exec(`INSERT INTO users (telegram_id, verified) VALUES (42, 1)`); err != nil {
+		t.Fatal(err)
+	}
+	id := enqueue(t, w.db, 42)
+	due, err := database.DueOutbox(w.db, time.Now(), 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("к отправке %v, %v", due, err)
+	}
+	w.deliver(due[0])
+
+	if r := getRow(t, w.db, id); r.status != database.OutboxDead || r.attempts != 1 {
+		t.Errorf("сообщение: %+v", r)
+	}
+	var blocked int
+	if err := w.db.QueryRow(`SELECT COALESCE(blocked, 0) FROM users WHERE telegram_id=42`).Scan(&blocked); err != nil {
+		t.Fatal(err)
+	}
+	if blocked != 1 {
+		t.Error("пользователь не помечен заблокировавшим бота")
+	}
+}
+
+// Пока первое сообщение чата ждёт повтора, второе в тот же чат не уходит.
+func TestProcessDueKeepsChatOrder(t *testing.T) {
+	w, tg := newWorker(t, http.StatusInternalServerError)
+	first := enqueue(t, w.db, 42)
+	second := enqueue(t, w.db, 42)
+	enqueue(t, w.db, 7)
+
+	if n := w.processDue(context.Background()); n != 2 {
+		t.Fatalf("обработано %d, want 2: первое в чат 42 и сообщение в чат 7", n)
+	}
+	if r := getRow(t, w.db, first); r.attempts != 1 {
+		t.Fatalf("первое сообщение: %+v", r)
+	}
+
+	// Лимит чата прошёл, но первое ждёт повтора — второе не отправляется
+	tg.mu.Lock()
+	tg.code, tg.chats = 0, nil
+	tg.mu.Unlock()
+	w.lastSent = make(map[int64]time.Time)
+	w.processDue(context.Background())
+	if len(tg.chats) != 0 {
+		t.Errorf("отправлено в чаты %v до повтора первого сообщения", tg.chats)
+	}
+	if r := getRow(t, w.db, second); r.status != database.OutboxPending || r.attempts != 0 {
+		t.Errorf("второе сообщение: %+v", r)
+	}
+}
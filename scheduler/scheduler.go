@@ -0,0 +1,161 @@
+// Package scheduler выполняет фоновые задачи по cron-расписанию.
+// Определения задач и время запусков хранятся в таблице jobs, поэтому
+// перезапуск бота не приводит ни к пропуску, ни к повтору запуска.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"tbViT/database"
+)
+
+// Tick — как часто планировщик проверяет сроки задач.
+var Tick = 30 * time.Second
+
+type Scheduler struct {
+	db   *sql.DB
+	mu   sync.Mutex
+	jobs map[string]func() error
+	stop chan struct{}
+	done chan struct{}
+}
+
+func New(db *sql.DB) *Scheduler {
+	return &Scheduler{db: db, jobs: make(map[string]func() error),
+		stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// ParseSpec разбирает cron-выражение из 5 полей ("0 9 * * 1"), допускаются
+// @daily/@weekly и префикс CRON_TZ=Europe/Moscow.
+func ParseSpec(spec string) (cron.Schedule, error) {
+	return cron.ParseStandard(spec)
+}
+
+// Add регистрирует задачу. defaultSpec используется, только если задачи ещё нет в базе.
+func (s *Scheduler) Add(name, defaultSpec string, run func() error) error {
+	if _, err := ParseSpec(defaultSpec); err != nil {
+		return fmt.Errorf("задача %s: %w", name, err)
+	}
+	if err := database.RegisterJob(s.db, name, defaultSpec); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.jobs[name] = run
+	s.mu.Unlock()
+	return nil
+}
+
+// Start запускает цикл проверки задач в отдельной горутине.
+func (s *Scheduler) Start() {
+	go func() {
+		defer close(s.done)
+		for {
+			s.runDue(time.Now())
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(Tick):
+			}
+		}
+	}()
+}
+
+// Stop останавливает цикл и ждёт окончания выполняемой задачи, но не дольше ctx.
+// Задача, прерванная выходом процесса, считается выполненной по расписанию:
+// срок следующего запуска уже записан при захвате.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	close(s.stop)
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait дожидается, пока цикл задач остановится после Stop, без ограничения по времени.
+func (s *Scheduler) Wait() {
+	<-s.done
+}
+
+func (s *Scheduler) stopping() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Scheduler) runDue(now time.Time) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	for _, name := range names {
+		if s.stopping() {
+			return
+		}
+		job, err := database.GetJob(s.db, name)
+		if err != nil {
+			slog.Error("Планировщик: ошибка загрузки задачи", "job", name, "err", err)
+			continue
+		}
+		schedule, err := ParseSpec(job.Spec)
+		if err != nil {
+			slog.Error("Планировщик: некорректное расписание", "spec", job.Spec, "job", name, "err", err)
+			continue
+		}
+		if job.NextRun == 0 {
+			database.ScheduleJob(s.db, name, schedule.Next(now))
+			continue
+		}
+		if job.NextRun > now.Unix() {
+			continue
+		}
+		// Пропущенные за время простоя сроки выполняем один раз, дальше — по расписанию
+		claimed, err := database.ClaimJob(s.db, name, job.NextRun, schedule.Next(now), now)
+		if err != nil {
+			slog.Error("Планировщик: ошибка захвата задачи", "job", name, "err", err)
+			continue
+		}
+		if claimed {
+			s.run(name)
+		}
+	}
+}
+
+func (s *Scheduler) run(name string) {
+	s.mu.Lock()
+	fn := s.jobs[name]
+	s.mu.Unlock()
+
+	errText := ""
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errText = fmt.Sprintf("panic: %v", r)
+				slog.Error("Планировщик: паника в задаче", "job", name, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			}
+		}()
+		start := time.Now()
+		if err := fn(); err != nil {
+			errText = err.Error()
+		}
+		slog.Info("Планировщик: задача выполнена", "job", name, "ms", time.Since(start).Milliseconds())
+	}()
+	if errText != "" {
+		slog.Error("Планировщик: задача завершилась с ошибкой", "job", name, "err", errText)
+	}
+	database.FinishJob(s.db, name, errText)
+}
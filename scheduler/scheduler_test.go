@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tbViT/database"
+)
+
+func TestRunDue(t *testing.T) {
+	db, err := database.Open(database.DriverSQLite, filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := database.Migrate(db, database.DriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := 0
+	s := New(db)
+	// По понедельникам в 9:00 UTC
+	if err := s.Add("digest", "CRON_TZ=UTC 0 9 * * 1", func() error { runs++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	mon := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	nextRun := func() time.Time {
+		t.Helper()
+		j, err := database.GetJob(db, "digest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Unix(j.NextRun, 0).UTC()
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		runs int
+		next time.Time
+	}{
+		{"первый тик назначает срок", mon.Add(8 * time.Hour), 0, mon.Add(9 * time.Hour)},
+		{"до срока не запускается", mon.Add(8*time.Hour + 59*time.Minute), 0, mon.Add(9 * time.Hour)},
+		{"в срок запускается", mon.Add(9 * time.Hour), 1, mon.AddDate(0, 0, 7).Add(9 * time.Hour)},
+		{"тот же срок повторно не запускается", mon.Add(10 * time.Hour), 1, mon.AddDate(0, 0, 7).Add(9 * time.Hour)},
+		// Простой три недели: пропущенное выполняется один раз, дальше — по расписанию
+		{"после простоя один запуск", mon.AddDate(0, 0, 22), 2, mon.AddDate(0, 0, 28).Add(9 * time.Hour)},
+	}
+	for _, tt := range tests {
+		s.runDue(tt.now)
+		if runs != tt.runs {
+			t.Errorf("%s: запусков %d, want %d", tt.name, runs, tt.runs)
+		}
+		if got := nextRun(); !got.Equal(tt.next) {
+			t.Errorf("%s: следующий запуск %v, want %v", tt.name, got, tt.next)
+		}
+	}
+
+	// После смены расписания срок пересчитывается на следующем тике
+	if err := database.SetJobSpec(db, "digest", "CRON_TZ=UTC 30 6 * * *"); err != nil {
+		t.Fatal(err)
+	}
+	now := mon.AddDate(0, 0, 22).Add(time.Hour)
+	s.runDue(now)
+	if want := mon.AddDate(0, 0, 22).Add(6*time.Hour + 30*time.Minute); !nextRun().Equal(want) {
+		t.Errorf("после смены расписания следующий запуск %v, want %v", nextRun(), want)
+	}
+}
@@ -0,0 +1,47 @@
+// Package service — действия над сотрудниками, балансами и магазином, общие для
+// бота и HTTP API: проверка правил, запись в журнал аудита и уведомления через
+// очередь. Нарушение правил возвращается как *i18n.Error — его текст можно
+// показать пользователю на его языке; остальные ошибки — сбои базы.
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"tbViT/database"
+	"tbViT/outbox"
+)
+
+// TopUp начисляет работнику amount звёзд от имени actorID: с паузой между
+// начислениями, бюджетом менеджера и максимальным балансом предприятия.
+// Возвращает новый баланс.
+func TopUp(ctx context.Context, db *sql.DB, actorID, workerID int64, amount int) (int, error) {
+	balance, err := database.TopUpBalanceContext(ctx, db, actorID, workerID, amount)
+	if err != nil {
+		return 0, err
+	}
+	database.AuditAction(db, actorID, "topup", strconv.FormatInt(workerID, 10), "", strconv.Itoa(amount))
+	outbox.Send(db, workerID, database.Tr(db, workerID, "topup.notice", amount))
+	return balance, nil
+}
+
+// CorrectStaff исправляет поле сотрудника (balance, name, tablenumber) или удаляет
+// его (delete); последнего админа предприятия удалить нельзя.
+func CorrectStaff(db *sql.DB, actorID, workerID int64, field, value string) error {
+	before := database.GetCorrectionValue(db, workerID, field)
+	if err := database.ApplyCorrection(db, actorID, workerID, field, value); err != nil {
+		return err
+	}
+	database.AuditAction(db, actorID, "correction:"+field, strconv.FormatInt(workerID, 10), before, value)
+	return nil
+}
+
+// SetAccessLevel меняет роль сотрудника; последнего админа предприятия понизить нельзя.
+func SetAccessLevel(db *sql.DB, actorID, userID int64, level string) error {
+	before, err := database.SetAccessLevel(db, userID, level)
+	if err != nil {
+		return err
+	}
+	database.AuditAction(db, actorID, "change_role", strconv.FormatInt(userID, 10), before, level)
+	return nil
+}
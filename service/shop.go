@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/outbox"
+)
+
+// Buy оформляет покупку товара: списывает звёзды, уменьшает остаток, создаёт заказ
+// и уведомляет админов предприятия.
+func Buy(ctx context.Context, db *sql.DB, buyerID int64, productID int) (database.Order, error) {
+	orderID, err := database.BuyProductContext(ctx, db, buyerID, productID)
+	if err != nil {
+		return database.Order{}, err
+	}
+	o, err := database.GetOrder(db, orderID)
+	if err != nil {
+		return o, err
+	}
+	admins, err := database.GetRestAdminIDs(db, o.RestNumber)
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка поиска админов для заказа", "user_id", buyerID, "err", err)
+	}
+	for _, admin := range admins {
+		outbox.Send(db, admin, database.Tr(db, admin, "buy.admin_notice", buyerID, o.Product))
+	}
+	database.EvaluateAchievements(db, buyerID, database.EventPurchase)
+	return o, nil
+}
+
+// DecideOrder выдаёт (accept) или отменяет (deny) заказ с возвратом звёзд и
+// уведомляет покупателя. Уже рассмотренный заказ — database.ErrOrderDecided.
+func DecideOrder(ctx context.Context, db *sql.DB, actorID int64, orderID int, decision string) (database.Order, error) {
+	if decision != database.OrderAccepted && decision != database.OrderDenied {
+		return database.Order{}, i18n.NewError("orders.err_decision", decision)
+	}
+	o, err := database.GetOrder(db, orderID)
+	if err != nil {
+		return o, err
+	}
+	// Статус проверяет CompleteOrder в транзакции: заказ могли рассмотреть и после GetOrder
+	buyerID, product, err := database.CompleteOrderContext(ctx, db, orderID, decision)
+	if err != nil {
+		return o, err
+	}
+	o.Status = decision
+	database.AuditAction(db, actorID, "order_"+decision, strconv.Itoa(orderID), database.OrderOpen, decision)
+	notice := "orders.ready_notice"
+	if decision == database.OrderDenied {
+		notice = "orders.denied_notice"
+	}
+	outbox.Send(db, buyerID, database.Tr(db, buyerID, notice, product))
+	return o, nil
+}
+
+func checkProduct(p database.Product) error {
+	switch {
+	case strings.TrimSpace(p.Name) == "":
+		return i18n.NewError("shop.err_empty_name")
+	case p.Price < 0:
+		return i18n.NewError("shop.err_negative_price")
+	case p.Remains < 0:
+		return i18n.NewError("err.value_negative")
+	}
+	return nil
+}
+
+// productAudit — товар в журнале аудита.
+func productAudit(p database.Product) string {
+	return fmt.Sprintf("%s|%d🌟|%d шт.", p.Name, p.Price, p.Remains)
+}
+
+// AddProduct добавляет товар в магазин предприятия p.RestNumber и возвращает его id.
+func AddProduct(db *sql.DB, actorID int64, p database.Product) (int, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if err := checkProduct(p); err != nil {
+		return 0, err
+	}
+	id, err := database.AddProduct(db, p.RestNumber, p.Name, p.Price, p.Remains)
+	if err != nil {
+		return 0, err
+	}
+	database.AuditAction(db, actorID, "product_add", strconv.Itoa(id), "", productAudit(p))
+	return id, nil
+}
+
+// UpdateProduct сохраняет название, цену и остаток товара p.ID; каждое изменённое
+// поле записывается в журнал аудита отдельно.
+func UpdateProduct(db *sql.DB, actorID int64, p database.Product) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if err := checkProduct(p); err != nil {
+		return err
+	}
+	before, err := database.GetProduct(db, p.ID)
+	if err != nil {
+		return err
+	}
+	p.RestNumber = before.RestNumber
+	if err := database.UpdateProduct(db, p); err != nil {
+		return err
+	}
+	target := strconv.Itoa(p.ID)
+	if p.Name != before.Name {
+		database.AuditAction(db, actorID, "product_name", target, before.Name, p.Name)
+	}
+	if p.Price != before.Price {
+		database.AuditAction(db, actorID, "product_price", target, strconv.Itoa(before.Price), strconv.Itoa(p.Price))
+	}
+	if p.Remains != before.Remains {
+		database.AuditAction(db, actorID, "product_remains", target, strconv.Itoa(before.Remains), strconv.Itoa(p.Remains))
+	}
+	return nil
+}
+
+// DeleteProduct удаляет товар; оформленные заказы остаются.
+func DeleteProduct(db *sql.DB, actorID int64, id int) error {
+	before, err := database.GetProduct(db, id)
+	if err != nil {
+		return err
+	}
+	if err := database.DeleteProduct(db, id); err != nil {
+		return err
+	}
+	database.AuditAction(db, actorID, "product_delete", strconv.Itoa(id), productAudit(before), "")
+	return nil
+}
@@ -1,20 +1,25 @@
 package stepreg
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/outbox"
+	"tbViT/view"
 )
 
 // RegistrationHandler обрабатывает команды и сообщения, связанные с регистрацией пользователя
 // в формате "номер_расписания Имя номер_предприятия".
 // Возвращает true, если сообщение было обработано, иначе false.
-func RegistrationHandler(bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Update) bool {
+// ctx несёт cid обновления для журнала и запросов к БД.
+func RegistrationHandler(ctx context.Context, bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Update) bool {
 	if update.Message == nil || update.Message.From == nil {
 		return false
 	}
@@ -27,11 +32,16 @@ func RegistrationHandler(bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Updat
 	// --- Получение состояния пользователя ---
 	// Получаем текущее состояние регистрации и время начала последней регистрации.
 	// Обратите внимание на порядок полей в SELECT и Scan
-	err := db.QueryRow(`SELECT reg_state, registration_start_time FROM users WHERE telegram_id=?`,
+	err := db.QueryRowContext(ctx, `SELECT reg_state, registration_start_time FROM users WHERE telegram_id=?`,
 		userID).Scan(&regState, &registrationStartTime) // <-- Сканируем в regState и registrationStartTime
+	// Язык нового пользователя берём из настроек Telegram, пока он не выбрал свой
+	lang := i18n.Normalize(user.LanguageCode)
+	if err == nil {
+		lang = database.UserLang(db, userID)
+	}
 	if err != nil && err != sql.ErrNoRows {
-		log.Printf("Ошибка при чтении состояния пользователя для user_id %d: %v", userID, err)
-		bot.Send(tgbotapi.NewMessage(userID, "Произошла ошибка при проверке вашего статуса. Попробуйте позже."))
+		slog.ErrorContext(ctx, "Ошибка чтения состояния регистрации", "user_id", userID, "err", err)
+		bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.err_status")))
 		return true // Обработали, но с ошибкой
 	}
 
@@ -39,51 +49,42 @@ func RegistrationHandler(bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Updat
 
 	// 1. Команда /start - инициирует регистрацию
 	if update.Message.IsCommand() && update.Message.Command() == "start" {
-		tx, err := db.Begin()
+		tx, err := db.BeginTx(ctx, nil)
 		if err != nil {
-			log.Printf("Ошибка начала транзакции для /start (user_id %d): %v", userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла внутренняя ошибка. Попробуйте позже."))
+			slog.ErrorContext(ctx, "Ошибка начала транзакции для /start", "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "err.internal")))
 			return true
 		}
 		defer tx.Rollback() // Откат, если что-то пойдет не так
 
 		// Создаем пользователя, если его нет, или обновляем/игнорируем, если существует.
 		// Устанавливаем состояние ожидания данных регистрации и текущее время.
-		// Используем datetime('now') для SQLite
-		_, err = tx.Exec(`INSERT OR IGNORE INTO users (telegram_id, username, verified, reg_state, registration_start_time)
-                         VALUES (?, ?, 0, 'waiting_registration_data', datetime('now'))`, userID, user.UserName)
+		// CURRENT_TIMESTAMP одинаково работает в SQLite и PostgreSQL
+		_, err = tx.ExecContext(ctx, `INSERT INTO users (telegram_id, username, verified, reg_state, registration_start_time, language)
+                         VALUES (?, ?, 0, 'waiting_registration_data', CURRENT_TIMESTAMP, ?) ON CONFLICT(telegram_id) DO NOTHING`, userID, user.UserName, lang)
 		if err != nil {
-			log.Printf("Ошибка INSERT OR IGNORE для /start (user_id %d): %v", userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла ошибка при регистрации. Попробуйте позже."))
+			slog.ErrorContext(ctx, "Ошибка создания пользователя для /start", "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.err_register")))
 			return true
 		}
 
 		// Обновляем состояние и очищаем предыдущие данные, если пользователь уже был.
 		// Также устанавливаем текущее время для нового старта регистрации.
-		_, err = tx.Exec(`UPDATE users SET reg_state='waiting_registration_data', name='', table_number='', rest_number='', registration_start_time=datetime('now')
+		_, err = tx.ExecContext(ctx, `UPDATE users SET reg_state='waiting_registration_data', name='', table_number='', rest_number=NULL, registration_start_time=CURRENT_TIMESTAMP, submitted_at=NULL
                          WHERE telegram_id=?`, userID)
 		if err != nil {
-			log.Printf("Ошибка UPDATE для /start (user_id %d): %v", userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла ошибка при обновлении данных. Попробуйте позже."))
+			slog.ErrorContext(ctx, "Ошибка обновления пользователя для /start", "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.err_update")))
 			return true
 		}
 
 		if err = tx.Commit(); err != nil {
-			log.Printf("Ошибка коммита транзакции для /start (user_id %d): %v", userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла внутренняя ошибка. Попробуйте позже."))
+			slog.ErrorContext(ctx, "Ошибка коммита транзакции для /start", "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "err.internal")))
 			return true
 		}
 
-		bot.Send(tgbotapi.NewMessage(userID, `👋 Здравствуйте!
-Для регистрации введите данные в таком формате:
-**[номер в расписании] [Ваше имя] [номер предприятия]**
-
-Пример:
-15 Петр 11047
-
-*Имя может состоять из нескольких слов.*
-
-*Для сброса регистрации введите /start заново.*`))
+		bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.help")))
 		return true
 	}
 
@@ -95,11 +96,11 @@ func RegistrationHandler(bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Updat
 		if registrationStartTime.Valid && time.Since(registrationStartTime.Time) > 5*time.Minute {
 			// Сбрасываем состояние и просим начать заново
 			// Устанавливаем registration_start_time в NULL, так как состояние сбрасывается
-			_, err := db.Exec(`UPDATE users SET reg_state='', name='', table_number='', rest_number='', registration_start_time=NULL WHERE telegram_id=?`, userID)
+			_, err := db.ExecContext(ctx, `UPDATE users SET reg_state='', name='', table_number='', rest_number=NULL, registration_start_time=NULL WHERE telegram_id=?`, userID)
 			if err != nil {
-				log.Printf("Ошибка сброса reg_state после таймаута для user_id %d: %v", userID, err)
+				slog.ErrorContext(ctx, "Ошибка сброса reg_state после таймаута", "user_id", userID, "err", err)
 			}
-			bot.Send(tgbotapi.NewMessage(userID, "👋 Время ожидания ввода данных истекло. Пожалуйста, введите /start для начала регистрации заново."))
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.timeout")))
 			return true // Завершаем обработку, пользователь получил уведомление
 		}
 
@@ -109,12 +110,12 @@ func RegistrationHandler(bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Updat
 
 		// Ожидаем как минимум 3 части: номер расписания, имя (может быть несколько слов), номер предприятия.
 		if len(parts) < 3 {
-			bot.Send(tgbotapi.NewMessage(userID, "❗️ Некорректный формат ввода. Пожалуйста, убедитесь, что вы указали номер расписания, ваше имя и номер предприятия через пробелы.\n\nПример: 15 Петр 1023\n\n*Для сброса регистрации введите /start заново.*"))
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.bad_format")))
 			// Сбрасываем состояние пользователя, чтобы он мог начать заново.
 			// Устанавливаем registration_start_time в NULL, так как состояние сбрасывается
-			_, err := db.Exec(`UPDATE users SET reg_state='', name='', table_number='', rest_number='', registration_start_time=NULL WHERE telegram_id=?`, userID)
+			_, err := db.ExecContext(ctx, `UPDATE users SET reg_state='', name='', table_number='', rest_number=NULL, registration_start_time=NULL WHERE telegram_id=?`, userID)
 			if err != nil {
-				log.Printf("Ошибка сброса reg_state после некорректного ввода для user_id %d: %v", userID, err)
+				slog.ErrorContext(ctx, "Ошибка сброса reg_state после некорректного ввода", "user_id", userID, "err", err)
 			}
 			return true
 		}
@@ -129,115 +130,117 @@ func RegistrationHandler(bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Updat
 		// 1. Валидация номера расписания
 		_, err = strconv.Atoi(tableNumberStr)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(userID, "❗️ Номер в расписании должен состоять только из цифр. Пожалуйста, введите /start для начала регистрации заново."))
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.bad_table")))
 			// Сбрасываем состояние пользователя, так как ввод некорректен.
 			// Устанавливаем registration_start_time в NULL, так как состояние сбрасывается
-			_, errExec := db.Exec(`UPDATE users SET reg_state='', name='', table_number='', rest_number='', registration_start_time=NULL WHERE telegram_id=?`, userID)
+			_, errExec := db.ExecContext(ctx, `UPDATE users SET reg_state='', name='', table_number='', rest_number=NULL, registration_start_time=NULL WHERE telegram_id=?`, userID)
 			if errExec != nil {
-				log.Printf("Ошибка сброса reg_state после некорректного ввода номера расписания для user_id %d: %v", userID, errExec)
+				slog.ErrorContext(ctx, "Ошибка сброса reg_state после некорректного номера расписания", "user_id", userID, "err", errExec)
 			}
 			return true
 		}
 
 		// 2. Валидация номера предприятия (должен быть числом)
-		_, err = strconv.Atoi(restNumberStr)
+		restNumber, err := strconv.Atoi(restNumberStr)
 		if err != nil {
-			bot.Send(tgbotapi.NewMessage(userID, "❗️ Номер предприятия должен состоять только из цифр. Пожалуйста, введите /start для начала регистрации заново."))
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.bad_rest")))
 			// Сбрасываем состояние пользователя, так как ввод некорректен.
 			// Устанавливаем registration_start_time в NULL, так как состояние сбрасывается
-			_, errExec := db.Exec(`UPDATE users SET reg_state='', name='', table_number='', rest_number='', registration_start_time=NULL WHERE telegram_id=?`, userID)
+			_, errExec := db.ExecContext(ctx, `UPDATE users SET reg_state='', name='', table_number='', rest_number=NULL, registration_start_time=NULL WHERE telegram_id=?`, userID)
 			if errExec != nil {
-				log.Printf("Ошибка сброса reg_state после некорректного ввода номера предприятия для user_id %d: %v", userID, errExec)
+				slog.ErrorContext(ctx, "Ошибка сброса reg_state после некорректного номера предприятия", "user_id", userID, "err", errExec)
 			}
 			return true
 		}
 
 		// 3. Проверка имени (не должно быть пустым после удаления пробелов)
 		if nameInput == "" {
-			bot.Send(tgbotapi.NewMessage(userID, "❗️ Имя не может быть пустым. Пожалуйста, введите /start для начала регистрации заново."))
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.empty_name")))
 			// Сбрасываем состояние пользователя, так как ввод некорректен.
 			// Устанавливаем registration_start_time в NULL, так как состояние сбрасывается
-			_, errExec := db.Exec(`UPDATE users SET reg_state='', name='', table_number='', rest_number='', registration_start_time=NULL WHERE telegram_id=?`, userID)
+			_, errExec := db.ExecContext(ctx, `UPDATE users SET reg_state='', name='', table_number='', rest_number=NULL, registration_start_time=NULL WHERE telegram_id=?`, userID)
 			if errExec != nil {
-				log.Printf("Ошибка сброса reg_state после некорректного ввода имени для user_id %d: %v", userID, errExec)
+				slog.ErrorContext(ctx, "Ошибка сброса reg_state после некорректного имени", "user_id", userID, "err", errExec)
 			}
 			return true
 		}
 
-		// --- Поиск админа ресторана ---
-		var adminTelegramID int64
-		adminFound := false
+		// --- Поиск админов ресторана ---
+		var adminIDs []int64
 
 		// Начинаем новую транзакцию для выполнения операций с БД
-		tx, err := db.Begin()
+		tx, err := db.BeginTx(ctx, nil)
 		if err != nil {
-			log.Printf("Ошибка начала транзакции для обновления данных регистрации (user_id %d): %v", userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла внутренняя ошибка. Попробуйте позже."))
+			slog.ErrorContext(ctx, "Ошибка начала транзакции для регистрации", "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "err.internal")))
 			return true
 		}
 		defer tx.Rollback() // Откат, если что-то пойдет не так
 
-		// Ищем администратора для указанного номера предприятия
-		err = tx.QueryRow(`SELECT telegram_id FROM users WHERE rest_number=? AND access_level='admin' LIMIT 1`, restNumberStr).Scan(&adminTelegramID)
-		if err == sql.ErrNoRows {
+		// Ищем администраторов для указанного номера предприятия
+		adminIDs, err = database.RestAdminIDs(tx, restNumber)
+		if err == nil && len(adminIDs) == 0 {
 			// Ресторан не найден или у него нет админа.
-			bot.Send(tgbotapi.NewMessage(userID, "❗️ Ресторан с таким номером не найден или у него еще не назначен администратор. Пожалуйста, введите /start для начала регистрации заново."))
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.rest_not_found")))
 			// Сбрасываем состояние пользователя.
 			// Устанавливаем registration_start_time в NULL, так как состояние сбрасывается
-			_, errExec := tx.Exec(`UPDATE users SET reg_state='', name='', table_number='', rest_number='', registration_start_time=NULL WHERE telegram_id=?`, userID)
+			_, errExec := tx.ExecContext(ctx, `UPDATE users SET reg_state='', name='', table_number='', rest_number=NULL, registration_start_time=NULL WHERE telegram_id=?`, userID)
 			if errExec != nil {
-				log.Printf("Ошибка сброса reg_state после ненахождения ресторана для user_id %d: %v", userID, errExec)
+				slog.ErrorContext(ctx, "Ошибка сброса reg_state: предприятие не найдено", "user_id", userID, "err", errExec)
 			}
 			// Не коммитим, так как это фактически откат всех изменений, если бы они были.
 			return true
 		}
+		if err == nil && restFrozen(ctx, tx, restNumberStr) {
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.rest_frozen")))
+			_, errExec := tx.ExecContext(ctx, `UPDATE users SET reg_state='', name='', table_number='', rest_number=NULL, registration_start_time=NULL WHERE telegram_id=?`, userID)
+			if errExec != nil {
+				slog.ErrorContext(ctx, "Ошибка сброса reg_state: предприятие заморожено", "user_id", userID, "err", errExec)
+			}
+			tx.Commit()
+			return true
+		}
 		if err != nil {
 			// Другая ошибка при поиске админа.
-			log.Printf("Ошибка поиска администратора ресторана (rest_number %s, user_id %d): %v", restNumberStr, userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла ошибка при поиске ресторана. Попробуйте позже!"))
+			slog.ErrorContext(ctx, "Ошибка поиска администратора предприятия", "rest", restNumberStr, "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.err_rest_lookup")))
 			return true
 		}
-		adminFound = true // Админ найден
 
 		// --- Обновляем данные пользователя ---
 		// Устанавливаем все данные и сбрасываем состояние регистрации.
 		// Устанавливаем registration_start_time в NULL, так как регистрация завершена (переходит в другое состояние или верифицируется).
-		_, err = tx.Exec(`UPDATE users SET name=?, table_number=?, rest_number=?, reg_state='', registration_start_time=NULL WHERE telegram_id=?`,
+		// submitted_at — начало ожидания подтверждения, по нему считаются напоминания админам.
+		_, err = tx.ExecContext(ctx, `UPDATE users SET name=?, table_number=?, rest_number=?, reg_state='', registration_start_time=NULL,
+                         submitted_at=CURRENT_TIMESTAMP, sla_stage=0 WHERE telegram_id=?`,
 			nameInput, tableNumberStr, restNumberStr, userID)
 		if err != nil {
-			log.Printf("Ошибка обновления данных пользователя при регистрации (user_id %d): %v", userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла ошибка при сохранении ваших данных. Попробуйте позже!"))
+			slog.ErrorContext(ctx, "Ошибка сохранения данных регистрации", "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.err_save")))
 			return true
 		}
 
 		// --- Коммитим транзакцию ---
 		if err = tx.Commit(); err != nil {
-			log.Printf("Ошибка коммита транзакции для регистрации (user_id %d): %v", userID, err)
-			bot.Send(tgbotapi.NewMessage(userID, "Произошла внутренняя ошибка. Попробуйте позже."))
+			slog.ErrorContext(ctx, "Ошибка коммита транзакции для регистрации", "user_id", userID, "err", err)
+			bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "err.internal")))
 			return true
 		}
 
 		// --- Отправляем сообщение пользователю ---
-		bot.Send(tgbotapi.NewMessage(userID, "✅ Спасибо! Ваши данные переданы на модерацию. Ожидайте подтверждения."))
-
-		// --- Отправляем уведомление админу ---
-		if adminFound { // Отправляем только если админ был найден
-			txt := fmt.Sprintf(
-				"✨ Новая регистрация!\n\n👤 **Имя:** %s\n#️⃣ **Номер в расписании:** %s\n🏢 **Номер предприятия (ПБО):** %s\n\n🌐 **Username:** @%s\n🆔 **Telegram ID:** `%d`",
-				nameInput, tableNumberStr, restNumberStr, user.UserName, userID)
-
-			approveKeyboard := tgbotapi.NewInlineKeyboardMarkup(
-				tgbotapi.NewInlineKeyboardRow(
-					tgbotapi.NewInlineKeyboardButtonData("✅ Работник", fmt.Sprintf("approve:worker:%d", userID)),
-					tgbotapi.NewInlineKeyboardButtonData("👑 Менеджер", fmt.Sprintf("approve:manager:%d", userID)),
-					tgbotapi.NewInlineKeyboardButtonData("❌ Отклонить", fmt.Sprintf("reject:%d", userID)),
-				),
-			)
+		bot.Send(tgbotapi.NewMessage(userID, i18n.T(lang, "reg.submitted")))
+
+		// --- Отправляем уведомление всем админам предприятия ---
+		for _, adminTelegramID := range adminIDs {
+			adminLang := database.UserLang(db, adminTelegramID)
+			txt := i18n.T(adminLang, "reg.admin_notice", nameInput, tableNumberStr, restNumberStr, user.UserName, userID)
+
+			approveKeyboard := tgbotapi.NewInlineKeyboardMarkup(view.ApprovalRow(adminLang, userID, ""))
 			adminMsg := tgbotapi.NewMessage(adminTelegramID, txt)
 			adminMsg.ReplyMarkup = approveKeyboard
 			adminMsg.ParseMode = tgbotapi.ModeMarkdown // Используем Markdown для форматирования
-			if _, err := bot.Send(adminMsg); err != nil {
-				log.Printf("Ошибка отправки сообщения админу (admin_id %d, user_id %d): %v", adminTelegramID, userID, err)
+			if err := outbox.Enqueue(db, adminMsg); err != nil {
+				slog.ErrorContext(ctx, "Ошибка постановки в очередь сообщения админу", "admin_id", adminTelegramID, "user_id", userID, "err", err)
 				// Не возвращаем ошибку, так как регистрация пользователя прошла успешно
 			}
 		}
@@ -248,3 +251,10 @@ func RegistrationHandler(bot *tgbotapi.BotAPI, db *sql.DB, update tgbotapi.Updat
 	// возвращаем false, чтобы его мог обработать другой хэндлер.
 	return false
 }
+
+// restFrozen проверяет, заморожено ли предприятие суперпользователем.
+func restFrozen(ctx context.Context, tx *sql.Tx, restNumber string) bool {
+	var frozen bool
+	err := tx.QueryRowContext(ctx, `SELECT frozen FROM restaurants WHERE rest_number=?`, restNumber).Scan(&frozen)
+	return err == nil && frozen
+}
@@ -0,0 +1,69 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// OrderStatus — статус заказа на языке lang.
+func OrderStatus(lang, status string) string {
+	switch status {
+	case database.OrderDenied:
+		return i18n.T(lang, "order.status_deny")
+	case database.OrderOpen:
+		return i18n.T(lang, "order.status_open")
+	case database.OrderAccepted:
+		return i18n.T(lang, "order.status_accept")
+	}
+	return status
+}
+
+// OrderHistory — история заказов покупателя: дата | товар | статус | цена.
+func OrderHistory(lang string, orders []database.Order) string {
+	var list strings.Builder
+	for _, o := range orders {
+		list.WriteString(fmt.Sprintf("%s | %s | %s | %d🌟\n",
+			o.CreatedAt.Format("2006-01-02"), o.Product, OrderStatus(lang, o.Status), o.Price))
+	}
+	return list.String()
+}
+
+// OpenOrdersList — незакрытые заказы предприятия в текстовом виде.
+func OpenOrdersList(orders []database.Order) string {
+	var list strings.Builder
+	for _, o := range orders {
+		list.WriteString(fmt.Sprintf("%s | %s %s | %s | %d🌟\n",
+			o.CreatedAt.Format("2006-01-02"), o.BuyerTable, o.BuyerName, o.Product, o.Price))
+	}
+	return list.String()
+}
+
+// OrdersKeyboard — кнопка на каждый заказ в сборке, ведёт к карточке заказа.
+func OrdersKeyboard(orders []database.Order) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, o := range orders {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s %s (%s)", o.BuyerTable, o.BuyerName, o.Product),
+			fmt.Sprintf("orders_order:%d", o.ID),
+		)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// OrderCard — заказ для админа магазина с кнопками выдачи и отмены.
+func OrderCard(lang string, chatID int64, o database.Order) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "orders.card", o.BuyerTable, o.BuyerName, o.Product, o.Price))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "transfer.btn_orders_complete"),
+				fmt.Sprintf("orders_order:%d:accept", o.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "orders.btn_deny"),
+				fmt.Sprintf("orders_order:%d:deny", o.ID)),
+		),
+	)
+	return msg
+}
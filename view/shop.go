@@ -0,0 +1,36 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// ProductsList — товары с ценами и остатками.
+func ProductsList(lang string, products []database.Product) string {
+	var list strings.Builder
+	for _, p := range products {
+		list.WriteString(i18n.T(lang, "shop.line", p.Name, p.Price, p.Remains))
+	}
+	return list.String()
+}
+
+// Shop — витрина магазина для покупателя с кнопкой покупки на каждый товар.
+func Shop(lang string, chatID int64, products []database.Product) tgbotapi.MessageConfig {
+	if len(products) == 0 {
+		return tgbotapi.NewMessage(chatID, i18n.T(lang, "shop.empty"))
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, p := range products {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			i18n.T(lang, "shop.btn_buy", p.Name, p.Price),
+			fmt.Sprintf("buy_product:%d", p.ID),
+		)))
+	}
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "shop.header")+ProductsList(lang, products))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return msg
+}
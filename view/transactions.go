@@ -0,0 +1,48 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// TxTypeName — название типа операции на языке lang.
+func TxTypeName(lang, txType string) string {
+	switch txType {
+	case database.TxTopUp:
+		return i18n.T(lang, "tx.topup")
+	case database.TxPurchase:
+		return i18n.T(lang, "tx.purchase")
+	case database.TxRefund:
+		return i18n.T(lang, "tx.refund")
+	case database.TxCorrection:
+		return i18n.T(lang, "tx.correction")
+	case database.TxTransfer:
+		return i18n.T(lang, "tx.transfer")
+	case database.TxBonus:
+		return i18n.T(lang, "tx.bonus")
+	case database.TxKudos:
+		return i18n.T(lang, "tx.kudos")
+	case database.TxExpiry:
+		return i18n.T(lang, "tx.expiry")
+	}
+	return txType
+}
+
+// Transaction — строка истории: дата | сумма | тип (кто/за что) | остаток.
+func Transaction(lang string, t database.Transaction) string {
+	details := TxTypeName(lang, t.Type)
+	var extra []string
+	if t.Comment != "" {
+		extra = append(extra, t.Comment)
+	}
+	if t.ActorName != "" && t.ActorID != t.TelegramID {
+		extra = append(extra, t.ActorName)
+	}
+	if len(extra) > 0 {
+		details += " (" + strings.Join(extra, ", ") + ")"
+	}
+	return fmt.Sprintf("%s | %+d🌟 | %s | =%d🌟", t.CreatedAt.Format("2006-01-02"), t.Amount, details, t.BalanceAfter)
+}
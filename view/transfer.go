@@ -0,0 +1,30 @@
+package view
+
+import (
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// TransferBalanceMode — что происходит с балансом при переводе.
+func TransferBalanceMode(lang, mode string) string {
+	switch mode {
+	case database.TransferBalanceCarry:
+		return i18n.T(lang, "transfer.balance_carry")
+	case database.TransferBalanceConvert:
+		return i18n.T(lang, "transfer.balance_convert", database.TransferConvertPercent)
+	case database.TransferBalanceReset:
+		return i18n.T(lang, "transfer.balance_reset")
+	}
+	return mode
+}
+
+// TransferOrdersMode — что происходит с открытыми заказами при переводе.
+func TransferOrdersMode(lang, mode string) string {
+	switch mode {
+	case database.TransferOrdersCancel:
+		return i18n.T(lang, "transfer.orders_cancel")
+	case database.TransferOrdersComplete:
+		return i18n.T(lang, "transfer.orders_complete")
+	}
+	return mode
+}
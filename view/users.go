@@ -0,0 +1,70 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"tbViT/database"
+	"tbViT/i18n"
+)
+
+// UsersList — список сотрудников: номер, имя, роль, баланс.
+func UsersList(lang string, users []database.User) string {
+	var list strings.Builder
+	for _, u := range users {
+		list.WriteString(fmt.Sprintf("%s %s|%s|%d🌟%s\n",
+			u.TableNumber, u.Name, u.AccessLevel, u.Balance, BlockedMark(lang, u.Blocked)))
+	}
+	return list.String()
+}
+
+// WorkerInfo — карточка выбранного сотрудника.
+func WorkerInfo(lang string, u database.User) string {
+	return i18n.T(lang, "worker.info", u.TableNumber, u.Name, u.AccessLevel, u.Balance)
+}
+
+// ApprovalRow — кнопки решения по заявке на регистрацию userID: принять работником,
+// менеджером или отклонить. name подписывает кнопку принятия, когда заявок в
+// сообщении несколько; пустое — без подписи.
+func ApprovalRow(lang string, userID int64, name string) []tgbotapi.InlineKeyboardButton {
+	worker := i18n.T(lang, "reg.btn_worker")
+	if name != "" {
+		worker += " · " + name
+	}
+	return tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(worker, fmt.Sprintf("approve:worker:%d", userID)),
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "reg.btn_manager"), fmt.Sprintf("approve:manager:%d", userID)),
+		tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.reject"), fmt.Sprintf("reject:%d", userID)),
+	)
+}
+
+// WorkersPicker — страница выбора работника. Кнопка работника шлёт "<status>:<id>",
+// листание — "topup_select_worker:<page>:<status>:<dep>".
+func WorkersPicker(lang string, chatID int64, workers []database.User, status, dep string, page int, hasNext bool) tgbotapi.MessageConfig {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, w := range workers {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%s %s%s", w.TableNumber, w.Name, BlockedMark(lang, w.Blocked)),
+				fmt.Sprintf("%s:%d", status, w.TelegramID)),
+		))
+	}
+
+	var paging []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		paging = append(paging, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.prev"),
+			fmt.Sprintf("topup_select_worker:%d:%s:%s", page-1, status, dep)))
+	}
+	if hasNext {
+		paging = append(paging, tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn.next"),
+			fmt.Sprintf("topup_select_worker:%d:%s:%s", page+1, status, dep)))
+	}
+	if len(paging) > 0 {
+		rows = append(rows, paging)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "workers.choose", page+1))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return msg
+}
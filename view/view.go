@@ -0,0 +1,15 @@
+// Package view превращает данные из database в тексты и клавиатуры Telegram.
+// Здесь нет обращений к базе: всё, что нужно для отображения, передаётся аргументами.
+package view
+
+import (
+	"tbViT/i18n"
+)
+
+// BlockedMark — пометка в списках сотрудников для тех, кто заблокировал бота.
+func BlockedMark(lang string, blocked bool) string {
+	if blocked {
+		return i18n.T(lang, "user.blocked_mark")
+	}
+	return ""
+}
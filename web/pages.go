@@ -0,0 +1,239 @@
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/service"
+)
+
+// staffRow — строка таблицы сотрудников.
+type staffRow struct {
+	database.User
+	Role string
+}
+
+type staffData struct {
+	Staff   []staffRow
+	Summary database.RestaurantSummary
+}
+
+// staffPage — главная страница: сотрудники предприятия с балансами.
+func (srv Server) staffPage(w http.ResponseWriter, r *http.Request, s *session) error {
+	v := srv.newView(r.Context(), s, "staff")
+	if s.rest > 0 {
+		users, err := database.ListRestUsers(srv.DB, s.rest, false)
+		if err != nil {
+			return err
+		}
+		summary, err := database.GetRestaurantSummary(srv.DB, s.rest)
+		if err != nil {
+			return err
+		}
+		data := staffData{Summary: summary}
+		for _, u := range users {
+			role := u.AccessLevel
+			if database.IsValidAccessLevel(role) {
+				role = i18n.T(s.lang, "role."+role)
+			}
+			data.Staff = append(data.Staff, staffRow{User: u, Role: role})
+		}
+		v.Data = data
+	}
+	render(w, http.StatusOK, "staff", v)
+	return nil
+}
+
+// ordersPage — заказы в сборке в порядке поступления.
+func (srv Server) ordersPage(w http.ResponseWriter, r *http.Request, s *session) error {
+	v := srv.newView(r.Context(), s, "orders")
+	switch r.URL.Query().Get("done") {
+	case database.OrderAccepted:
+		v.Notices = append(v.Notices, i18n.T(s.lang, "orders.ready_ack"))
+	case database.OrderDenied:
+		v.Notices = append(v.Notices, i18n.T(s.lang, "orders.denied_ack"))
+	}
+	return srv.renderOrders(w, http.StatusOK, s, v)
+}
+
+func (srv Server) renderOrders(w http.ResponseWriter, status int, s *session, v *view) error {
+	if s.rest > 0 {
+		orders, err := database.ListOpenOrders(srv.DB, s.rest)
+		if err != nil {
+			return err
+		}
+		v.Data = orders
+	}
+	render(w, status, "orders", v)
+	return nil
+}
+
+// decideOrder выдаёт или отменяет заказ — как кнопки «✅»/«❌» в боте.
+func (srv Server) decideOrder(w http.ResponseWriter, r *http.Request, s *session) error {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return sql.ErrNoRows
+	}
+	o, err := database.GetOrder(srv.DB, id)
+	if err != nil {
+		return err
+	}
+	if o.RestNumber != s.rest {
+		return sql.ErrNoRows
+	}
+	decision := r.PostForm.Get("decision")
+	_, err = service.DecideOrder(r.Context(), srv.DB, s.user.TelegramID, id, decision)
+	var ruleErr *i18n.Error
+	if errors.As(err, &ruleErr) {
+		v := srv.newView(r.Context(), s, "orders")
+		v.Errors = append(v.Errors, i18n.Err(s.lang, ruleErr))
+		return srv.renderOrders(w, http.StatusUnprocessableEntity, s, v)
+	}
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, "/web/orders?done="+decision, http.StatusSeeOther)
+	return nil
+}
+
+// productsPage — таблица товаров, которую можно править целиком.
+func (srv Server) productsPage(w http.ResponseWriter, r *http.Request, s *session) error {
+	v := srv.newView(r.Context(), s, "products")
+	if r.URL.Query().Get("saved") != "" {
+		v.Notices = append(v.Notices, i18n.T(s.lang, "web.saved"))
+	}
+	return srv.renderProducts(w, http.StatusOK, s, v)
+}
+
+func (srv Server) renderProducts(w http.ResponseWriter, status int, s *session, v *view) error {
+	if s.rest > 0 {
+		products, err := database.ListProducts(srv.DB, s.rest, false)
+		if err != nil {
+			return err
+		}
+		v.Data = products
+	}
+	render(w, status, "products", v)
+	return nil
+}
+
+// saveProducts применяет правки таблицы товаров: изменённые строки, отмеченные
+// на удаление и новую строку. Каждый товар сохраняется отдельно, поэтому ошибка
+// в одной строке не отменяет остальные — она показывается над таблицей.
+func (srv Server) saveProducts(w http.ResponseWriter, r *http.Request, s *session) error {
+	if s.rest == 0 {
+		return sql.ErrNoRows
+	}
+	products, err := database.ListProducts(srv.DB, s.rest, false)
+	if err != nil {
+		return err
+	}
+	actor := s.user.TelegramID
+	var problems []string
+	fail := func(name string, err error) error {
+		var ruleErr *i18n.Error
+		if !errors.As(err, &ruleErr) {
+			return err
+		}
+		problems = append(problems, fmt.Sprintf("%s: %s", name, i18n.Err(s.lang, ruleErr)))
+		return nil
+	}
+
+	for _, p := range products {
+		id := strconv.Itoa(p.ID)
+		// Товар добавлен после того, как страница была открыта, — его в форме нет
+		if !r.PostForm.Has("name_" + id) {
+			continue
+		}
+		if r.PostForm.Get("delete_"+id) != "" {
+			if err := fail(p.Name, service.DeleteProduct(srv.DB, actor, p.ID)); err != nil {
+				return err
+			}
+			continue
+		}
+		edited, err := productEdits(r, id, p)
+		if err == nil && edited == p {
+			continue
+		}
+		if err == nil {
+			err = service.UpdateProduct(srv.DB, actor, edited)
+		}
+		if err := fail(p.Name, err); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(r.PostForm.Get("name_new")+r.PostForm.Get("price_new")+r.PostForm.Get("remains_new")) != "" {
+		p, err := productForm(r, database.Product{RestNumber: s.rest})
+		if err == nil {
+			_, err = service.AddProduct(srv.DB, actor, p)
+		}
+		if err := fail(i18n.T(s.lang, "web.product_new"), err); err != nil {
+			return err
+		}
+	}
+
+	if len(problems) == 0 {
+		http.Redirect(w, r, "/web/products?saved=1", http.StatusSeeOther)
+		return nil
+	}
+	v := srv.newView(r.Context(), s, "products")
+	v.Errors = problems
+	return srv.renderProducts(w, http.StatusUnprocessableEntity, s, v)
+}
+
+// productEdits накладывает на p поля строки id, которые пользователь изменил:
+// значение сравнивается с исходным из скрытого поля was_*. Так покупка, сделанная,
+// пока страница была открыта, не откатывается старым остатком из формы.
+func productEdits(r *http.Request, id string, p database.Product) (database.Product, error) {
+	changed := func(field string) (string, bool) {
+		v := strings.TrimSpace(r.PostForm.Get(field + "_" + id))
+		return v, v != r.PostForm.Get("was_"+field+"_"+id)
+	}
+	var err error
+	if v, ok := changed("name"); ok {
+		p.Name = v
+	}
+	if v, ok := changed("price"); ok {
+		if p.Price, err = parseInt(v); err != nil {
+			return p, err
+		}
+	}
+	if v, ok := changed("remains"); ok {
+		if p.Remains, err = parseInt(v); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// productForm читает из формы новый товар: поля с суффиксом _new.
+func productForm(r *http.Request, p database.Product) (database.Product, error) {
+	p.Name = strings.TrimSpace(r.PostForm.Get("name_new"))
+	var err error
+	if p.Price, err = formInt(r, "price_new"); err != nil {
+		return p, err
+	}
+	if p.Remains, err = formInt(r, "remains_new"); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func formInt(r *http.Request, field string) (int, error) {
+	return parseInt(r.PostForm.Get(field))
+}
+
+// parseInt — число из поля формы; не число — ошибка правил, как при вводе в боте.
+func parseInt(v string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return 0, i18n.NewError("err.value_not_integer")
+	}
+	return n, nil
+}
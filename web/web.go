@@ -0,0 +1,337 @@
+// Package web — веб-панель админа предприятия: сотрудники и балансы, заказы в
+// сборке и таблица товаров, которую можно править целиком. Страницы собираются
+// на сервере из шаблонов html/template, стили лежат рядом и встраиваются в
+// бинарник — сторонние CDN не нужны. Вход — по одноразовой ссылке, которую бот
+// присылает по кнопке «🌐 Веб-панель»; ссылка открывает сессию в cookie.
+// Изменения выполняются функциями пакета service, как в боте и в API.
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"tbViT/database"
+	"tbViT/i18n"
+	"tbViT/logging"
+)
+
+// PublicURL — адрес, по которому панель открывается из браузера (http.public_url),
+// без завершающего «/». Пустой — панель выключена, кнопки в боте нет.
+var PublicURL string
+
+// Enabled — включена ли панель.
+func Enabled() bool {
+	return PublicURL != ""
+}
+
+// LoginLink выпускает одноразовую ссылку для входа пользователя userID.
+func LoginLink(db *sql.DB, userID int64) (string, error) {
+	token, err := database.CreateWebLogin(db, userID)
+	if err != nil {
+		return "", err
+	}
+	return PublicURL + "/web/login?token=" + url.QueryEscape(token), nil
+}
+
+// cookieName — cookie с токеном сессии.
+const cookieName = "tbvit_web"
+
+// MaxForm — предельный размер отправленной формы.
+const MaxForm = 1 << 20
+
+//go:embed templates static
+var files embed.FS
+
+// pages — шаблоны страниц: каждая страница — layout.html со своим блоком content.
+var pages = map[string]*template.Template{}
+
+func init() {
+	// Настоящая tr подставляется при выводе страницы — на языке пользователя
+	funcs := template.FuncMap{"tr": func(key string, args ...interface{}) string { return key }}
+	for _, name := range []string{"login", "message", "staff", "orders", "products"} {
+		pages[name] = template.Must(template.New("layout.html").Funcs(funcs).
+			ParseFS(files, "templates/layout.html", "templates/"+name+".html"))
+	}
+}
+
+// Server обслуживает /web/.
+type Server struct {
+	DB *sql.DB
+}
+
+// session — вошедший пользователь и предприятие, с которым он работает.
+type session struct {
+	token   string
+	user    database.User
+	isSuper bool
+	rest    int
+	lang    string
+}
+
+// handler — обработчик страницы для вошедшего пользователя.
+type handler func(w http.ResponseWriter, r *http.Request, s *session) error
+
+// Register подключает страницы панели к mux.
+func (srv Server) Register(mux *http.ServeMux) {
+	static, _ := fs.Sub(files, "static")
+	mux.Handle("GET /web/static/", http.StripPrefix("/web/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /web/login", srv.loginPage)
+	mux.HandleFunc("POST /web/login", srv.login)
+
+	mux.Handle("POST /web/logout", srv.auth(srv.logout))
+	mux.Handle("POST /web/rest", srv.auth(srv.chooseRest))
+	mux.Handle("GET /web/{$}", srv.auth(srv.staffPage))
+	mux.Handle("GET /web/orders", srv.auth(srv.ordersPage))
+	mux.Handle("POST /web/orders/{id}", srv.auth(srv.decideOrder))
+	mux.Handle("GET /web/products", srv.auth(srv.productsPage))
+	mux.Handle("POST /web/products", srv.auth(srv.saveProducts))
+}
+
+var (
+	// errForbidden — пользователь больше не админ предприятия
+	errForbidden = errors.New("нет доступа к веб-панели")
+	// errFrozen — предприятие админа заморожено
+	errFrozen = errors.New("предприятие заморожено")
+)
+
+// auth находит сессию по cookie и проверяет права пользователя при каждом
+// запросе: панель доступна админу своего предприятия и суперпользователю.
+// Для POST сверяется токен CSRF из формы.
+func (srv Server) auth(h handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := logging.WithID(r.Context(), logging.NewID())
+		r = r.WithContext(ctx)
+		secureHeaders(w)
+		lang := i18n.Normalize(r.Header.Get("Accept-Language"))
+
+		s, err := srv.session(r)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Redirect(w, r, "/web/login", http.StatusSeeOther)
+			return
+		}
+		if err == nil {
+			lang = s.lang
+			if r.Method == http.MethodPost {
+				r.Body = http.MaxBytesReader(w, r.Body, MaxForm)
+				if r.ParseForm() != nil || !validCSRF(s.token, r.PostForm.Get("csrf")) {
+					slog.WarnContext(ctx, "Неверный токен CSRF в веб-панели", "user_id", s.user.TelegramID)
+					renderMessage(w, http.StatusForbidden, lang, i18n.T(lang, "web.err_csrf"))
+					return
+				}
+			}
+			err = h(w, r, s)
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, errForbidden):
+			renderMessage(w, http.StatusForbidden, lang, i18n.T(lang, "web.no_access"))
+		case errors.Is(err, errFrozen):
+			renderMessage(w, http.StatusForbidden, lang, i18n.T(lang, "rest.frozen"))
+		case errors.Is(err, sql.ErrNoRows):
+			renderMessage(w, http.StatusNotFound, lang, i18n.T(lang, "api.not_found"))
+		default:
+			slog.ErrorContext(ctx, "Ошибка веб-панели", "method", r.Method, "route", r.Pattern, "err", err)
+			renderMessage(w, http.StatusInternalServerError, lang, i18n.T(lang, "err.internal"))
+		}
+	})
+}
+
+// session читает сессию из cookie; нет cookie или сессия истекла — sql.ErrNoRows.
+func (srv Server) session(r *http.Request) (*session, error) {
+	c, err := r.Cookie(cookieName)
+	if err != nil || c.Value == "" {
+		return nil, sql.ErrNoRows
+	}
+	ws, err := database.FindWebSession(srv.DB, c.Value)
+	if err != nil {
+		return nil, err
+	}
+	u, err := database.GetUser(srv.DB, ws.TelegramID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	u.TelegramID = ws.TelegramID
+	s := &session{token: c.Value, user: u, isSuper: database.IsSuperUser(srv.DB, ws.TelegramID),
+		lang: database.UserLang(srv.DB, ws.TelegramID)}
+	if s.isSuper {
+		// Суперпользователь выбирает предприятие сам; сначала — своё, если оно есть
+		s.rest = ws.RestNumber
+		if s.rest == 0 {
+			s.rest = u.RestNumber
+		}
+		return s, nil
+	}
+	if u.AccessLevel != "admin" || u.RestNumber == 0 {
+		slog.WarnContext(r.Context(), "Пользователь веб-панели лишился прав", "user_id", ws.TelegramID)
+		return s, errForbidden
+	}
+	if frozen, _ := database.IsRestFrozen(srv.DB, u.RestNumber); frozen {
+		return s, errFrozen
+	}
+	s.rest = u.RestNumber
+	return s, nil
+}
+
+// csrfToken — токен для форм сессии: производный от токена сессии, поэтому его
+// не нужно хранить, а без cookie его не подобрать.
+func csrfToken(sessionToken string) string {
+	sum := sha256.Sum256([]byte("csrf:" + sessionToken))
+	return hex.EncodeToString(sum[:])
+}
+
+func validCSRF(sessionToken, got string) bool {
+	return subtle.ConstantTimeCompare([]byte(csrfToken(sessionToken)), []byte(got)) == 1
+}
+
+// secureHeaders запрещает встраивание страниц и сторонние ресурсы, а ссылка со
+// входом не должна уходить в Referer.
+func secureHeaders(w http.ResponseWriter) {
+	h := w.Header()
+	h.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'; form-action 'self'")
+	h.Set("Referrer-Policy", "no-referrer")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "no-store")
+}
+
+func (srv Server) loginPage(w http.ResponseWriter, r *http.Request) {
+	secureHeaders(w)
+	lang := i18n.Normalize(r.Header.Get("Accept-Language"))
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		renderMessage(w, http.StatusOK, lang, i18n.T(lang, "web.login_hint"))
+		return
+	}
+	// Вход — только по кнопке: предпросмотр ссылки в Telegram и сканеры ссылок
+	// открывают её GET-запросом и не должны гасить токен
+	render(w, http.StatusOK, "login", &view{Lang: lang, Data: token})
+}
+
+func (srv Server) login(w http.ResponseWriter, r *http.Request) {
+	ctx := logging.WithID(r.Context(), logging.NewID())
+	secureHeaders(w)
+	lang := i18n.Normalize(r.Header.Get("Accept-Language"))
+	r.Body = http.MaxBytesReader(w, r.Body, MaxForm)
+	if err := r.ParseForm(); err != nil {
+		renderMessage(w, http.StatusBadRequest, lang, i18n.T(lang, "web.link_expired"))
+		return
+	}
+	token, ws, err := database.StartWebSession(srv.DB, r.PostForm.Get("token"))
+	if errors.Is(err, sql.ErrNoRows) {
+		renderMessage(w, http.StatusBadRequest, lang, i18n.T(lang, "web.link_expired"))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Ошибка входа в веб-панель", "err", err)
+		renderMessage(w, http.StatusInternalServerError, lang, i18n.T(lang, "err.internal"))
+		return
+	}
+	database.AuditAction(srv.DB, ws.TelegramID, "web_login", "", "", "")
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    token,
+		Path:     "/web/",
+		Expires:  ws.ExpiresAt,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(PublicURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/web/", http.StatusSeeOther)
+}
+
+func (srv Server) logout(w http.ResponseWriter, r *http.Request, s *session) error {
+	if err := database.EndWebSession(srv.DB, s.token); err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{Name: cookieName, Path: "/web/", MaxAge: -1})
+	http.Redirect(w, r, "/web/login", http.StatusSeeOther)
+	return nil
+}
+
+// view — данные страницы для шаблона.
+type view struct {
+	Lang    string
+	CSRF    string
+	Page    string
+	User    database.User
+	IsSuper bool
+	Rest    int
+	// Rests — предприятия для переключателя суперпользователя
+	Rests   []database.RestaurantSummary
+	Notices []string
+	Errors  []string
+	Data    interface{}
+}
+
+// Path — адрес страницы, куда вернуться после смены предприятия.
+func (v *view) Path() string {
+	if v.Page == "staff" {
+		return "/web/"
+	}
+	return "/web/" + v.Page
+}
+
+// newView — данные общей части страницы: шапка, меню, выбор предприятия.
+func (srv Server) newView(ctx context.Context, s *session, page string) *view {
+	v := &view{Lang: s.lang, CSRF: csrfToken(s.token), Page: page, User: s.user, IsSuper: s.isSuper, Rest: s.rest}
+	if s.isSuper {
+		rests, err := database.ListRestaurants(srv.DB)
+		if err != nil {
+			slog.ErrorContext(ctx, "Ошибка загрузки предприятий для веб-панели", "err", err)
+		}
+		v.Rests = rests
+	}
+	return v
+}
+
+// chooseRest переключает суперпользователя на другое предприятие.
+func (srv Server) chooseRest(w http.ResponseWriter, r *http.Request, s *session) error {
+	if !s.isSuper {
+		return errForbidden
+	}
+	rest, err := formInt(r, "rest")
+	if err != nil || rest <= 0 {
+		return sql.ErrNoRows
+	}
+	if err := database.SetWebSessionRest(srv.DB, s.token, rest); err != nil {
+		return err
+	}
+	back := r.PostForm.Get("back")
+	if !strings.HasPrefix(back, "/web/") || strings.HasPrefix(back, "//") {
+		back = "/web/"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+	return nil
+}
+
+func render(w http.ResponseWriter, status int, name string, v *view) {
+	t, err := pages[name].Clone()
+	if err == nil {
+		t.Funcs(template.FuncMap{"tr": func(key string, args ...interface{}) string {
+			return i18n.T(v.Lang, key, args...)
+		}})
+		var b strings.Builder
+		if err = t.Execute(&b, v); err == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			w.Write([]byte(b.String()))
+			return
+		}
+	}
+	slog.Error("Ошибка вывода страницы веб-панели", "page", name, "err", err)
+	http.Error(w, i18n.T(v.Lang, "err.internal"), http.StatusInternalServerError)
+}
+
+// renderMessage выводит страницу с одним сообщением: подсказку о входе или ошибку.
+func renderMessage(w http.ResponseWriter, status int, lang, text string) {
+	render(w, status, "message", &view{Lang: lang, Data: text})
+}